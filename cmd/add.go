@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	vfs "github.com/twpayne/go-vfs"
@@ -26,6 +25,7 @@ var addCmd = &cobra.Command{
 
 type addCmdConfig struct {
 	force   bool
+	layer   string
 	prompt  bool
 	options chezmoi.AddOptions
 }
@@ -38,6 +38,7 @@ func init() {
 	persistentFlags.BoolVar(&config.add.options.Encrypt, "encrypt", false, "encrypt files")
 	persistentFlags.BoolVarP(&config.add.force, "force", "f", false, "overwrite source state, even if template would be lost")
 	persistentFlags.BoolVarP(&config.add.options.Exact, "exact", "x", false, "add directories exactly")
+	persistentFlags.StringVar(&config.add.layer, "layer", "", "add to source layer")
 	persistentFlags.BoolVarP(&config.add.prompt, "prompt", "p", false, "prompt before adding")
 	persistentFlags.BoolVarP(&config.add.options.Recursive, "recursive", "r", false, "recurse in to subdirectories")
 	persistentFlags.BoolVarP(&config.add.options.Template, "template", "T", false, "add files as templates")
@@ -51,15 +52,18 @@ func (c *Config) runAddCmd(cmd *cobra.Command, args []string) (err error) {
 	if c.add.options.AutoTemplate {
 		c.add.options.Template = true
 	}
+	// Only root can create targets that are owned by other users, so only
+	// root needs to record their ownership.
+	c.add.options.Ownership = os.Geteuid() == 0
 
-	ts, err := c.getTargetState(nil)
+	ts, err := c.getLayerTargetState(c.add.layer, nil)
 	if err != nil {
 		return err
 	}
+	c.sourceLayerChanged(ts.SourceDir)
 	if err := c.ensureSourceDirectory(); err != nil {
 		return err
 	}
-	destDirPrefix := filepath.FromSlash(ts.DestDir + "/")
 	var quit int // quit is an int with a unique address
 	defer func() {
 		if r := recover(); r != nil {
@@ -80,7 +84,9 @@ func (c *Config) runAddCmd(cmd *cobra.Command, args []string) (err error) {
 				if err != nil {
 					return err
 				}
-				if ts.TargetIgnore.Match(strings.TrimPrefix(path, destDirPrefix)) {
+				if ignored, err := ts.IgnoredPath(c.fs, path); err != nil {
+					return err
+				} else if ignored {
 					cmd.Printf("warning: %s: skipping file ignored by .chezmoiignore\n", path)
 					return nil
 				}
@@ -114,7 +120,9 @@ func (c *Config) runAddCmd(cmd *cobra.Command, args []string) (err error) {
 				return err
 			}
 		} else {
-			if ts.TargetIgnore.Match(strings.TrimPrefix(path, destDirPrefix)) {
+			if ignored, err := ts.IgnoredPath(c.fs, path); err != nil {
+				return err
+			} else if ignored {
 				cmd.Printf("warning: %s: skipping file ignored by .chezmoiignore\n", path)
 				continue
 			}
@@ -60,14 +60,13 @@ func TestAddCommand(t *testing.T) {
 					vfst.TestModePerm(0o700),
 				),
 				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config",
-					vfst.TestIsDir,
-					vfst.TestModePerm(0o755),
+					vfst.TestDoesNotExist,
 				),
-				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config/htop",
+				vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_htop",
 					vfst.TestIsDir,
 					vfst.TestModePerm(0o755),
 				),
-				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config/htop/.keep",
+				vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_htop/.keep",
 					vfst.TestModeIsRegular,
 					vfst.TestModePerm(0o644),
 					vfst.TestContents(nil),
@@ -88,14 +87,13 @@ func TestAddCommand(t *testing.T) {
 					vfst.TestModePerm(0o700),
 				),
 				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config",
-					vfst.TestIsDir,
-					vfst.TestModePerm(0o755),
+					vfst.TestDoesNotExist,
 				),
-				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config/htop",
+				vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_htop",
 					vfst.TestIsDir,
 					vfst.TestModePerm(0o755),
 				),
-				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config/htop/.keep",
+				vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_htop/.keep",
 					vfst.TestModeIsRegular,
 					vfst.TestModePerm(0o644),
 					vfst.TestContents(nil),
@@ -213,6 +211,21 @@ func TestAddCommand(t *testing.T) {
 				"/home/user/.local/share/chezmoi":        &vfst.Dir{Perm: 0o700},
 				"/home/user/.config/micro/settings.json": "{}",
 			},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_micro/settings.json",
+					vfst.TestModeIsRegular,
+					vfst.TestContentsString("{}"),
+				),
+			},
+		},
+		{
+			name: "add_nested_directory_existing_non_xdg_dir",
+			args: []string{"/home/user/.config/micro/settings.json"},
+			root: map[string]interface{}{
+				"/home/user": &vfst.Dir{Perm: 0o755},
+				"/home/user/.local/share/chezmoi/dot_config": &vfst.Dir{Perm: 0o755},
+				"/home/user/.config/micro/settings.json":     "{}",
+			},
 			tests: []vfst.Test{
 				vfst.TestPath("/home/user/.local/share/chezmoi/dot_config/micro/settings.json",
 					vfst.TestModeIsRegular,
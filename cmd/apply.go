@@ -1,7 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
+	vfs "github.com/twpayne/go-vfs"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
 )
 
 var applyCmd = &cobra.Command{
@@ -13,9 +22,25 @@ var applyCmd = &cobra.Command{
 	RunE:    config.runApplyCmd,
 }
 
+type applyCmdConfig struct {
+	KeepGoing     bool
+	Transactional bool
+	fromPlan      string
+	plan          string
+	rollbackLast  bool
+}
+
 func init() {
 	rootCmd.AddCommand(applyCmd)
 
+	persistentFlags := applyCmd.PersistentFlags()
+	persistentFlags.BoolVarP(&config.Apply.KeepGoing, "keep-going", "k", config.Apply.KeepGoing, "keep going after errors")
+	persistentFlags.BoolVar(&config.Apply.Transactional, "transactional", config.Apply.Transactional, "revert all changes on failure")
+	persistentFlags.BoolVar(&config.Apply.rollbackLast, "rollback-last", false, "revert the most recent transactional apply")
+	persistentFlags.StringVar(&config.Apply.plan, "plan", "", "write a plan of the changes to file instead of making them")
+	persistentFlags.StringVar(&config.Apply.fromPlan, "from-plan", "", "make exactly the changes in a plan file")
+	addFilterFlags(applyCmd)
+
 	markRemainingZshCompPositionalArgumentsAsFiles(applyCmd, 1)
 }
 
@@ -26,5 +51,139 @@ func (c *Config) runApplyCmd(cmd *cobra.Command, args []string) error {
 	}
 	defer persistentState.Close()
 
-	return c.applyArgs(args, persistentState)
+	if c.Apply.rollbackLast {
+		if len(args) != 0 {
+			return errors.New("--rollback-last does not accept targets")
+		}
+		return c.rollbackLastApply()
+	}
+
+	if c.Apply.plan != "" && c.Apply.fromPlan != "" {
+		return errors.New("--plan and --from-plan are mutually exclusive")
+	}
+
+	if c.Apply.plan != "" {
+		return c.writePlan(args, persistentState)
+	}
+
+	apply := c.applyArgs
+	if c.Apply.fromPlan != "" {
+		if len(args) != 0 {
+			return errors.New("--from-plan does not accept targets")
+		}
+		apply = c.applyPlan
+	}
+
+	if !c.Apply.Transactional || c.DryRun {
+		return apply(args, persistentState)
+	}
+
+	mutator := c.mutator
+	journalMutator := chezmoi.NewJournalMutator(mutator, c.fs)
+	c.mutator = journalMutator
+	err = apply(args, persistentState)
+	c.mutator = mutator
+	journal := journalMutator.Journal()
+	if err != nil {
+		if rollbackErr := journal.Rollback(c.fs, c.mutator); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return fmt.Errorf("%w (all changes reverted)", err)
+	}
+
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	journalFile := c.getJournalFile()
+	if err := vfs.MkdirAll(c.fs, filepath.Dir(journalFile), 0o700); err != nil {
+		return err
+	}
+	// The journal contains the previous contents of files, which may include
+	// secrets, so it is kept out of the persistent state, which is shared by
+	// all commands, in a file that only the user can read.
+	return c.fs.WriteFile(journalFile, data, 0o600)
+}
+
+// getJournalFile returns the path of the journal of the most recent successful
+// transactional apply.
+func (c *Config) getJournalFile() string {
+	return filepath.Join(filepath.Dir(c.getPersistentStateFile()), "chezmoijournal.json")
+}
+
+// rollbackLastApply reverts the changes made by the most recent successful
+// transactional apply.
+func (c *Config) rollbackLastApply() error {
+	journalFile := c.getJournalFile()
+	data, err := c.fs.ReadFile(journalFile)
+	switch {
+	case os.IsNotExist(err):
+		return errors.New("no transactional apply to roll back")
+	case err != nil:
+		return err
+	}
+	var journal chezmoi.Journal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return fmt.Errorf("%s: %w", journalFile, err)
+	}
+	if err := journal.Rollback(c.fs, c.mutator); err != nil {
+		return err
+	}
+	if c.DryRun {
+		return nil
+	}
+	return c.fs.Remove(journalFile)
+}
+
+// applyPlan makes the changes in the plan file.
+func (c *Config) applyPlan(args []string, persistentState chezmoi.PersistentState) error {
+	data, err := c.readFileOrStdin(c.Apply.fromPlan)
+	if err != nil {
+		return err
+	}
+	var plan chezmoi.Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return fmt.Errorf("%s: %w", c.Apply.fromPlan, err)
+	}
+	applyOptions := &chezmoi.ApplyOptions{
+		DestDir:           c.DestDir,
+		DryRun:            c.DryRun,
+		PersistentState:   persistentState,
+		Redactor:          c.getRedactor(),
+		ScriptStateBucket: c.scriptStateBucket,
+		Stdout:            c.Stdout,
+		Verbose:           c.Verbose,
+	}
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		return plan.Execute(vfs.NewReadOnlyFS(c.fs), mutator, applyOptions)
+	})
+}
+
+// writePlan writes a plan of the changes needed to apply args to the plan
+// file, without making them.
+func (c *Config) writePlan(args []string, persistentState chezmoi.PersistentState) error {
+	ts, err := c.getTargetState(nil)
+	if err != nil {
+		return err
+	}
+	plan := chezmoi.NewPlan(ts.DestDir)
+	applyOptions, err := c.getApplyOptions(ts, persistentState)
+	if err != nil {
+		return err
+	}
+	applyOptions.Plan = plan
+	if err := c.applyTargetState(ts, args, chezmoi.NewPlanMutator(vfs.NewReadOnlyFS(c.fs), plan), applyOptions); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if c.Apply.plan == "-" {
+		_, err = c.Stdout.Write(data)
+		return err
+	}
+	// Plans contain the contents of files, which may include secrets.
+	return c.fs.WriteFile(c.Apply.plan, data, 0o600)
 }
@@ -31,9 +31,14 @@ func init() {
 	persistentFlags := archiveCmd.PersistentFlags()
 	persistentFlags.StringVarP(&config.archive.output, "output", "o", "", "output filename")
 	panicOnError(archiveCmd.MarkPersistentFlagFilename("output"))
+	addFilterFlags(archiveCmd)
 }
 
 func (c *Config) runArchiveCmd(cmd *cobra.Command, args []string) error {
+	filter, err := c.getFilter()
+	if err != nil {
+		return err
+	}
 	ts, err := c.getTargetState(nil)
 	if err != nil {
 		return err
@@ -41,7 +46,7 @@ func (c *Config) runArchiveCmd(cmd *cobra.Command, args []string) error {
 
 	output := &strings.Builder{}
 	w := tar.NewWriter(output)
-	if err := ts.Archive(w, os.FileMode(c.Umask)); err != nil {
+	if err := ts.Archive(w, filter, os.FileMode(c.Umask)); err != nil {
 		return err
 	}
 	if err := w.Close(); err != nil {
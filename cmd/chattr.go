@@ -71,8 +71,14 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string) error {
 
 	updates := make(map[string]func() error)
 	for _, entry := range entries {
+		sourceDir := ts.SourceDirOf(entry)
 		dir, oldBase := filepath.Split(entry.SourceName())
-		oldpath := filepath.Join(ts.SourceDir, dir, oldBase)
+		oldpath := filepath.Join(sourceDir, dir, oldBase)
+		// Top level source names keep their XDG prefix.
+		xdgPrefix := ""
+		if dir == "" {
+			xdgPrefix, oldBase = chezmoi.SplitXDGPrefix(oldBase)
+		}
 		switch entry := entry.(type) {
 		case *chezmoi.Dir:
 			da := chezmoi.ParseDirAttributes(oldBase)
@@ -84,7 +90,7 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string) error {
 			da.Perm = perm
 			newBase := da.SourceName()
 			if newBase != oldBase {
-				newpath := filepath.Join(ts.SourceDir, dir, newBase)
+				newpath := filepath.Join(sourceDir, dir, xdgPrefix+newBase)
 				updates[oldpath] = func() error {
 					return c.mutator.Rename(oldpath, newpath)
 				}
@@ -102,9 +108,9 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string) error {
 			fa.Encrypted = ams.encrypt.modify(entry.Encrypted)
 			fa.Empty = ams.empty.modify(entry.Empty)
 			fa.Template = ams.template.modify(entry.Template)
-			newpath := filepath.Join(ts.SourceDir, dir, fa.SourceName())
+			newpath := filepath.Join(sourceDir, dir, xdgPrefix+fa.SourceName())
 			if fa.Encrypted != entry.Encrypted {
-				oldContents, err := c.fs.ReadFile(filepath.Join(c.SourceDir, entry.SourceName()))
+				oldContents, err := c.fs.ReadFile(oldpath)
 				if err != nil {
 					return err
 				}
@@ -135,7 +141,7 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string) error {
 			fa.Template = ams.template.modify(entry.Template)
 			newBase := fa.SourceName()
 			if newBase != oldBase {
-				newpath := filepath.Join(ts.SourceDir, dir, newBase)
+				newpath := filepath.Join(sourceDir, dir, xdgPrefix+newBase)
 				updates[oldpath] = func() error {
 					return c.mutator.Rename(oldpath, newpath)
 				}
@@ -156,6 +162,7 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string) error {
 		if err := updates[oldpath](); err != nil {
 			return err
 		}
+		c.sourceLayerChanged(oldpath)
 	}
 	return nil
 }
@@ -6,13 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"text/template"
 	"unicode"
 
@@ -52,22 +55,30 @@ type Config struct {
 	fs                vfs.FS
 	mutator           chezmoi.Mutator
 	SourceDir         string
+	SourceLayers      []string
 	DestDir           string
 	Umask             permValue
 	DryRun            bool
 	Follow            bool
 	Remove            bool
+	Roots             []rootConfig
 	Verbose           bool
 	Color             string
 	Debug             bool
 	GPG               chezmoi.GPG
 	GPGRecipient      string
+	Age               ageConfig
+	Sops              sopsConfig
+	EncryptedData     map[string]encryptedDataConfig
 	SourceVCS         sourceVCSConfig
 	Template          templateConfig
 	Merge             mergeConfig
+	Apply             applyCmdConfig
+	Backup            backupConfig
 	Bitwarden         bitwardenCmdConfig
 	CD                cdCmdConfig
 	Diff              diffCmdConfig
+	Drift             driftConfig
 	GenericSecret     genericSecretCmdConfig
 	Gopass            gopassCmdConfig
 	KeePassXC         keePassXCCmdConfig
@@ -75,10 +86,18 @@ type Config struct {
 	Onepassword       onepasswordCmdConfig
 	Vault             vaultCmdConfig
 	Pass              passCmdConfig
+	SecretProviders   map[string]secretProviderConfig
+	SecretCache       secretCacheConfig
 	Data              map[string]interface{}
+	Parallelism       int
+	OutputFormat      string
 	colored           bool
+	noRedact          bool
+	filter            filterConfig
+	redactor          *chezmoi.Redactor
 	maxDiffDataSize   int
 	templateFuncs     template.FuncMap
+	secretFuncNames   map[string]bool
 	add               addCmdConfig
 	archive           archiveCmdConfig
 	completion        completionCmdConfig
@@ -92,13 +111,37 @@ type Config struct {
 	managed           managedCmdConfig
 	purge             purgeCmdConfig
 	remove            removeCmdConfig
+	restore           restoreCmdConfig
+	secretAudit       secretAuditCmdConfig
+	secretStore       secretStoreCmdConfig
 	update            updateCmdConfig
 	upgrade           upgradeCmdConfig
+	watch             watchCmdConfig
 	Stdin             io.Reader
 	Stdout            io.Writer
 	Stderr            io.Writer
 	bds               *xdg.BaseDirectorySpecification
 	scriptStateBucket []byte
+	secretCacheBucket []byte
+
+	// persistentState is the most recently opened persistent state, shared
+	// with the secret cache.
+	persistentState         chezmoi.PersistentState
+	persistentStateReadOnly bool
+
+	// changedSourceLayers are the source layers changed by the command, which
+	// are committed and pushed by autoCommitAndAutoPush. If it is empty then
+	// the source directory is committed and pushed.
+	changedSourceLayers []string
+
+	// secretCachePending contains encrypted secret cache entries, keyed by
+	// persistent state key, that could not be written because the persistent
+	// state was read-only. They are written when the command finishes.
+	secretCachePending map[string][]byte
+
+	// secretMutex serializes calls to secret managers, as they may prompt the
+	// user and templates may be executed concurrently.
+	secretMutex sync.Mutex
 }
 
 // A configOption sets an option on a Config.
@@ -151,9 +194,12 @@ func newConfig(options ...configOption) *Config {
 		GPG: chezmoi.GPG{
 			Command: "gpg",
 		},
+		Parallelism:       1,
 		maxDiffDataSize:   1 * 1024 * 1024, // 1MB
 		templateFuncs:     sprig.TxtFuncMap(),
 		scriptStateBucket: []byte("script"),
+		secretCacheBucket: []byte("secretCache"),
+		redactor:          chezmoi.NewRedactor(),
 		Stdin:             os.Stdin,
 		Stdout:            os.Stdout,
 		Stderr:            os.Stderr,
@@ -174,47 +220,73 @@ func (c *Config) addTemplateFunc(key string, value interface{}) {
 	c.templateFuncs[key] = value
 }
 
+// addSecretTemplateFunc adds a template function that returns secrets. The
+// secrets returned by value are remembered so that they can be redacted, and
+// only one secret template function is called at a time.
+func (c *Config) addSecretTemplateFunc(key string, value interface{}) {
+	if c.secretFuncNames == nil {
+		c.secretFuncNames = make(map[string]bool)
+	}
+	c.secretFuncNames[key] = true
+	fn := reflect.ValueOf(value)
+	c.addTemplateFunc(key, reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		c.secretMutex.Lock()
+		defer c.secretMutex.Unlock()
+		var results []reflect.Value
+		if fn.Type().IsVariadic() {
+			results = fn.CallSlice(args)
+		} else {
+			results = fn.Call(args)
+		}
+		c.redactor.AddValue(results[0].Interface())
+		return results
+	}).Interface())
+}
+
 func (c *Config) applyArgs(args []string, persistentState chezmoi.PersistentState) error {
-	fs := vfs.NewReadOnlyFS(c.fs)
 	ts, err := c.getTargetState(nil)
 	if err != nil {
 		return err
 	}
-	applyOptions := &chezmoi.ApplyOptions{
-		DestDir:           ts.DestDir,
-		DryRun:            c.DryRun,
-		Ignore:            ts.TargetIgnore.Match,
-		PersistentState:   persistentState,
-		Remove:            c.Remove,
-		ScriptStateBucket: c.scriptStateBucket,
-		Stdout:            c.Stdout,
-		Umask:             ts.Umask,
-		Verbose:           c.Verbose,
+	applyOptions, err := c.getApplyOptions(ts, persistentState)
+	if err != nil {
+		return err
 	}
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		return c.withEvents(ts, args, mutator, applyOptions, func(mutator chezmoi.Mutator) error {
+			return c.applyTargetState(ts, args, mutator, applyOptions)
+		})
+	})
+}
+
+// applyTargetState applies the targets args in ts, or all targets if args is
+// empty, with mutator.
+func (c *Config) applyTargetState(ts *chezmoi.TargetState, args []string, mutator chezmoi.Mutator, applyOptions *chezmoi.ApplyOptions) error {
 	if len(args) == 0 {
-		return ts.Apply(fs, c.mutator, c.Follow, applyOptions)
+		return ts.Apply(vfs.NewReadOnlyFS(c.fs), mutator, c.Follow, applyOptions)
 	}
 	entries, err := c.getEntries(ts, args)
 	if err != nil {
 		return err
 	}
-	for _, entry := range entries {
-		if err := entry.Apply(fs, c.mutator, c.Follow, applyOptions); err != nil {
-			return err
-		}
-	}
-	return nil
+	return c.applyEntries(ts, entries, mutator, applyOptions)
+}
+
+// applyEntries applies entries in ts with mutator.
+func (c *Config) applyEntries(ts *chezmoi.TargetState, entries []chezmoi.Entry, mutator chezmoi.Mutator, applyOptions *chezmoi.ApplyOptions) error {
+	return ts.ApplyEntries(vfs.NewReadOnlyFS(c.fs), mutator, c.Follow, entries, applyOptions)
 }
 
-func (c *Config) autoCommit(vcs VCS) error {
+// autoCommit commits all changes in the source layer sourceDir.
+func (c *Config) autoCommit(vcs VCS, sourceDir string) error {
 	addArgs := vcs.AddArgs(".")
 	if addArgs == nil {
 		return fmt.Errorf("%s: autocommit not supported", c.SourceVCS.Command)
 	}
-	if err := c.run(c.SourceDir, c.SourceVCS.Command, addArgs...); err != nil {
+	if err := c.run(sourceDir, c.SourceVCS.Command, addArgs...); err != nil {
 		return err
 	}
-	output, err := c.output(c.SourceDir, c.SourceVCS.Command, vcs.StatusArgs()...)
+	output, err := c.output(sourceDir, c.SourceVCS.Command, vcs.StatusArgs()...)
 	if err != nil {
 		return err
 	}
@@ -235,7 +307,7 @@ func (c *Config) autoCommit(vcs VCS) error {
 		return err
 	}
 	commitArgs := vcs.CommitArgs(sb.String())
-	return c.run(c.SourceDir, c.SourceVCS.Command, commitArgs...)
+	return c.run(sourceDir, c.SourceVCS.Command, commitArgs...)
 }
 
 func (c *Config) autoCommitAndAutoPush(cmd *cobra.Command, args []string) error {
@@ -246,25 +318,54 @@ func (c *Config) autoCommitAndAutoPush(cmd *cobra.Command, args []string) error
 	if c.DryRun {
 		return nil
 	}
-	if c.SourceVCS.AutoCommit || c.SourceVCS.AutoPush {
-		if err := c.autoCommit(vcs); err != nil {
-			return err
-		}
+	sourceDirs := c.changedSourceLayers
+	if len(sourceDirs) == 0 {
+		sourceDirs = []string{c.SourceDir}
 	}
-	if c.SourceVCS.AutoPush {
-		if err := c.autoPush(vcs); err != nil {
-			return err
+	c.changedSourceLayers = nil
+	for _, sourceDir := range sourceDirs {
+		if c.SourceVCS.AutoCommit || c.SourceVCS.AutoPush {
+			if err := c.autoCommit(vcs, sourceDir); err != nil {
+				return err
+			}
+		}
+		if c.SourceVCS.AutoPush {
+			if err := c.autoPush(vcs, sourceDir); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-func (c *Config) autoPush(vcs VCS) error {
+// autoPush pushes the source layer sourceDir.
+func (c *Config) autoPush(vcs VCS, sourceDir string) error {
 	pushArgs := vcs.PushArgs()
 	if pushArgs == nil {
 		return fmt.Errorf("%s: autopush not supported", c.SourceVCS.Command)
 	}
-	return c.run(c.SourceDir, c.SourceVCS.Command, pushArgs...)
+	return c.run(sourceDir, c.SourceVCS.Command, pushArgs...)
+}
+
+// sourceLayerChanged records that path, which is in a source layer, was
+// changed, so that its source layer is committed and pushed by
+// autoCommitAndAutoPush.
+func (c *Config) sourceLayerChanged(path string) {
+	layer := ""
+	for _, sourceDir := range c.getSourceDirs() {
+		if (path == sourceDir || strings.HasPrefix(path, sourceDir+string(os.PathSeparator))) && len(sourceDir) > len(layer) {
+			layer = sourceDir
+		}
+	}
+	if layer == "" {
+		layer = c.SourceDir
+	}
+	for _, changedSourceLayer := range c.changedSourceLayers {
+		if changedSourceLayer == layer {
+			return
+		}
+	}
+	c.changedSourceLayers = append(c.changedSourceLayers, layer)
 }
 
 // ensureNoError ensures that no error was encountered when loading c.
@@ -407,6 +508,46 @@ func (c *Config) getEntries(ts *chezmoi.TargetState, args []string) ([]chezmoi.E
 	return entries, nil
 }
 
+// getApplyOptions returns the options for applying ts.
+func (c *Config) getApplyOptions(ts *chezmoi.TargetState, persistentState chezmoi.PersistentState) (*chezmoi.ApplyOptions, error) {
+	filter, err := c.getFilter()
+	if err != nil {
+		return nil, err
+	}
+	return &chezmoi.ApplyOptions{
+		DestDir:           ts.DestDir,
+		DryRun:            c.DryRun,
+		Filter:            filter,
+		Ignore:            ts.TargetIgnore.Match,
+		KeepGoing:         c.Apply.KeepGoing,
+		PersistentState:   persistentState,
+		Redactor:          c.getRedactor(),
+		Remove:            c.Remove,
+		ScriptStateBucket: c.scriptStateBucket,
+		Stdout:            c.Stdout,
+		Umask:             ts.Umask,
+		Verbose:           c.Verbose && c.OutputFormat != outputFormatJSON,
+	}, nil
+}
+
+// getGPG returns c's GPG configuration.
+func (c *Config) getGPG() *chezmoi.GPG {
+	// For backwards compatibility, prioritize gpgRecipient over gpg.recipient.
+	if c.GPGRecipient != "" {
+		c.GPG.Recipient = c.GPGRecipient
+	}
+	return &c.GPG
+}
+
+// getRedactor returns the redactor used to mask secrets in human-facing output,
+// or nil if redaction is disabled.
+func (c *Config) getRedactor() *chezmoi.Redactor {
+	if c.noRedact {
+		return nil
+	}
+	return c.redactor
+}
+
 func (c *Config) getPersistentState(options *bolt.Options) (chezmoi.PersistentState, error) {
 	persistentStateFile := c.getPersistentStateFile()
 	if c.DryRun {
@@ -415,7 +556,20 @@ func (c *Config) getPersistentState(options *bolt.Options) (chezmoi.PersistentSt
 		}
 		options.ReadOnly = true
 	}
-	return chezmoi.NewBoltPersistentState(c.fs, persistentStateFile, os.FileMode(c.Umask), options)
+	// bolt blocks when the same database is opened twice, so close any
+	// persistent state that is already open, for example by the secret cache.
+	if c.persistentState != nil {
+		if err := c.persistentState.Close(); err != nil {
+			return nil, err
+		}
+	}
+	persistentState, err := chezmoi.NewBoltPersistentState(c.fs, persistentStateFile, os.FileMode(c.Umask), options)
+	if err != nil {
+		return nil, err
+	}
+	c.persistentState = persistentState
+	c.persistentStateReadOnly = options != nil && options.ReadOnly
+	return persistentState, nil
 }
 
 func (c *Config) getPersistentStateFile() string {
@@ -431,7 +585,53 @@ func (c *Config) getPersistentStateFile() string {
 	return filepath.Join(filepath.Dir(getDefaultConfigFile(c.bds)), "chezmoistate.boltdb")
 }
 
+// getLayerTargetState returns the target state of the single source directory
+// layer, which is c.SourceDir if layer is empty.
+func (c *Config) getLayerTargetState(layer string, populateOptions *chezmoi.PopulateOptions) (*chezmoi.TargetState, error) {
+	sourceDir, err := c.getSourceLayer(layer)
+	if err != nil {
+		return nil, err
+	}
+	return c.newTargetState(sourceDir, nil, populateOptions)
+}
+
+// getSourceDirs returns all source directories, from lowest to highest
+// priority.
+func (c *Config) getSourceDirs() []string {
+	sourceDirs := make([]string, 0, len(c.SourceLayers)+1)
+	sourceDirs = append(sourceDirs, c.SourceLayers...)
+	return append(sourceDirs, c.SourceDir)
+}
+
+// getSourceLayer returns the source directory of layer, which must be
+// c.SourceDir or one of c.SourceLayers. An empty layer is c.SourceDir.
+func (c *Config) getSourceLayer(layer string) (string, error) {
+	if layer == "" {
+		return c.SourceDir, nil
+	}
+	absLayer, err := filepath.Abs(layer)
+	if err != nil {
+		return "", err
+	}
+	for _, sourceDir := range c.getSourceDirs() {
+		absSourceDir, err := filepath.Abs(sourceDir)
+		if err != nil {
+			return "", err
+		}
+		if absSourceDir == absLayer {
+			return sourceDir, nil
+		}
+	}
+	return "", fmt.Errorf("%s: not a source layer", layer)
+}
+
 func (c *Config) getTargetState(populateOptions *chezmoi.PopulateOptions) (*chezmoi.TargetState, error) {
+	return c.newTargetState(c.SourceDir, c.SourceLayers, populateOptions)
+}
+
+// newTargetState returns the target state of sourceDir layered over
+// sourceLayers.
+func (c *Config) newTargetState(sourceDir string, sourceLayers []string, populateOptions *chezmoi.PopulateOptions) (*chezmoi.TargetState, error) {
 	fs := vfs.NewReadOnlyFS(c.fs)
 
 	data, err := c.getData()
@@ -447,23 +647,40 @@ func (c *Config) getTargetState(populateOptions *chezmoi.PopulateOptions) (*chez
 		}
 	}
 
-	// For backwards compatibility, prioritize gpgRecipient over gpg.recipient.
-	if c.GPGRecipient != "" {
-		c.GPG.Recipient = c.GPGRecipient
+	// Roots share all options except their destination directory and umask.
+	roots, inactiveRoots, err := c.getRoots([]chezmoi.TargetStateOption{
+		chezmoi.WithGPG(c.getGPG()),
+		chezmoi.WithLazyTemplateData(c.getLazyTemplateData()),
+		chezmoi.WithParallelism(c.Parallelism),
+		chezmoi.WithTemplateData(data),
+		chezmoi.WithTemplateFuncs(c.templateFuncs),
+		chezmoi.WithTemplateOptions(c.Template.Options),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	ts := chezmoi.NewTargetState(
 		chezmoi.WithDestDir(destDir),
-		chezmoi.WithGPG(&c.GPG),
-		chezmoi.WithSourceDir(c.SourceDir),
+		chezmoi.WithGPG(c.getGPG()),
+		chezmoi.WithInactiveRoots(inactiveRoots),
+		chezmoi.WithLazyTemplateData(c.getLazyTemplateData()),
+		chezmoi.WithParallelism(c.Parallelism),
+		chezmoi.WithRoots(roots),
+		chezmoi.WithSourceDir(sourceDir),
+		chezmoi.WithSourceLayers(sourceLayers),
 		chezmoi.WithTemplateData(data),
 		chezmoi.WithTemplateFuncs(c.templateFuncs),
 		chezmoi.WithTemplateOptions(c.Template.Options),
 		chezmoi.WithUmask(os.FileMode(c.Umask)),
+		chezmoi.WithXDG(c.bds),
 	)
 	if err := ts.Populate(fs, populateOptions); err != nil {
 		return nil, err
 	}
+	for _, skippedXDGEntry := range ts.SkippedXDGEntries {
+		fmt.Fprintf(c.Stderr, "warning: %s: skipping, %s is not in the destination directory\n", skippedXDGEntry.SourcePath, skippedXDGEntry.XDGDir)
+	}
 	if Version != nil && ts.MinVersion != nil && Version.LessThan(*ts.MinVersion) {
 		return nil, fmt.Errorf("chezmoi version %s too old, source state requires at least %s", Version, ts.MinVersion)
 	}
@@ -509,6 +726,15 @@ func (c *Config) prompt(s, choices string) (byte, error) {
 	}
 }
 
+// readFileOrStdin returns the contents of filename, or of c.Stdin if filename
+// is -.
+func (c *Config) readFileOrStdin(filename string) ([]byte, error) {
+	if filename == "-" {
+		return ioutil.ReadAll(c.Stdin)
+	}
+	return c.fs.ReadFile(filename)
+}
+
 // run runs name argv... in dir.
 func (c *Config) run(dir, name string, argv ...string) error {
 	cmd := exec.Command(name, argv...)
@@ -210,10 +210,16 @@ func withStdout(stdout io.Writer) configOption {
 	}
 }
 
+func withSecretCacheConfig(secretCache secretCacheConfig) configOption {
+	return func(c *Config) {
+		c.SecretCache = secretCache
+	}
+}
+
 func withTestFS(fs vfs.FS) configOption {
 	return func(c *Config) {
 		c.fs = fs
-		c.mutator = chezmoi.NewVerboseMutator(os.Stdout, chezmoi.NewFSMutator(fs), false, 0)
+		c.mutator = chezmoi.NewVerboseMutator(os.Stdout, chezmoi.NewFSMutator(fs), false, 0, nil)
 		c.Verbose = true
 	}
 }
@@ -232,3 +238,9 @@ func withTestUser(username string) configOption {
 		}
 	}
 }
+
+func withWatchCmdConfig(watchCmdConfig watchCmdConfig) configOption {
+	return func(c *Config) {
+		c.watch = watchCmdConfig
+	}
+}
@@ -38,6 +38,7 @@ func init() {
 	persistentFlags := diffCmd.PersistentFlags()
 	persistentFlags.StringVarP(&config.Diff.Format, "format", "f", config.Diff.Format, "format, \"chezmoi\" or \"git\"")
 	persistentFlags.BoolVar(&config.Diff.NoPager, "no-pager", false, "disable pager")
+	addFilterFlags(diffCmd)
 
 	markRemainingZshCompPositionalArgumentsAsFiles(diffCmd, 1)
 }
@@ -45,16 +46,16 @@ func init() {
 func (c *Config) runDiffCmd(cmd *cobra.Command, args []string) error {
 	c.DryRun = true // Prevent scripts from running.
 
-	switch c.Diff.Format {
-	case "chezmoi":
+	switch {
+	case c.OutputFormat == outputFormatJSON || c.Diff.Format == "chezmoi":
 		c.mutator = chezmoi.NullMutator{}
-	case "git":
+	case c.Diff.Format == "git":
 		c.mutator = chezmoi.NewFSMutator(vfs.NewReadOnlyFS(c.fs))
 	default:
 		return fmt.Errorf("unknown diff format: %q", c.Diff.Format)
 	}
 	if c.Debug {
-		c.mutator = chezmoi.NewDebugMutator(c.mutator)
+		c.mutator = chezmoi.NewDebugMutator(c.mutator, c.getRedactor())
 	}
 
 	persistentState, err := c.getPersistentState(&bolt.Options{
@@ -65,16 +66,22 @@ func (c *Config) runDiffCmd(cmd *cobra.Command, args []string) error {
 	}
 	defer persistentState.Close()
 
+	// In JSON output mode, the changes that would be made are reported as
+	// events.
+	if c.OutputFormat == outputFormatJSON {
+		return c.applyArgs(args, persistentState)
+	}
+
 	if c.Diff.NoPager || c.Diff.Pager == "" {
 		switch c.Diff.Format {
 		case "chezmoi":
-			c.mutator = chezmoi.NewVerboseMutator(c.Stdout, c.mutator, c.colored, c.maxDiffDataSize)
+			c.mutator = chezmoi.NewVerboseMutator(c.Stdout, c.mutator, c.colored, c.maxDiffDataSize, c.getRedactor())
 		case "git":
 			unifiedEncoder := diff.NewUnifiedEncoder(c.Stdout, diff.DefaultContextLines)
 			if c.colored {
 				unifiedEncoder.SetColor(diff.NewColorConfig())
 			}
-			c.mutator = chezmoi.NewGitDiffMutator(unifiedEncoder, c.mutator, c.DestDir+string(filepath.Separator))
+			c.mutator = chezmoi.NewGitDiffMutator(unifiedEncoder, c.mutator, c.DestDir+string(filepath.Separator), c.getRedactor())
 		}
 		return c.applyArgs(args, persistentState)
 	}
@@ -105,13 +112,13 @@ func (c *Config) runDiffCmd(cmd *cobra.Command, args []string) error {
 
 	switch c.Diff.Format {
 	case "chezmoi":
-		c.mutator = chezmoi.NewVerboseMutator(pagerStdinPipe, c.mutator, c.colored, c.maxDiffDataSize)
+		c.mutator = chezmoi.NewVerboseMutator(pagerStdinPipe, c.mutator, c.colored, c.maxDiffDataSize, c.getRedactor())
 	case "git":
 		unifiedEncoder := diff.NewUnifiedEncoder(pagerStdinPipe, diff.DefaultContextLines)
 		if c.colored {
 			unifiedEncoder.SetColor(diff.NewColorConfig())
 		}
-		c.mutator = chezmoi.NewGitDiffMutator(unifiedEncoder, c.mutator, c.DestDir+string(filepath.Separator))
+		c.mutator = chezmoi.NewGitDiffMutator(unifiedEncoder, c.mutator, c.DestDir+string(filepath.Separator), c.getRedactor())
 	}
 
 	if err := c.applyArgs(args, persistentState); err != nil {
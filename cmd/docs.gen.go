@@ -527,6 +527,7 @@ func init() {
 		"  * [Use Bitwarden to keep your secrets](#use-bitwarden-to-keep-your-secrets)\n" +
 		"  * [Use gopass to keep your secrets](#use-gopass-to-keep-your-secrets)\n" +
 		"  * [Use gpg to keep your secrets](#use-gpg-to-keep-your-secrets)\n" +
+		"  * [Use chezmoi's secret store to keep your secrets](#use-chezmois-secret-store-to-keep-your-secrets)\n" +
 		"  * [Use KeePassXC to keep your secrets](#use-keepassxc-to-keep-your-secrets)\n" +
 		"  * [Use a keyring to keep your secrets](#use-a-keyring-to-keep-your-secrets)\n" +
 		"  * [Use LastPass to keep your secrets](#use-lastpass-to-keep-your-secrets)\n" +
@@ -534,12 +535,17 @@ func init() {
 		"  * [Use pass to keep your secrets](#use-pass-to-keep-your-secrets)\n" +
 		"  * [Use Vault to keep your secrets](#use-vault-to-keep-your-secrets)\n" +
 		"  * [Use a generic tool to keep your secrets](#use-a-generic-tool-to-keep-your-secrets)\n" +
+		"  * [Write a secret provider to keep your secrets](#write-a-secret-provider-to-keep-your-secrets)\n" +
 		"  * [Use templates variables to keep your secrets](#use-templates-variables-to-keep-your-secrets)\n" +
+		"  * [Use encrypted data files to keep your secrets](#use-encrypted-data-files-to-keep-your-secrets)\n" +
+		"  * [Audit which targets use which secrets](#audit-which-targets-use-which-secrets)\n" +
+		"  * [Cache secrets to avoid repeatedly unlocking your secret manager](#cache-secrets-to-avoid-repeatedly-unlocking-your-secret-manager)\n" +
 		"* [Use scripts to perform actions](#use-scripts-to-perform-actions)\n" +
 		"  * [Understand how scripts work](#understand-how-scripts-work)\n" +
 		"  * [Install packages with scripts](#install-packages-with-scripts)\n" +
 		"* [Import archives](#import-archives)\n" +
 		"* [Export archives](#export-archives)\n" +
+		"* [Review changes before applying them](#review-changes-before-applying-them)\n" +
 		"* [Use a non-git version control system](#use-a-non-git-version-control-system)\n" +
 		"* [Customize the `diff` command](#customize-the-diff-command)\n" +
 		"* [Use a merge tool other than vimdiff](#use-a-merge-tool-other-than-vimdiff)\n" +
@@ -922,6 +928,10 @@ func init() {
 		"There are several ways to keep these tokens secure, and to prevent them leaving\n" +
 		"your machine.\n" +
 		"\n" +
+		"Values returned by secret template functions are redacted as `********` in the\n" +
+		"output of `chezmoi diff`, `--verbose`, and `--debug`, so that they do not end up\n" +
+		"in your terminal's scrollback or in CI logs. Pass `--no-redact` to see them.\n" +
+		"\n" +
 		"### Use Bitwarden to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for [Bitwarden](https://bitwarden.com/) using the\n" +
@@ -944,6 +954,16 @@ func init() {
 		"    username = {{ (bitwarden \"item\" \"example.com\").login.username }}\n" +
 		"    password = {{ (bitwarden \"item\" \"example.com\").login.password }}\n" +
 		"\n" +
+		"Custom fields are available by name with the `bitwardenFields` template\n" +
+		"function, for example:\n" +
+		"\n" +
+		"    token = {{ (bitwardenFields \"example.com\").token.value }}\n" +
+		"\n" +
+		"Attachments, for example SSH keys, are available with the `bitwardenAttachment`\n" +
+		"template function, for example in `private_dot_ssh/private_id_rsa.tmpl`:\n" +
+		"\n" +
+		"    {{- bitwardenAttachment \"id_rsa\" \"bf22e4b4-ae4a-4d1c-8c98-ac620004b628\" -}}\n" +
+		"\n" +
 		"### Use gopass to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for [gopass](https://www.gopass.pw/) using the gopass CLI.\n" +
@@ -995,6 +1015,22 @@ func init() {
 		"\n" +
 		"    gpg --armor --symmetric\n" +
 		"\n" +
+		"### Use chezmoi's secret store to keep your secrets\n" +
+		"\n" +
+		"If you do not have a password manager installed, chezmoi can store secrets\n" +
+		"itself in a GPG-encrypted file called `.chezmoisecrets` in the source directory.\n" +
+		"Configure GPG as described above, then add secrets with:\n" +
+		"\n" +
+		"    chezmoi secret set github-token\n" +
+		"\n" +
+		"You will be prompted for the value. Secrets can be listed with `chezmoi secret\n" +
+		"list` and removed with `chezmoi secret rm`. Secrets are available as the\n" +
+		"`secretStore` template function, for example:\n" +
+		"\n" +
+		"    token = {{ secretStore \"github-token\" }}\n" +
+		"\n" +
+		"As `.chezmoisecrets` is encrypted, it is safe to commit it to your dotfiles repo.\n" +
+		"\n" +
 		"### Use KeePassXC to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for [KeePassXC](https://keepassxc.org) using the\n" +
@@ -1017,6 +1053,23 @@ func init() {
 		"\n" +
 		"    {{ keepassxcAttribute \"SSH Key\" \"private-key\" }}\n" +
 		"\n" +
+		"Attachments are available through the `keepassxcAttachment` function, for\n" +
+		"example:\n" +
+		"\n" +
+		"    {{ keepassxcAttachment \"SSH Key\" \"id_rsa\" }}\n" +
+		"\n" +
+		"If you do not have `keepassxc-cli` installed, chezmoi can read KDBX 3 and 4\n" +
+		"databases itself. Set `keepassxc.mode` to `builtin` and, if your database is\n" +
+		"protected by a key file, set `keepassxc.keyFile`:\n" +
+		"\n" +
+		"    [keepassxc]\n" +
+		"      database = \"/home/user/Passwords.kdbx\"\n" +
+		"      keyFile = \"/home/user/Passwords.keyx\"\n" +
+		"      mode = \"builtin\"\n" +
+		"\n" +
+		"You will be prompted for the database password once. If you use a key file\n" +
+		"without a password, press enter at the prompt.\n" +
+		"\n" +
 		"### Use a keyring to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for Keychain (on macOS), GNOME Keyring (on Linux), and\n" +
@@ -1046,6 +1099,20 @@ func init() {
 		"\n" +
 		"    chezmoi keyring get --service=github --user=<github-username>\n" +
 		"\n" +
+		"To set passwords from a script, read the password from a file or, with `-`, from\n" +
+		"the standard input:\n" +
+		"\n" +
+		"    echo \"$GITHUB_TOKEN\" | chezmoi secret keyring set --service=github --user=<github-username> --password-file=-\n" +
+		"\n" +
+		"Many passwords can be imported at once from a JSON file containing an array of\n" +
+		"objects with `service`, `user`, and `password` keys:\n" +
+		"\n" +
+		"    chezmoi secret keyring import passwords.json\n" +
+		"\n" +
+		"and passwords can be removed with:\n" +
+		"\n" +
+		"    chezmoi secret keyring delete --service=github --user=<github-username>\n" +
+		"\n" +
 		"### Use LastPass to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for [LastPass](https://lastpass.com) using the\n" +
@@ -1110,6 +1177,20 @@ func init() {
 		"\n" +
 		"    {{ (onepassword \"<uuid>\").details.password }}\n" +
 		"\n" +
+		"As the layout of 1Password's items can change, it is more robust to use the\n" +
+		"`onepasswordFields` template function, which returns a flat map of the item's\n" +
+		"fields keyed by designation or label:\n" +
+		"\n" +
+		"    {{ (onepasswordFields \"<uuid>\").password }}\n" +
+		"\n" +
+		"Items in a specific vault or account can be retrieved by passing the vault and\n" +
+		"account as extra arguments:\n" +
+		"\n" +
+		"    {{ (onepasswordFields \"<uuid>\" \"<vault>\" \"<account>\").password }}\n" +
+		"\n" +
+		"If you have not signed in, chezmoi will run `op signin` for you, prompting for\n" +
+		"your password once per run.\n" +
+		"\n" +
 		"Documents can be retrieved with:\n" +
 		"\n" +
 		"    {{- onepasswordDocument \"uuid\" -}}\n" +
@@ -1129,6 +1210,16 @@ func init() {
 		"\n" +
 		"    {{ pass \"<pass-name>\" }}\n" +
 		"\n" +
+		"If your pass entries contain extra `key: value` lines after the password, they\n" +
+		"are available with the `passFields` template function, for example:\n" +
+		"\n" +
+		"    login = {{ (passFields \"<pass-name>\").login }}\n" +
+		"    password = {{ (passFields \"<pass-name>\").password }}\n" +
+		"\n" +
+		"The full entry is available with `passRaw` and, if you use\n" +
+		"[pass-otp](https://github.com/tadfisher/pass-otp), the current one time password\n" +
+		"is available with `passOTP`.\n" +
+		"\n" +
 		"### Use Vault to keep your secrets\n" +
 		"\n" +
 		"chezmoi includes support for [Vault](https://www.vaultproject.io/) using the\n" +
@@ -1147,6 +1238,17 @@ func init() {
 		"\n" +
 		"    {{ (vault \"<key>\").data.data.password }}\n" +
 		"\n" +
+		"The `vaultKV` and `vaultKVField` template functions hide the differences between\n" +
+		"versions 1 and 2 of the KV secrets engine, and let you pin a secret's version,\n" +
+		"for example:\n" +
+		"\n" +
+		"    {{ vaultKVField \"secret\" \"<path>\" \"password\" }}\n" +
+		"    {{ vaultKVField \"secret\" \"<path>\" \"password\" 3 }}\n" +
+		"\n" +
+		"If you use [Vault\n" +
+		"namespaces](https://www.vaultproject.io/docs/enterprise/namespaces), set\n" +
+		"`VAULT_NAMESPACE` or `vault.namespace` in your config file.\n" +
+		"\n" +
 		"### Use a generic tool to keep your secrets\n" +
 		"\n" +
 		"You can use any command line tool that outputs secrets either as a string or in\n" +
@@ -1165,6 +1267,37 @@ func init() {
 		"| KeePassXC       | `keepassxc-cli`         | Not possible (interactive command only)           |\n" +
 		"| pass            | `pass`                  | `{{ secret \"show\" <id> }}`                        |\n" +
 		"\n" +
+		"### Write a secret provider to keep your secrets\n" +
+		"\n" +
+		"If your password manager is not supported, you can integrate it without changing\n" +
+		"chezmoi by writing a secret provider: a small program that reads a JSON request\n" +
+		"on its standard input and writes a JSON response to its standard output. Any\n" +
+		"number of secret providers can be configured, each with a name, for example:\n" +
+		"\n" +
+		"    [secretProviders.myvault]\n" +
+		"      command = \"myvault-chezmoi\"\n" +
+		"\n" +
+		"chezmoi sends requests like:\n" +
+		"\n" +
+		"    {\"version\":1,\"method\":\"get\",\"id\":\"github\"}\n" +
+		"\n" +
+		"and expects responses like:\n" +
+		"\n" +
+		"    {\"value\":\"ghp_0123456789\"}\n" +
+		"\n" +
+		"The full protocol is described in the [reference\n" +
+		"manual](https://github.com/twpayne/chezmoi/blob/master/docs/REFERENCE.md#secret-provider-protocol).\n" +
+		"Check that your secret provider works with:\n" +
+		"\n" +
+		"    chezmoi secret myvault get github\n" +
+		"\n" +
+		"Secrets are then available with the `secretProvider`, `secretProviderField`, and\n" +
+		"`secretProviderList` template functions, for example:\n" +
+		"\n" +
+		"    [github]\n" +
+		"      user = \"{{ secretProviderField \"myvault\" \"github\" \"username\" }}\"\n" +
+		"      token = \"{{ secretProvider \"myvault\" \"github\" }}\"\n" +
+		"\n" +
 		"### Use templates variables to keep your secrets\n" +
 		"\n" +
 		"Typically, `~/.config/chezmoi/chezmoi.toml` is not checked in to version control\n" +
@@ -1187,6 +1320,67 @@ func init() {
 		"Any config files containing tokens in plain text should be private (permissions\n" +
 		"`0600`).\n" +
 		"\n" +
+		"### Use encrypted data files to keep your secrets\n" +
+		"\n" +
+		"If you keep structured secrets in a document, you can store it encrypted in your\n" +
+		"source directory and use its contents as template data. Each encrypted data file\n" +
+		"is configured in the `encryptedData` section of your config file, for example:\n" +
+		"\n" +
+		"    [encryptedData.tokens]\n" +
+		"      path = \".tokens.yaml.asc\"\n" +
+		"\n" +
+		"    [encryptedData.keys]\n" +
+		"      path = \".keys.json\"\n" +
+		"      encryption = \"sops\"\n" +
+		"\n" +
+		"Relative paths are relative to the source directory. Files can be encrypted with\n" +
+		"gpg (`.asc` and `.gpg` extensions), [age](https://age-encryption.org) (`.age`\n" +
+		"extension), or [sops](https://github.com/mozilla/sops), which must be set\n" +
+		"explicitly. The format, JSON, TOML, or YAML, is determined by the extension after\n" +
+		"removing any encryption extension, or can be set with `format`. For age, set\n" +
+		"`age.identity` to the path to your identity file.\n" +
+		"\n" +
+		"The contents of each file are then available under its key, for example:\n" +
+		"\n" +
+		"    token = {{ .tokens.prod.token }}\n" +
+		"\n" +
+		"Files are only decrypted when a template references their key, either as a field\n" +
+		"like `.tokens` or `$.tokens`, or as a string like `index . \"tokens\"`, so you are\n" +
+		"not prompted for passphrases when running commands that do not need them. A\n" +
+		"template that uses the whole template data, for example with `toJson .`, `range\n" +
+		"$key, $value := .`, or `template \"name\" .`, decrypts every file.\n" +
+		"\n" +
+		"### Audit which targets use which secrets\n" +
+		"\n" +
+		"Before rotating a credential, or when setting up a machine that should only have\n" +
+		"access to some of your secrets, you can list every use of a secret manager in\n" +
+		"your source state with:\n" +
+		"\n" +
+		"    chezmoi secret audit\n" +
+		"\n" +
+		"This parses your templates without executing them, so it does not need access\n" +
+		"to any secret manager. For each target, it prints each secret template function\n" +
+		"call with its arguments, including calls in `.chezmoitemplates`.\n" +
+		"\n" +
+		"### Cache secrets to avoid repeatedly unlocking your secret manager\n" +
+		"\n" +
+		"By default, chezmoi invokes your secret manager every time it evaluates a\n" +
+		"template that uses it, which can be slow and may require you to unlock your\n" +
+		"secret manager on every run. You can tell chezmoi to cache the results of secret\n" +
+		"manager lookups by setting `secretCache.ttl` in your config file, for example:\n" +
+		"\n" +
+		"    [secretCache]\n" +
+		"      ttl = \"8h\"\n" +
+		"\n" +
+		"Cached values are stored in chezmoi's persistent state, encrypted with a key\n" +
+		"that is stored in your keyring. Values are added to the cache by any command\n" +
+		"that looks them up, including `chezmoi diff`, and reused by later runs until\n" +
+		"they expire. If you\n" +
+		"change a secret in your secret manager before its cached value expires, flush\n" +
+		"the cache with:\n" +
+		"\n" +
+		"    chezmoi secret cache flush\n" +
+		"\n" +
 		"## Use scripts to perform actions\n" +
 		"\n" +
 		"### Understand how scripts work\n" +
@@ -1272,6 +1466,25 @@ func init() {
 		"\n" +
 		"which lists all the targets in the target state.\n" +
 		"\n" +
+		"## Review changes before applying them\n" +
+		"\n" +
+		"`chezmoi apply --plan` writes a plan of all the changes that `chezmoi apply`\n" +
+		"would make, without making them:\n" +
+		"\n" +
+		"    chezmoi apply --plan=plan.json\n" +
+		"\n" +
+		"The plan is a JSON file listing every directory to be created, file to be\n" +
+		"written or removed, permission to be changed, and script to be run, together\n" +
+		"with the state that each target is expected to be in beforehand. Once the plan\n" +
+		"has been reviewed, make exactly those changes with:\n" +
+		"\n" +
+		"    chezmoi apply --from-plan=plan.json\n" +
+		"\n" +
+		"If any target has changed since the plan was made then chezmoi stops at that\n" +
+		"step, so a plan that has been approved can safely be applied later, for example\n" +
+		"by an unattended job. Plans include the contents of files, so treat them as\n" +
+		"secrets.\n" +
+		"\n" +
 		"## Use a non-git version control system\n" +
 		"\n" +
 		"By default, chezmoi uses git, but you can use any version control system of your\n" +
@@ -1528,6 +1741,8 @@ func init() {
 		"  * [`-f`, `--follow`](#-f---follow)\n" +
 		"  * [`-n`, `--dry-run`](#-n---dry-run)\n" +
 		"  * [`-h`, `--help`](#-h---help)\n" +
+		"  * [`--no-redact`](#--no-redact)\n" +
+		"  * [`--output-format` *format*](#--output-format-format)\n" +
 		"  * [`-r`. `--remove`](#-r---remove)\n" +
 		"  * [`-S`, `--source` *directory*](#-s---source-directory)\n" +
 		"  * [`-v`, `--verbose`](#-v---verbose)\n" +
@@ -1535,10 +1750,14 @@ func init() {
 		"* [Configuration file](#configuration-file)\n" +
 		"  * [Configuration variables](#configuration-variables)\n" +
 		"* [Source state attributes](#source-state-attributes)\n" +
+		"* [Source layers](#source-layers)\n" +
+		"* [Destination roots](#destination-roots)\n" +
 		"* [Special files and directories](#special-files-and-directories)\n" +
 		"  * [`.chezmoi.<format>.tmpl`](#chezmoiformattmpl)\n" +
+		"  * [`.chezmoiattributes`](#chezmoiattributes)\n" +
 		"  * [`.chezmoiignore`](#chezmoiignore)\n" +
 		"  * [`.chezmoiremove`](#chezmoiremove)\n" +
+		"  * [`.chezmoisecrets`](#chezmoisecrets)\n" +
 		"  * [`.chezmoitemplates`](#chezmoitemplates)\n" +
 		"  * [`.chezmoiversion`](#chezmoiversion)\n" +
 		"* [Commands](#commands)\n" +
@@ -1568,6 +1787,7 @@ func init() {
 		"  * [`merge` *targets*](#merge-targets)\n" +
 		"  * [`purge`](#purge)\n" +
 		"  * [`remove` *targets*](#remove-targets)\n" +
+		"  * [`restore` [*targets*]](#restore-targets)\n" +
 		"  * [`rm` *targets*](#rm-targets)\n" +
 		"  * [`secret`](#secret)\n" +
 		"  * [`source` [*args*]](#source-args)\n" +
@@ -1577,25 +1797,40 @@ func init() {
 		"  * [`update`](#update)\n" +
 		"  * [`upgrade`](#upgrade)\n" +
 		"  * [`verify` [*targets*]](#verify-targets)\n" +
+		"  * [`watch`](#watch)\n" +
 		"* [Editor configuration](#editor-configuration)\n" +
 		"* [Umask configuration](#umask-configuration)\n" +
+		"* [Secret provider protocol](#secret-provider-protocol)\n" +
 		"* [Template execution](#template-execution)\n" +
 		"* [Template variables](#template-variables)\n" +
 		"* [Template functions](#template-functions)\n" +
 		"  * [`bitwarden` [*args*]](#bitwarden-args)\n" +
+		"  * [`bitwardenAttachment` *filename* *item*](#bitwardenattachment-filename-item)\n" +
+		"  * [`bitwardenFields` [*args*]](#bitwardenfields-args)\n" +
 		"  * [`gopass` *gopass-name*](#gopass-gopass-name)\n" +
 		"  * [`keepassxc` *entry*](#keepassxc-entry)\n" +
+		"  * [`keepassxcAttachment` *entry* *name*](#keepassxcattachment-entry-name)\n" +
 		"  * [`keepassxcAttribute` *entry* *attribute*](#keepassxcattribute-entry-attribute)\n" +
 		"  * [`keyring` *service* *user*](#keyring-service-user)\n" +
 		"  * [`lastpass` *id*](#lastpass-id)\n" +
 		"  * [`lastpassRaw` *id*](#lastpassraw-id)\n" +
-		"  * [`onepassword` *uuid*](#onepassword-uuid)\n" +
-		"  * [`onepasswordDocument` *uuid*](#onepassworddocument-uuid)\n" +
+		"  * [`onepassword` *uuid* [*vault* [*account*]]](#onepassword-uuid-vault-account)\n" +
+		"  * [`onepasswordDocument` *uuid* [*vault* [*account*]]](#onepassworddocument-uuid-vault-account)\n" +
+		"  * [`onepasswordFields` *uuid* [*vault* [*account*]]](#onepasswordfields-uuid-vault-account)\n" +
 		"  * [`pass` *pass-name*](#pass-pass-name)\n" +
+		"  * [`passFields` *pass-name*](#passfields-pass-name)\n" +
+		"  * [`passOTP` *pass-name*](#passotp-pass-name)\n" +
+		"  * [`passRaw` *pass-name*](#passraw-pass-name)\n" +
 		"  * [`promptString` *prompt*](#promptstring-prompt)\n" +
 		"  * [`secret` [*args*]](#secret-args)\n" +
 		"  * [`secretJSON` [*args*]](#secretjson-args)\n" +
+		"  * [`secretProvider` *name* *id*](#secretprovider-name-id)\n" +
+		"  * [`secretProviderField` *name* *id* *field*](#secretproviderfield-name-id-field)\n" +
+		"  * [`secretProviderList` *name*](#secretproviderlist-name)\n" +
+		"  * [`secretStore` *key*](#secretstore-key)\n" +
 		"  * [`vault` *key*](#vault-key)\n" +
+		"  * [`vaultKV` *mount* *path* [*version*]](#vaultkv-mount-path-version)\n" +
+		"  * [`vaultKVField` *mount* *path* *field* [*version*]](#vaultkvfield-mount-path-field-version)\n" +
 		"\n" +
 		"## Concepts\n" +
 		"\n" +
@@ -1608,6 +1843,9 @@ func init() {
 		"* The *source directory* is where chezmoi stores the source state, by default\n" +
 		"  `~/.local/share/chezmoi`.\n" +
 		"\n" +
+		"* *Source layers* are further source directories beneath the source directory,\n" +
+		"  for example a shared base repo, whose source states are merged with it.\n" +
+		"\n" +
 		"* The *target state* is the source state computed for the current machine.\n" +
 		"\n" +
 		"* The *destination directory* is the directory that chezmoi manages, by default\n" +
@@ -1615,6 +1853,10 @@ func init() {
 		"\n" +
 		"* A *target* is a file, directory, or symlink in the destination directory.\n" +
 		"\n" +
+		"* *Destination roots* map subdirectories of the source directory to\n" +
+		"  destination directories other than the destination directory, for example\n" +
+		"  `/etc`.\n" +
+		"\n" +
 		"* The *destination state* is the state of all the targets in the destination\n" +
 		"  directory.\n" +
 		"\n" +
@@ -1660,6 +1902,38 @@ func init() {
 		"\n" +
 		"Print help.\n" +
 		"\n" +
+		"### `--no-redact`\n" +
+		"\n" +
+		"Do not redact secrets in output. By default, secrets returned by secret template\n" +
+		"functions, such as `pass` or `onepassword`, are replaced with `********` in the\n" +
+		"output of `--verbose`, `--debug`, `chezmoi diff`, and `chezmoi edit --diff`.\n" +
+		"When a secret template function returns a structure, such as a 1Password or\n" +
+		"Bitwarden item or a Vault secret, all of its values are redacted except for\n" +
+		"those of metadata fields such as `id`, `name`, `title`, `type`, `url`, and\n" +
+		"`uris`. Values shorter than four characters are not redacted.\n" +
+		"\n" +
+		"### `--output-format` *format*\n" +
+		"\n" +
+		"Set the output format of `apply`, `diff`, and `verify`, either `text` (the\n" +
+		"default) or `json`. In `json` format, chezmoi writes one JSON object per line to\n" +
+		"the standard output for each event. Every event has a `type`:\n" +
+		"\n" +
+		"| Type      | Description                                                                                       |\n" +
+		"| --------- | ------------------------------------------------------------------------------------------------- |\n" +
+		"| `action`  | A change to a target, with its `action`, `targetPath`, and `sourcePath`                           |\n" +
+		"| `drift`   | With `watch --drift`, a target was changed, with the `action` of its drift policy                 |\n" +
+		"| `script`  | A script was run, with its `targetPath` and `sourcePath`                                          |\n" +
+		"| `skip`    | A target was not changed, with a `reason` of `upToDate`, `ignored`, `filtered`, or `parentFailed` |\n" +
+		"| `error`   | The command or a target failed, with the `error` and, if a target failed, its `targetPath`        |\n" +
+		"| `summary` | The final `summary`, counting the events of each type                                             |\n" +
+		"\n" +
+		"Actions are `chmod`, `mkdir`, `removeAll`, `rename`, `runCmd`, `writeFile`, and\n" +
+		"`writeSymlink`. An `action` or `script` event that failed also has an `error`.\n" +
+		"`diff` and `verify` report the changes that would be made, and their summary\n" +
+		"has `dryRun` set. Output from scripts is written to the standard error, and\n" +
+		"`--verbose` is ignored. If any error occurs then chezmoi exits with a non-zero\n" +
+		"exit code after writing the summary.\n" +
+		"\n" +
 		"### `-r`. `--remove`\n" +
 		"\n" +
 		"Also remove targets according to `.chezmoiremove`.\n" +
@@ -1694,40 +1968,65 @@ func init() {
 		"\n" +
 		"The following configuration variables are available:\n" +
 		"\n" +
-		"| Variable                | Type     | Default value             | Description                                         |\n" +
-		"| ----------------------- | -------- | ------------------------- | --------------------------------------------------- |\n" +
-		"| `bitwarden.command`     | string   | `bw`                      | Bitwarden CLI command                               |\n" +
-		"| `cd.args`               | []string | *none*                    | Extra args to shell in `cd` command                 |\n" +
-		"| `cd.command`            | string   | *none*                    | Shell to run in `cd` command                        |\n" +
-		"| `color`                 | string   | `auto`                    | Colorize diffs                                      |\n" +
-		"| `data`                  | any      | *none*                    | Template data                                       |\n" +
-		"| `destDir`               | string   | `~`                       | Destination directory                               |\n" +
-		"| `diff.format`           | string   | `chezmoi`                 | Diff format, either `chezmoi` or `git`              |\n" +
-		"| `diff.pager`            | string   | *none*                    | Pager                                               |\n" +
-		"| `dryRun`                | bool     | `false`                   | Dry run mode                                        |\n" +
-		"| `follow`                | bool     | `false`                   | Follow symlinks                                     |\n" +
-		"| `genericSecret.command` | string   | *none*                    | Generic secret command                              |\n" +
-		"| `gopass.command`        | string   | `gopass`                  | gopass CLI command                                  |\n" +
-		"| `gpg.command`           | string   | `gpg`                     | GPG CLI command                                     |\n" +
-		"| `gpg.recipient`         | string   | *none*                    | GPG recipient                                       |\n" +
-		"| `gpg.symmetric`         | bool     | `false`                   | Use symmetric GPG encryption                        |\n" +
-		"| `keepassxc.args`        | []string | *none*                    | Extra args to KeePassXC CLI command                 |\n" +
-		"| `keepassxc.command`     | string   | `keepassxc-cli`           | KeePassXC CLI command                               |\n" +
-		"| `keepassxc.database`    | string   | *none*                    | KeePassXC database                                  |\n" +
-		"| `lastpass.command`      | string   | `lpass`                   | Lastpass CLI command                                |\n" +
-		"| `merge.args`            | []string | *none*                    | Extra args to 3-way merge command                   |\n" +
-		"| `merge.command`         | string   | `vimdiff`                 | 3-way merge command                                 |\n" +
-		"| `onepassword.command`   | string   | `op`                      | 1Password CLI command                               |\n" +
-		"| `pass.command`          | string   | `pass`                    | Pass CLI command                                    |\n" +
-		"| `remove`                | bool     | `false`                   | Remove targets                                      |\n" +
-		"| `sourceDir`             | string   | `~/.local/share/chezmoi`  | Source directory                                    |\n" +
-		"| `sourceVCS.autoCommit`  | bool     | `false`                   | Commit changes to the source state after any change |\n" +
-		"| `sourceVCS.autoPush`    | bool     | `false`                   | Push changes to the source state after any change   |\n" +
-		"| `sourceVCS.command`     | string   | `git`                     | Source version control system                       |\n" +
-		"| `template.options`      | []string | `[\"missingkey=error\"]`    | Template options                                    |\n" +
-		"| `umask`                 | int      | *from system*             | Umask                                               |\n" +
-		"| `vault.command`         | string   | `vault`                   | Vault CLI command                                   |\n" +
-		"| `verbose`               | bool     | `false`                   | Verbose mode                                        |\n" +
+		"| Variable                           | Type     | Default value             | Description                                         |\n" +
+		"| ---------------------------------- | -------- | ------------------------- | --------------------------------------------------- |\n" +
+		"| `age.command`                      | string   | `age`                     | age CLI command                                     |\n" +
+		"| `age.identity`                     | string   | *none*                    | age identity file                                   |\n" +
+		"| `apply.keepGoing`                  | bool     | `false`                   | Apply all other targets if one fails                |\n" +
+		"| `apply.transactional`              | bool     | `false`                   | Revert all changes if `apply` fails                 |\n" +
+		"| `backup.dir`                       | string   | *none*                    | Directory to store backups in                       |\n" +
+		"| `backup.maxAge`                    | duration | *none*                    | Remove backups older than this                      |\n" +
+		"| `backup.maxCount`                  | int      | *none*                    | Maximum number of backups to keep                   |\n" +
+		"| `bitwarden.command`                | string   | `bw`                      | Bitwarden CLI command                               |\n" +
+		"| `cd.args`                          | []string | *none*                    | Extra args to shell in `cd` command                 |\n" +
+		"| `cd.command`                       | string   | *none*                    | Shell to run in `cd` command                        |\n" +
+		"| `color`                            | string   | `auto`                    | Colorize diffs                                      |\n" +
+		"| `data`                             | any      | *none*                    | Template data                                       |\n" +
+		"| `destDir`                          | string   | `~`                       | Destination directory                               |\n" +
+		"| `diff.format`                      | string   | `chezmoi`                 | Diff format, either `chezmoi` or `git`              |\n" +
+		"| `diff.pager`                       | string   | *none*                    | Pager                                               |\n" +
+		"| `drift.paths`                      | list     | *none*                    | Drift policies for targets matching patterns        |\n" +
+		"| `drift.policy`                     | string   | `log`                     | Default drift policy for `watch --drift`            |\n" +
+		"| `dryRun`                           | bool     | `false`                   | Dry run mode                                        |\n" +
+		"| `encryptedData.`*key*`.encryption` | string   | *from extension*          | Encryption, `age`, `gpg`, or `sops`                 |\n" +
+		"| `encryptedData.`*key*`.format`     | string   | *from extension*          | Format, `json`, `toml`, or `yaml`                   |\n" +
+		"| `encryptedData.`*key*`.path`       | string   | *none*                    | Encrypted data file                                 |\n" +
+		"| `follow`                           | bool     | `false`                   | Follow symlinks                                     |\n" +
+		"| `genericSecret.command`            | string   | *none*                    | Generic secret command                              |\n" +
+		"| `gopass.command`                   | string   | `gopass`                  | gopass CLI command                                  |\n" +
+		"| `gpg.command`                      | string   | `gpg`                     | GPG CLI command                                     |\n" +
+		"| `gpg.recipient`                    | string   | *none*                    | GPG recipient                                       |\n" +
+		"| `gpg.symmetric`                    | bool     | `false`                   | Use symmetric GPG encryption                        |\n" +
+		"| `keepassxc.args`                   | []string | *none*                    | Extra args to KeePassXC CLI command                 |\n" +
+		"| `keepassxc.command`                | string   | `keepassxc-cli`           | KeePassXC CLI command                               |\n" +
+		"| `keepassxc.database`               | string   | *none*                    | KeePassXC database                                  |\n" +
+		"| `keepassxc.keyFile`                | string   | *none*                    | KeePassXC key file, builtin mode only               |\n" +
+		"| `keepassxc.mode`                   | string   | `cli`                     | KeePassXC mode, `cli` or `builtin`                  |\n" +
+		"| `lastpass.command`                 | string   | `lpass`                   | Lastpass CLI command                                |\n" +
+		"| `merge.args`                       | []string | *none*                    | Extra args to 3-way merge command                   |\n" +
+		"| `merge.command`                    | string   | `vimdiff`                 | 3-way merge command                                 |\n" +
+		"| `onepassword.command`              | string   | `op`                      | 1Password CLI command                               |\n" +
+		"| `parallelism`                      | int      | `1`                       | Maximum number of templates to execute concurrently |\n" +
+		"| `pass.command`                     | string   | `pass`                    | Pass CLI command                                    |\n" +
+		"| `remove`                           | bool     | `false`                   | Remove targets                                      |\n" +
+		"| `roots.destination`                | string   | *none*                    | Destination directory of a destination root         |\n" +
+		"| `roots.privileged`                 | bool     | `false`                   | Only use the destination root when running as root  |\n" +
+		"| `roots.source`                     | string   | *none*                    | Source subdirectory of a destination root           |\n" +
+		"| `roots.umask`                      | int      | *umask*                   | Umask of a destination root                         |\n" +
+		"| `secretCache.ttl`                  | duration | *none*                    | Cache secrets on disk for this long, e.g. `24h`     |\n" +
+		"| `secretProviders.`*name*`.args`    | []string | *none*                    | Extra args to secret provider *name*                |\n" +
+		"| `secretProviders.`*name*`.command` | string   | *none*                    | Secret provider *name* command                      |\n" +
+		"| `sops.command`                     | string   | `sops`                    | sops CLI command                                    |\n" +
+		"| `sourceDir`                        | string   | `~/.local/share/chezmoi`  | Source directory                                    |\n" +
+		"| `sourceLayers`                     | []string | *none*                    | Source layers beneath the source directory          |\n" +
+		"| `sourceVCS.autoCommit`             | bool     | `false`                   | Commit changes to the source state after any change |\n" +
+		"| `sourceVCS.autoPush`               | bool     | `false`                   | Push changes to the source state after any change   |\n" +
+		"| `sourceVCS.command`                | string   | `git`                     | Source version control system                       |\n" +
+		"| `template.options`                 | []string | `[\"missingkey=error\"]`    | Template options                                    |\n" +
+		"| `umask`                            | int      | *from system*             | Umask                                               |\n" +
+		"| `vault.command`                    | string   | `vault`                   | Vault CLI command                                   |\n" +
+		"| `vault.namespace`                  | string   | `$VAULT_NAMESPACE`        | Vault namespace                                     |\n" +
+		"| `verbose`                          | bool     | `false`                   | Verbose mode                                        |\n" +
 		"\n" +
 		"## Source state attributes\n" +
 		"\n" +
@@ -1767,6 +2066,89 @@ func init() {
 		"| Script        | `run_`, `once_`                                           | `.tmpl`          |\n" +
 		"| Symbolic link | `symlink_`, `dot_`,                                       | `.tmpl`          |\n" +
 		"\n" +
+		"Source names at the top level of the source directory can also begin with an\n" +
+		"XDG prefix, before any other prefixes, which puts the target in an XDG base\n" +
+		"directory instead of the destination directory:\n" +
+		"\n" +
+		"| Prefix        | Target directory                                  |\n" +
+		"| ------------- | ------------------------------------------------- |\n" +
+		"| `xdg_cache_`  | `$XDG_CACHE_HOME`, by default `~/.cache`          |\n" +
+		"| `xdg_config_` | `$XDG_CONFIG_HOME`, by default `~/.config`        |\n" +
+		"| `xdg_data_`   | `$XDG_DATA_HOME`, by default `~/.local/share`     |\n" +
+		"\n" +
+		"For example, `xdg_config_private_starship.toml` is the private file\n" +
+		"`starship.toml` in `$XDG_CONFIG_HOME`. Entries whose XDG base directory is not\n" +
+		"in the destination directory, for example when `--destination` is set or when\n" +
+		"`$XDG_CONFIG_HOME` is outside your home directory, are skipped with a warning.\n" +
+		"Use [destination roots](#destination-roots) to manage other directories. The\n" +
+		"XDG base directory and its parent directories are created if needed, but are\n" +
+		"not otherwise managed. `add` uses XDG prefixes for targets that are\n" +
+		"directly in an XDG base directory, so `chezmoi add ~/.config/nvim` adds\n" +
+		"`xdg_config_nvim`.\n" +
+		"\n" +
+		"## Source layers\n" +
+		"\n" +
+		"The `sourceLayers` configuration variable lists source directories that are\n" +
+		"layered beneath the source directory, from lowest to highest priority. The\n" +
+		"source directory is always the highest priority layer. The target state is the\n" +
+		"merge of the source states of all layers:\n" +
+		"\n" +
+		"* A target in a higher priority layer overrides the same target in lower\n" +
+		"  priority layers, whatever its attributes. Directories are merged, so a\n" +
+		"  directory's targets can come from any layer.\n" +
+		"\n" +
+		"* The patterns in all layers' `.chezmoiignore` and `.chezmoiremove` files are\n" +
+		"  merged.\n" +
+		"\n" +
+		"* Templates in all layers' `.chezmoitemplates` directories are shared by all\n" +
+		"  layers. A template in a higher priority layer overrides a template with the\n" +
+		"  same name in lower priority layers.\n" +
+		"\n" +
+		"`add` adds targets to the source directory unless `--layer` is given. `edit`\n" +
+		"edits each target in the layer that it is in, unless `--layer` is given.\n" +
+		"`managed --show-layer` shows the layer of each target, and `dump` and\n" +
+		"`source-path` print the path of each target in its layer. If\n" +
+		"`sourceVCS.autoCommit` or `sourceVCS.autoPush` is set then changes are\n" +
+		"committed and pushed in each layer that was changed, which must be a repository\n" +
+		"of its own.\n" +
+		"\n" +
+		"For example, to layer a personal source directory over a shared base repo:\n" +
+		"\n" +
+		"```toml\n" +
+		"sourceLayers = [\"/usr/local/share/dotfiles\"]\n" +
+		"```\n" +
+		"\n" +
+		"## Destination roots\n" +
+		"\n" +
+		"Each entry in the `roots` configuration variable maps a subdirectory of the\n" +
+		"source directory, `source`, to a destination directory, `destination`.\n" +
+		"`source` must be a top-level subdirectory, for example `etc` but not\n" +
+		"`system/etc`. The subdirectory is not part of the source state of the destination directory.\n" +
+		"Instead, its targets are applied to the destination root's destination\n" +
+		"directory, with the destination root's `umask`, which defaults to `umask`.\n" +
+		"\n" +
+		"A destination root's source subdirectory has its own `.chezmoiignore` and\n" +
+		"`.chezmoiremove` files, whose patterns are relative to the destination root's\n" +
+		"destination directory. Templates in `.chezmoitemplates` are shared by all\n" +
+		"destination roots. If `privileged` is `true` then the destination root is\n" +
+		"ignored unless chezmoi is run as root. Its source subdirectory is still never\n" +
+		"part of the source state of the destination directory.\n" +
+		"\n" +
+		"Commands that take targets accept targets in any destination root, and\n" +
+		"commands that print targets print targets in destination roots as absolute\n" +
+		"paths. `archive` does not include destination roots.\n" +
+		"\n" +
+		"For example, to manage some files in `/etc` from the `etc` subdirectory of the\n" +
+		"source directory:\n" +
+		"\n" +
+		"```toml\n" +
+		"[[roots]]\n" +
+		"    source = \"etc\"\n" +
+		"    destination = \"/etc\"\n" +
+		"    umask = 0o022\n" +
+		"    privileged = true\n" +
+		"```\n" +
+		"\n" +
 		"## Special files and directories\n" +
 		"\n" +
 		"All files and directories in the source state whose name begins with `.` are\n" +
@@ -1784,6 +2166,34 @@ func init() {
 		"    data:\n" +
 		"        email: \"{{ $email }}\"\n" +
 		"\n" +
+		"### `.chezmoiattributes`\n" +
+		"\n" +
+		"If a file called `.chezmoiattributes` exists in the source state then each line\n" +
+		"is interpreted as a pattern followed by attributes that set the owner and group\n" +
+		"of the targets that match the pattern, as either names or numeric IDs:\n" +
+		"\n" +
+		"| Attribute       | Effect                        |\n" +
+		"| --------------- | ----------------------------- |\n" +
+		"| `owner=`*owner* | Set the owner of the target   |\n" +
+		"| `group=`*group* | Set the group of the target   |\n" +
+		"\n" +
+		"Patterns are matched in the same way as in `.chezmoiignore`. Whitespace, `#`,\n" +
+		"and pattern characters in a pattern can be escaped with a `\\` character. If\n" +
+		"more than one pattern matches a target then later attributes override earlier\n" +
+		"ones. Only files and directories have owners and groups. Targets that match no\n" +
+		"pattern are owned by the user running chezmoi.\n" +
+		"\n" +
+		"Comments are introduced with the `#` character and run until the end of the\n" +
+		"line.\n" +
+		"\n" +
+		"`.chezmoiattributes` is interpreted as a template, and `.chezmoiattributes`\n" +
+		"files in subdirectories apply only to that subdirectory.\n" +
+		"\n" +
+		"#### `.chezmoiattributes` examples\n" +
+		"\n" +
+		"    .config/** owner=root group=wheel\n" +
+		"    .ssh/authorized_keys group=1001\n" +
+		"\n" +
 		"### `.chezmoiignore`\n" +
 		"\n" +
 		"If a file called `.chezmoiignore` exists in the source state then it is\n" +
@@ -1824,6 +2234,14 @@ func init() {
 		"interpreted as a list of targets to remove. `.chezmoiremove` is interpreted as a\n" +
 		"template.\n" +
 		"\n" +
+		"### `.chezmoisecrets`\n" +
+		"\n" +
+		"If a file called `.chezmoisecrets` exists in the source state then it is\n" +
+		"interpreted as chezmoi's own secret store, a set of key-value pairs encrypted\n" +
+		"with the configured GPG settings. It is managed with the `secret get`, `secret\n" +
+		"list`, `secret rm`, and `secret set` commands and read from templates with the\n" +
+		"`secretStore` template function.\n" +
+		"\n" +
 		"### `.chezmoitemplates`\n" +
 		"\n" +
 		"If a directory called `.chezmoitemplates` exists, then all files in this\n" +
@@ -1862,7 +2280,9 @@ func init() {
 		"\n" +
 		"Add *targets* to the source state. If any target is already in the source state,\n" +
 		"then its source state is replaced with its current state in the destination\n" +
-		"directory. The `add` command accepts additional flags:\n" +
+		"directory. When run as root, the owner and group of any target that is not\n" +
+		"owned by root are added to `.chezmoiattributes`. The `add` command accepts\n" +
+		"additional flags:\n" +
 		"\n" +
 		"#### `--autotemplate`\n" +
 		"\n" +
@@ -1882,6 +2302,11 @@ func init() {
 		"\n" +
 		"Set the `exact` attribute on added directories.\n" +
 		"\n" +
+		"#### `--layer` *directory*\n" +
+		"\n" +
+		"Add *targets* to the source layer *directory*, which must be the source\n" +
+		"directory or one of `sourceLayers`, instead of the source directory.\n" +
+		"\n" +
 		"#### `-p`, `--prompt`\n" +
 		"\n" +
 		"Interactively prompt before adding each file.\n" +
@@ -1900,31 +2325,110 @@ func init() {
 		"    chezmoi add ~/.gitconfig --template\n" +
 		"    chezmoi add ~/.vim --recursive\n" +
 		"    chezmoi add ~/.oh-my-zsh --exact --recursive\n" +
+		"    chezmoi add ~/.gitconfig --layer=/usr/local/share/dotfiles\n" +
 		"\n" +
 		"### `apply` [*targets*]\n" +
 		"\n" +
 		"Ensure that *targets* are in the target state, updating them if necessary. If no\n" +
 		"targets are specified, the state of all targets are ensured.\n" +
 		"\n" +
+		"#### `-k`, `--keep-going`\n" +
+		"\n" +
+		"Keep going after a target cannot be applied, for example because its template\n" +
+		"fails, and apply all other targets. Targets inside a directory that could not be\n" +
+		"created are skipped. When finished, chezmoi prints a summary of the targets that\n" +
+		"could not be applied and exits with a non-zero exit code. This can be set with\n" +
+		"the `apply.keepGoing` variable in the configuration file.\n" +
+		"\n" +
+		"#### `--transactional`\n" +
+		"\n" +
+		"Record the state of every file, directory, and symlink before it is changed. If\n" +
+		"any target cannot be applied then all changes already made are reverted. This\n" +
+		"can be set with the `apply.transactional` variable in the configuration file.\n" +
+		"\n" +
+		"The record of a successful transactional apply is kept in\n" +
+		"`chezmoijournal.json`, next to chezmoi's persistent state, so that it can later\n" +
+		"be undone with `--rollback-last`. It contains the previous contents of changed\n" +
+		"files, which may include secrets, so it is only readable by you. The effects of\n" +
+		"scripts are not recorded and are never reverted.\n" +
+		"\n" +
+		"#### `--rollback-last`\n" +
+		"\n" +
+		"Revert the changes made by the most recent successful transactional apply.\n" +
+		"\n" +
+		"#### `--plan` *filename*\n" +
+		"\n" +
+		"Write a plan of every change that `apply` would make to *filename*, or to the\n" +
+		"standard output if *filename* is `-`, instead of making the changes. Each step\n" +
+		"of the plan records the expected state of its target before the change, and\n" +
+		"steps that write files include the new contents and their SHA256 hash. Scripts\n" +
+		"that would be run are included with their contents. Plans can contain secrets,\n" +
+		"so they are written with permissions `0600`.\n" +
+		"\n" +
+		"#### `--from-plan` *filename*\n" +
+		"\n" +
+		"Make exactly the changes in the plan in *filename*, or read from the standard\n" +
+		"input if *filename* is `-`, without reading the source state. Execution stops\n" +
+		"at the first step whose target is no longer in the state that the plan\n" +
+		"expects. `run_once_` scripts in the plan that have already been run, for\n" +
+		"example by an earlier `apply`, are skipped. `--from-plan` cannot be used with\n" +
+		"`--plan`.\n" +
+		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only apply entries of type *types*. *types* is a comma-separated list of types\n" +
+		"of entry to include. Valid types are `all` (the default), `scripts`, and\n" +
+		"`dirs`, `files`, `symlinks`, `encrypted`, `templates`, and `once-scripts`,\n" +
+		"which can be abbreviated to `d`, `f`, `s`, `e`, `t`, and `o` respectively. An entry is included if it has any of the included types, so\n" +
+		"`--include=encrypted` includes only encrypted files and `--include=templates`\n" +
+		"includes files, symlinks, and scripts that are templates.\n" +
+		"\n" +
+		"Directories that are not included are neither created nor modified, but\n" +
+		"included entries inside them are still applied if the directory already\n" +
+		"exists.\n" +
+		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Do not apply entries of any of the types *types*, even if they are included.\n" +
+		"*types* takes the same values as `--include`, plus `none`, which is the\n" +
+		"default. For example, `--exclude=scripts` applies everything except scripts.\n" +
+		"\n" +
 		"#### `apply` examples\n" +
 		"\n" +
 		"    chezmoi apply\n" +
 		"    chezmoi apply --dry-run --verbose\n" +
 		"    chezmoi apply ~/.bashrc\n" +
+		"    chezmoi apply --keep-going\n" +
+		"    chezmoi apply --transactional\n" +
+		"    chezmoi apply --rollback-last\n" +
+		"    chezmoi apply --plan=plan.json\n" +
+		"    chezmoi apply --from-plan=plan.json\n" +
+		"    chezmoi apply --exclude=scripts\n" +
 		"\n" +
 		"### `archive`\n" +
 		"\n" +
 		"Generate a tar archive of the target state. This can be piped into `tar` to\n" +
 		"inspect the target state.\n" +
 		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only include entries of type *types*, as for `apply --include`. Directories\n" +
+		"that are not included are omitted from the archive, but included entries inside\n" +
+		"them are not.\n" +
+		"\n" +
 		"#### `--output`, `-o` *filename*\n" +
 		"\n" +
 		"Write the output to *filename* instead of stdout.\n" +
 		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Exclude entries of type *types*, as for `apply --exclude`.\n" +
+		"\n" +
 		"#### `archive` examples\n" +
 		"\n" +
 		"    chezmoi archive | tar tvf -\n" +
 		"    chezmoi archive --output=dotfiles.tar\n" +
+		"    chezmoi archive --exclude=encrypted\n" +
 		"\n" +
 		"### `cat` targets\n" +
 		"\n" +
@@ -2026,15 +2530,26 @@ func init() {
 		"version 2.0.0 of chezmoi, `git` format diffs will become the default and include\n" +
 		"scripts and the `chezmoi` format will be removed.\n" +
 		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only print the differences for entries of type *types*, as for `apply\n" +
+		"--include`.\n" +
+		"\n" +
 		"#### `--no-pager`\n" +
 		"\n" +
 		"Do not use the pager.\n" +
 		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Do not print the differences for entries of type *types*, as for `apply\n" +
+		"--exclude`.\n" +
+		"\n" +
 		"#### `diff` examples\n" +
 		"\n" +
 		"    chezmoi diff\n" +
 		"    chezmoi diff ~/.bashrc\n" +
 		"    chezmoi diff --format=git\n" +
+		"    chezmoi diff --include=encrypted\n" +
 		"\n" +
 		"### `docs` [*regexp*]\n" +
 		"\n" +
@@ -2065,16 +2580,28 @@ func init() {
 		"Print the target state in the given format. The accepted formats are `json`\n" +
 		"(JSON) and `yaml` (YAML).\n" +
 		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only dump entries of type *types*, as for `apply --include`. Directories that\n" +
+		"are not included are omitted, and included entries inside them are dumped in\n" +
+		"their place.\n" +
+		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Do not dump entries of type *types*, as for `apply --exclude`.\n" +
+		"\n" +
 		"#### `dump` examples\n" +
 		"\n" +
 		"    chezmoi dump ~/.bashrc\n" +
 		"    chezmoi dump --format=yaml\n" +
+		"    chezmoi dump --include=templates\n" +
 		"\n" +
 		"### `edit` [*targets*]\n" +
 		"\n" +
-		"Edit the source state of *targets*, which must be files or symlinks. If no\n" +
-		"targets are given the the source directory itself is opened with `$EDITOR`. The\n" +
-		"`edit` command accepts additional arguments:\n" +
+		"Edit the source state of *targets*, which must be files or symlinks, in the\n" +
+		"source layer that they are in. If no targets are given the the source directory\n" +
+		"itself is opened with `$EDITOR`. The `edit` command accepts additional\n" +
+		"arguments:\n" +
 		"\n" +
 		"#### `-a`, `--apply`\n" +
 		"\n" +
@@ -2085,6 +2612,12 @@ func init() {
 		"Print the difference between the target state and the actual state after\n" +
 		"editing.. Ignored if there are no targets.\n" +
 		"\n" +
+		"#### `--layer` *directory*\n" +
+		"\n" +
+		"Edit the source state of *targets* in the source layer *directory*, which must\n" +
+		"be the source directory or one of `sourceLayers`. If no targets are given then\n" +
+		"*directory* is opened with `$EDITOR`.\n" +
+		"\n" +
 		"#### `-p`, `--prompt`\n" +
 		"\n" +
 		"Prompt before applying each target.. Ignored if there are no targets.\n" +
@@ -2093,6 +2626,7 @@ func init() {
 		"\n" +
 		"    chezmoi edit ~/.bashrc\n" +
 		"    chezmoi edit ~/.bashrc --apply --prompt\n" +
+		"    chezmoi edit ~/.bashrc --layer=/usr/local/share/dotfiles\n" +
 		"    chezmoi edit\n" +
 		"\n" +
 		"### `edit-config`\n" +
@@ -2224,6 +2758,10 @@ func init() {
 		"abbreviated to `d`, `f`, and `s` respectively. By default, `manage` will list\n" +
 		"entries of all types.\n" +
 		"\n" +
+		"#### `--show-layer`\n" +
+		"\n" +
+		"Print the source layer of each entry after its path, separated by a tab.\n" +
+		"\n" +
 		"#### `managed` examples\n" +
 		"\n" +
 		"    chezmoi managed\n" +
@@ -2231,6 +2769,7 @@ func init() {
 		"    chezmoi managed --include=files,symlinks\n" +
 		"    chezmoi managed -i d\n" +
 		"    chezmoi managed -i d,f\n" +
+		"    chezmoi managed --show-layer\n" +
 		"\n" +
 		"### `merge` *targets*\n" +
 		"\n" +
@@ -2267,6 +2806,36 @@ func init() {
 		"\n" +
 		"Remove without prompting.\n" +
 		"\n" +
+		"### `restore` [*targets*]\n" +
+		"\n" +
+		"Restore *targets* from the most recent backup that contains them. Backups are\n" +
+		"only made if the `backup.dir` variable is set in the configuration file, in\n" +
+		"which case every target that is overwritten or removed, including by `apply`\n" +
+		"and by `restore` itself, is first copied to a new subdirectory of `backup.dir`\n" +
+		"named after the current time. Targets in [destination\n" +
+		"roots](#destination-roots) are backed up in the same way in\n" +
+		"`backup.dir/roots/`*source*, where *source* is the destination root's source\n" +
+		"subdirectory. Old backups are removed according to the `backup.maxAge` and\n" +
+		"`backup.maxCount` variables.\n" +
+		"\n" +
+		"#### `-l`, `--list`\n" +
+		"\n" +
+		"List the time and path of every file and symlink in every backup, newest first\n" +
+		"for the destination directory and for each destination root. If *targets* are\n" +
+		"given, only list backups of *targets* and their contents.\n" +
+		"\n" +
+		"#### `-t`, `--time` *time*\n" +
+		"\n" +
+		"Ignore backups made after *time*, which can be either a time as printed by\n" +
+		"`--list` or an [RFC3339](https://tools.ietf.org/html/rfc3339) time.\n" +
+		"\n" +
+		"#### `restore` examples\n" +
+		"\n" +
+		"    chezmoi restore --list\n" +
+		"    chezmoi restore --list ~/.bashrc\n" +
+		"    chezmoi restore ~/.bashrc\n" +
+		"    chezmoi restore --time=2020-01-02T03:04:05Z ~/.config\n" +
+		"\n" +
 		"### `rm` *targets*\n" +
 		"\n" +
 		"`rm` is an alias for `remove`.\n" +
@@ -2279,20 +2848,69 @@ func init() {
 		"that if you want to pass flags to the secret manager's CLI you will need to\n" +
 		"separate them with `--` to prevent chezmoi from interpreting them.\n" +
 		"\n" +
+		"chezmoi also has its own secret store, which is stored in `.chezmoisecrets` in\n" +
+		"the source directory and encrypted with GPG. It can be used on machines that do\n" +
+		"not have a secret manager installed. The following commands manage it:\n" +
+		"\n" +
+		"| Command            | Effect                                                              |\n" +
+		"| ------------------ | ------------------------------------------------------------------- |\n" +
+		"| `secret get` *key* | Print the value of *key*.                                           |\n" +
+		"| `secret list`      | List all keys.                                                      |\n" +
+		"| `secret rm` *keys* | Remove *keys*.                                                      |\n" +
+		"| `secret set` *key* | Set the value of *key*, prompting for it unless `--value` is given. |\n" +
+		"\n" +
+		"If `secretCache.ttl` is set then the results of secret manager lookups are\n" +
+		"cached in chezmoi's persistent state for that duration, so that subsequent runs\n" +
+		"do not need to invoke or unlock the secret manager. Cached values are encrypted\n" +
+		"with a key that is stored in your keyring. Values are added to the cache by every\n" +
+		"command, including `chezmoi diff`, `chezmoi verify`, and `--dry-run`, which add\n" +
+		"them when they finish. `chezmoi secret cache flush` removes all cached values.\n" +
+		"\n" +
+		"`chezmoi secret audit` [*targets*] reports which targets use which secrets,\n" +
+		"without executing any templates or invoking any secret manager. It parses every\n" +
+		"template in the source state, including `.chezmoiattributes`,\n" +
+		"`.chezmoiignore`, `.chezmoiremove`, and templates in `.chezmoitemplates` that\n" +
+		"they include, and lists each call to a\n" +
+		"secret template function, for example `pass`, `onepassword`, `bitwarden`,\n" +
+		"`vault`, `keyring`, `lastpass`, `gopass`, `keepassxc`, or `secret`, with its\n" +
+		"arguments. Arguments that are not literals, and so cannot be known without\n" +
+		"executing the template, are reported as `null`. Encrypted templates are not\n" +
+		"audited. The output format can be set with `--format`, either `json` (the\n" +
+		"default) or `yaml`.\n" +
+		"\n" +
+		"Secret providers configured in the `secretProviders` section of the\n" +
+		"configuration file are available as `secret` *name* subcommands, for example\n" +
+		"`chezmoi secret` *name* `get` *id*, `chezmoi secret` *name* `get-field` *id*\n" +
+		"*field*, and `chezmoi secret` *name* `list`. See [Secret provider\n" +
+		"protocol](#secret-provider-protocol).\n" +
+		"\n" +
 		"To get a full list of available commands run:\n" +
 		"\n" +
 		"    chezmoi secret help\n" +
 		"\n" +
 		"#### `secret` examples\n" +
 		"\n" +
+		"    chezmoi secret set --value=token github\n" +
+		"    chezmoi secret get github\n" +
+		"    chezmoi secret list\n" +
+		"    chezmoi secret rm github\n" +
+		"    chezmoi secret cache flush\n" +
+		"    chezmoi secret audit\n" +
+		"    chezmoi secret audit --format=yaml ~/.netrc\n" +
 		"    chezmoi secret bitwarden list items\n" +
 		"    chezmoi secret keyring set --service service --user user\n" +
 		"    chezmoi secret keyring get --service service --user user\n" +
+		"    chezmoi secret keyring set --service service --user user --password-file=-\n" +
+		"    chezmoi secret keyring delete --service service --user user\n" +
+		"    chezmoi secret keyring import passwords.json\n" +
 		"    chezmoi secret lastpass ls\n" +
 		"    chezmoi secret lastpass -- show --format=json id\n" +
 		"    chezmoi secret onepassword list items\n" +
 		"    chezmoi secret onepassword get item id\n" +
 		"    chezmoi secret pass show id\n" +
+		"    chezmoi secret myvault get id\n" +
+		"    chezmoi secret myvault get-field id username\n" +
+		"    chezmoi secret myvault list\n" +
 		"    chezmoi secret vault -- kv get -format=json id\n" +
 		"\n" +
 		"### `source` [*args*]\n" +
@@ -2365,10 +2983,86 @@ func init() {
 		"(success) if all targets match their target state, or 1 (failure) otherwise. If\n" +
 		"no targets are specified then all targets are checked.\n" +
 		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only verify entries of type *types*, as for `apply --include`.\n" +
+		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Do not verify entries of type *types*, as for `apply --exclude`.\n" +
+		"\n" +
 		"#### `verify` examples\n" +
 		"\n" +
 		"    chezmoi verify\n" +
 		"    chezmoi verify ~/.bashrc\n" +
+		"    chezmoi verify --exclude=scripts\n" +
+		"\n" +
+		"### `watch`\n" +
+		"\n" +
+		"Watch the source directory for changes and apply them as they are made, printing\n" +
+		"the differences, until interrupted with Ctrl-C. `.git` directories and\n" +
+		"directories whose targets are ignored by `.chezmoiignore` are not watched.\n" +
+		"\n" +
+		"After each change, chezmoi waits until no further changes have been made for a\n" +
+		"short time, then reads the source state again and applies only the targets\n" +
+		"whose source files or directories changed. Changes to special files, like\n" +
+		"`.chezmoiignore` or templates in `.chezmoitemplates`, apply all targets. Errors,\n" +
+		"for example in templates, are printed and chezmoi keeps watching. Removing a\n" +
+		"file from the source directory does not remove its target.\n" +
+		"\n" +
+		"#### `--debounce` *duration*\n" +
+		"\n" +
+		"Wait until no changes have been made for *duration* before applying them. The\n" +
+		"default is `100ms`.\n" +
+		"\n" +
+		"#### `--drift`\n" +
+		"\n" +
+		"Instead of the source directory, watch the managed files and symlinks in the\n" +
+		"destination directory. When a target is changed so that it no longer matches\n" +
+		"its target state, chezmoi applies its drift policy, which is one of:\n" +
+		"\n" +
+		"| Policy   | Action                                                               |\n" +
+		"| -------- | -------------------------------------------------------------------- |\n" +
+		"| `log`    | Print that the target changed                                        |\n" +
+		"| `readd`  | Add the target to the source state, as with `chezmoi add`            |\n" +
+		"| `revert` | Apply the target state to the target again, printing the differences |\n" +
+		"\n" +
+		"Targets generated from templates and targets that were removed cannot be\n" +
+		"re-added, so the `readd` policy only prints that they changed. Re-added targets\n" +
+		"are committed and pushed according to the `sourceVCS.autoCommit` and\n" +
+		"`sourceVCS.autoPush` variables. In `json` output format, each changed target is\n" +
+		"reported as a `drift` event whose `action` is the policy that was applied.\n" +
+		"\n" +
+		"The default policy is set with the `drift.policy` variable in the configuration\n" +
+		"file. Policies for individual targets are set with `drift.paths`, a list of\n" +
+		"`pattern`s and `policy`s. Patterns match target paths relative to the\n" +
+		"destination directory, as in `.chezmoiignore`, and the first matching pattern\n" +
+		"is used. For example:\n" +
+		"\n" +
+		"    [drift]\n" +
+		"      policy = \"log\"\n" +
+		"      [[drift.paths]]\n" +
+		"        pattern = \".ssh/**\"\n" +
+		"        policy = \"revert\"\n" +
+		"      [[drift.paths]]\n" +
+		"        pattern = \".bashrc\"\n" +
+		"        policy = \"readd\"\n" +
+		"\n" +
+		"Only directories that contain targets when chezmoi starts are watched.\n" +
+		"\n" +
+		"#### `-i`, `--include` *types*\n" +
+		"\n" +
+		"Only apply entries of type *types*, as for `apply --include`.\n" +
+		"\n" +
+		"#### `-x`, `--exclude` *types*\n" +
+		"\n" +
+		"Do not apply entries of type *types*, as for `apply --exclude`.\n" +
+		"\n" +
+		"#### `watch` examples\n" +
+		"\n" +
+		"    chezmoi watch\n" +
+		"    chezmoi watch --debounce=1s --exclude=scripts\n" +
+		"    chezmoi watch --drift\n" +
 		"\n" +
 		"## Editor configuration\n" +
 		"\n" +
@@ -2388,6 +3082,50 @@ func init() {
 		"\n" +
 		"    umask = 0o22\n" +
 		"\n" +
+		"## Secret provider protocol\n" +
+		"\n" +
+		"A secret provider is a command that chezmoi runs to retrieve secrets. Secret\n" +
+		"providers are configured in the `secretProviders` section of the configuration\n" +
+		"file, for example:\n" +
+		"\n" +
+		"    [secretProviders.myvault]\n" +
+		"      command = \"myvault-chezmoi\"\n" +
+		"      args = [\"--profile\", \"work\"]\n" +
+		"\n" +
+		"Secret provider names are case insensitive and must not be the same as the name\n" +
+		"of a built-in `secret` subcommand.\n" +
+		"\n" +
+		"For each request, chezmoi runs the provider's command with its args, writes a\n" +
+		"single JSON object to its standard input, and reads a single JSON object from\n" +
+		"its standard output. Anything written to standard error is passed through to the\n" +
+		"user. The request has the following fields:\n" +
+		"\n" +
+		"| Field     | Type   | Description                                      |\n" +
+		"| --------- | ------ | ------------------------------------------------ |\n" +
+		"| `version` | int    | Protocol version, currently `1`                  |\n" +
+		"| `method`  | string | One of `get`, `get-field`, or `list`             |\n" +
+		"| `id`      | string | Secret identifier, for `get` and `get-field`     |\n" +
+		"| `field`   | string | Field name, for `get-field`                      |\n" +
+		"\n" +
+		"The response has the following fields:\n" +
+		"\n" +
+		"| Field   | Type     | Description                                          |\n" +
+		"| ------- | -------- | ---------------------------------------------------- |\n" +
+		"| `value` | string   | The secret or field value, for `get` and `get-field` |\n" +
+		"| `ids`   | []string | All secret identifiers, for `list`                   |\n" +
+		"| `error` | string   | An error message, if the request failed              |\n" +
+		"\n" +
+		"If `error` is set, or the command exits with a non-zero status, then chezmoi\n" +
+		"treats the request as failed. For example, the request:\n" +
+		"\n" +
+		"    {\"version\":1,\"method\":\"get-field\",\"id\":\"github\",\"field\":\"username\"}\n" +
+		"\n" +
+		"might receive the response:\n" +
+		"\n" +
+		"    {\"value\":\"octocat\"}\n" +
+		"\n" +
+		"Responses are cached so each distinct request is only sent once.\n" +
+		"\n" +
 		"## Template execution\n" +
 		"\n" +
 		"chezmoi executes templates using\n" +
@@ -2415,6 +3153,17 @@ func init() {
 		"For a full list of options, see\n" +
 		"[`Template.Option`](https://pkg.go.dev/text/template?tab=doc#Template.Option).\n" +
 		"\n" +
+		"By default, each template is executed just before its target is updated. If\n" +
+		"`parallelism` is greater than `1`, then templates are executed concurrently,\n" +
+		"using up to `parallelism` goroutines. Templates are executed in batches: each\n" +
+		"batch contains the targets up to the next script, and is executed just before\n" +
+		"the first of them is updated, so templates still see the effects of earlier\n" +
+		"scripts. Scripts themselves, and targets that are ignored or excluded, are not\n" +
+		"executed early. Changes are still made, scripts are still run, and errors are\n" +
+		"still reported in the same order as they would be if templates were executed\n" +
+		"one at a time. Calls to secret managers, and decryption, are never made\n" +
+		"concurrently, so you will only be prompted for one password at a time.\n" +
+		"\n" +
 		"## Template variables\n" +
 		"\n" +
 		"chezmoi provides the following automatically populated variables:\n" +
@@ -2456,6 +3205,33 @@ func init() {
 		"    username = {{ (bitwarden \"item\" \"example.com\").login.username }}\n" +
 		"    password = {{ (bitwarden \"item\" \"example.com\").login.password }}\n" +
 		"\n" +
+		"### `bitwardenAttachment` *filename* *item*\n" +
+		"\n" +
+		"`bitwardenAttachment` returns the contents of the attachment *filename* of\n" +
+		"*item* from [Bitwarden](https://bitwarden.com) using the [Bitwarden\n" +
+		"CLI](https://github.com/bitwarden/cli) (`bw`). *item* can be an item's name or\n" +
+		"ID, or any other search term accepted by `bw get item`. chezmoi looks up the\n" +
+		"item's ID with `bw get item` *item* and then runs `bw get attachment` *filename*\n" +
+		"`--itemid` *id* `--raw`. The output from `bw` is cached so calling\n" +
+		"`bitwardenAttachment` multiple times with the same arguments will only invoke\n" +
+		"`bw` once.\n" +
+		"\n" +
+		"#### `bitwardenAttachment` examples\n" +
+		"\n" +
+		"    {{- bitwardenAttachment \"id_rsa\" \"example.com\" -}}\n" +
+		"    {{- bitwardenAttachment \"id_rsa\" \"bf22e4b4-ae4a-4d1c-8c98-ac620004b628\" -}}\n" +
+		"\n" +
+		"### `bitwardenFields` [*args*]\n" +
+		"\n" +
+		"`bitwardenFields` returns the custom fields of a\n" +
+		"[Bitwarden](https://bitwarden.com) item as a map keyed by field name. *args* are\n" +
+		"passed to `bw get item` unchanged. Each field is a map with `name`, `value`, and\n" +
+		"`type` keys. `bitwardenFields` shares its cache with `bitwarden`.\n" +
+		"\n" +
+		"#### `bitwardenFields` examples\n" +
+		"\n" +
+		"    token = {{ (bitwardenFields \"example.com\").token.value }}\n" +
+		"\n" +
 		"### `gopass` *gopass-name*\n" +
 		"\n" +
 		"`gopass` returns passwords stored in [gopass](https://www.gopass.pw/) using the\n" +
@@ -2480,11 +3256,31 @@ func init() {
 		"key-value pairs and cached so calling `keepassxc` multiple times with the same\n" +
 		"*entry* will only invoke `keepassxc-cli` once.\n" +
 		"\n" +
+		"If `keepassxc.mode` is `builtin` then chezmoi reads the database itself instead\n" +
+		"of running `keepassxc-cli`. KDBX 3 and 4 databases are supported. The database\n" +
+		"is unlocked with the password, the key file set in `keepassxc.keyFile`, or both.\n" +
+		"An empty password with a key file unlocks the database with the key file alone.\n" +
+		"*entry* is the path to the entry relative to the root group, for example\n" +
+		"`Group/example.com`, and the returned data contains all of the entry's\n" +
+		"attributes, including `Title`, `UserName`, and `Password`.\n" +
+		"\n" +
 		"#### `keepassxc` examples\n" +
 		"\n" +
 		"    username = {{ (keepassxc \"example.com\").UserName }}\n" +
 		"    password = {{ (keepassxc \"example.com\").Password }}\n" +
 		"\n" +
+		"### `keepassxcAttachment` *entry* *name*\n" +
+		"\n" +
+		"`keepassxcAttachment` returns the contents of the attachment *name* of *entry*.\n" +
+		"When `keepassxc.mode` is `cli`, the attachment is read with `keepassxc-cli\n" +
+		"attachment-export --stdout`, which requires KeePassXC 2.7.0 or later. It behaves\n" +
+		"identically to the `keepassxc` function in terms of configuration, password\n" +
+		"prompting, password storage, and result caching.\n" +
+		"\n" +
+		"#### `keepassxcAttachment` examples\n" +
+		"\n" +
+		"    {{ keepassxcAttachment \"SSH Key\" \"id_rsa\" }}\n" +
+		"\n" +
 		"### `keepassxcAttribute` *entry* *attribute*\n" +
 		"\n" +
 		"`keepassxcAttribute` returns the attribute *attribute* of *entry* using\n" +
@@ -2539,32 +3335,55 @@ func init() {
 		"\n" +
 		"    {{ (index (lastpassRaw \"SSH Private Key\") 0).note }}\n" +
 		"\n" +
-		"### `onepassword` *uuid*\n" +
+		"### `onepassword` *uuid* [*vault* [*account*]]\n" +
 		"\n" +
 		"`onepassword` returns structured data from [1Password](https://1password.com/)\n" +
 		"using the [1Password\n" +
 		"CLI](https://support.1password.com/command-line-getting-started/) (`op`). *uuid*\n" +
-		"is passed to `op get item <uuid>` and the output from `op` is parsed as JSON.\n" +
-		"The output from `op` is cached so calling `onepassword` multiple times with the\n" +
-		"same *uuid* will only invoke `op` once.\n" +
+		"is passed to `op get item <uuid>` and the output from `op` is parsed as JSON. If\n" +
+		"*vault* or *account* are given then they are passed to `op` with the `--vault`\n" +
+		"and `--account` flags respectively. The output from `op` is cached so calling\n" +
+		"`onepassword` multiple times with the same arguments will only invoke `op` once.\n" +
+		"\n" +
+		"If there is no `OP_SESSION_`*account* environment variable, or no `OP_SESSION_`\n" +
+		"environment variable at all if *account* is not given, then chezmoi first signs\n" +
+		"in by running `op signin [account] --raw`, which prompts for your password, and\n" +
+		"passes the resulting session token to subsequent invocations of `op`.\n" +
 		"\n" +
 		"#### `onepassword` examples\n" +
 		"\n" +
 		"    {{ (onepassword \"<uuid>\").details.password }}\n" +
+		"    {{ (onepassword \"<uuid>\" \"<vault>\" \"<account>\").details.password }}\n" +
 		"\n" +
-		"### `onepasswordDocument` *uuid*\n" +
+		"### `onepasswordDocument` *uuid* [*vault* [*account*]]\n" +
 		"\n" +
-		"`onepassword` returns a document from [1Password](https://1password.com/)\n" +
-		"using the [1Password\n" +
+		"`onepasswordDocument` returns a document from\n" +
+		"[1Password](https://1password.com/) using the [1Password\n" +
 		"CLI](https://support.1password.com/command-line-getting-started/) (`op`). *uuid*\n" +
 		"is passed to `op get document <uuid>` and the output from `op` is returned.\n" +
-		"The output from `op` is cached so calling `onepasswordDocument` multiple times with the\n" +
-		"same *uuid* will only invoke `op` once.\n" +
+		"*vault* and *account* are handled as for `onepassword`. The output from `op` is\n" +
+		"cached so calling `onepasswordDocument` multiple times with the same arguments\n" +
+		"will only invoke `op` once.\n" +
 		"\n" +
 		"#### `onepasswordDocument` examples\n" +
 		"\n" +
 		"    {{- onepasswordDocument \"<uuid>\" -}}\n" +
 		"\n" +
+		"### `onepasswordFields` *uuid* [*vault* [*account*]]\n" +
+		"\n" +
+		"`onepasswordFields` returns the fields of a [1Password](https://1password.com/)\n" +
+		"item as a flat map of values. Fields in the item's details are keyed by their\n" +
+		"designation, for example `username` or `password`, or by their name if they do\n" +
+		"not have a designation. Fields in the item's sections are keyed by their label.\n" +
+		"`onepasswordFields` takes the same arguments as, and shares its cache with,\n" +
+		"`onepassword`.\n" +
+		"\n" +
+		"#### `onepasswordFields` examples\n" +
+		"\n" +
+		"    username = {{ (onepasswordFields \"<uuid>\").username }}\n" +
+		"    password = {{ (onepasswordFields \"<uuid>\").password }}\n" +
+		"    token = {{ index (onepasswordFields \"<uuid>\") \"API token\" }}\n" +
+		"\n" +
 		"### `pass` *pass-name*\n" +
 		"\n" +
 		"`pass` returns passwords stored in [pass](https://www.passwordstore.org/) using\n" +
@@ -2577,6 +3396,39 @@ func init() {
 		"\n" +
 		"    {{ pass \"<pass-name>\" }}\n" +
 		"\n" +
+		"### `passFields` *pass-name*\n" +
+		"\n" +
+		"`passFields` returns the entry *pass-name* stored in\n" +
+		"[pass](https://www.passwordstore.org/) as a map. Each line of the output of\n" +
+		"`pass show <pass-name>` after the first of the form `key: value` is added to the\n" +
+		"map as *key*, and the password on the first line is added as `password`. The\n" +
+		"output from `pass` is shared with `pass` and `passRaw`.\n" +
+		"\n" +
+		"#### `passFields` examples\n" +
+		"\n" +
+		"    {{ (passFields \"<pass-name>\").login }}\n" +
+		"\n" +
+		"### `passOTP` *pass-name*\n" +
+		"\n" +
+		"`passOTP` returns the current one time password for *pass-name* using the\n" +
+		"[pass-otp](https://github.com/tadfisher/pass-otp) extension by running `pass otp\n" +
+		"<pass-name>`. One time passwords are cached for the duration of a single\n" +
+		"invocation of chezmoi, but are never stored in the secret cache.\n" +
+		"\n" +
+		"#### `passOTP` examples\n" +
+		"\n" +
+		"    {{ passOTP \"<pass-name>\" }}\n" +
+		"\n" +
+		"### `passRaw` *pass-name*\n" +
+		"\n" +
+		"`passRaw` returns the full output of `pass show <pass-name>`, including all\n" +
+		"lines and the trailing newline. The output from `pass` is cached so calling\n" +
+		"`passRaw` multiple times with the same *pass-name* will only invoke `pass` once.\n" +
+		"\n" +
+		"#### `passRaw` examples\n" +
+		"\n" +
+		"    {{ passRaw \"<pass-name>\" }}\n" +
+		"\n" +
 		"### `promptString` *prompt*\n" +
 		"\n" +
 		"`promptString` takes a single argument is a string prompted to the user, and the\n" +
@@ -2603,6 +3455,45 @@ func init() {
 		"parsed as JSON. The output is cached so multiple calls to `secret` with the same\n" +
 		"*args* will only invoke the generic secret command once.\n" +
 		"\n" +
+		"### `secretStore` *key*\n" +
+		"\n" +
+		"`secretStore` returns the value of *key* from chezmoi's secret store in\n" +
+		"`.chezmoisecrets`. The secret store is decrypted the first time `secretStore` is\n" +
+		"called and the result is cached, so GPG is only invoked once.\n" +
+		"\n" +
+		"#### `secretStore` examples\n" +
+		"\n" +
+		"    token = {{ secretStore \"github\" }}\n" +
+		"\n" +
+		"### `secretProvider` *name* *id*\n" +
+		"\n" +
+		"`secretProvider` returns the secret *id* from the secret provider *name* using\n" +
+		"a `get` request. See [Secret provider protocol](#secret-provider-protocol).\n" +
+		"\n" +
+		"#### `secretProvider` examples\n" +
+		"\n" +
+		"    password = {{ secretProvider \"myvault\" \"github\" }}\n" +
+		"\n" +
+		"### `secretProviderField` *name* *id* *field*\n" +
+		"\n" +
+		"`secretProviderField` returns the field *field* of the secret *id* from the\n" +
+		"secret provider *name* using a `get-field` request.\n" +
+		"\n" +
+		"#### `secretProviderField` examples\n" +
+		"\n" +
+		"    username = {{ secretProviderField \"myvault\" \"github\" \"username\" }}\n" +
+		"\n" +
+		"### `secretProviderList` *name*\n" +
+		"\n" +
+		"`secretProviderList` returns the identifiers of all secrets available from the\n" +
+		"secret provider *name* using a `list` request.\n" +
+		"\n" +
+		"#### `secretProviderList` examples\n" +
+		"\n" +
+		"    {{ range secretProviderList \"myvault\" }}\n" +
+		"    {{ . }}\n" +
+		"    {{ end }}\n" +
+		"\n" +
 		"### `vault` *key*\n" +
 		"\n" +
 		"`vault` returns structured data from [Vault](https://www.vaultproject.io/) using\n" +
@@ -2613,5 +3504,31 @@ func init() {
 		"\n" +
 		"#### `vault` examples\n" +
 		"\n" +
-		"    {{ (vault \"<key>\").data.data.password }}\n")
+		"    {{ (vault \"<key>\").data.data.password }}\n" +
+		"\n" +
+		"### `vaultKV` *mount* *path* [*version*]\n" +
+		"\n" +
+		"`vaultKV` returns the data of the secret at *path* in the KV secrets engine\n" +
+		"mounted at *mount* from [Vault](https://www.vaultproject.io/), by running `vault\n" +
+		"kv get -format=json <mount>/<path>`. Responses from both version 1 and version 2\n" +
+		"of the KV secrets engine are unwrapped, so the secret's keys are available\n" +
+		"directly. If *version* is given then that version of a KV version 2 secret is\n" +
+		"returned. If `vault.namespace` is set in the config file, or the\n" +
+		"`VAULT_NAMESPACE` environment variable is set, then it is passed to `vault` with\n" +
+		"the `-namespace` flag. The output from `vault` is cached so calling `vaultKV`\n" +
+		"multiple times with the same arguments will only invoke `vault` once.\n" +
+		"\n" +
+		"#### `vaultKV` examples\n" +
+		"\n" +
+		"    {{ (vaultKV \"secret\" \"<path>\").password }}\n" +
+		"\n" +
+		"### `vaultKVField` *mount* *path* *field* [*version*]\n" +
+		"\n" +
+		"`vaultKVField` returns the field *field* of the secret returned by `vaultKV`\n" +
+		"*mount* *path* [*version*]. It is an error if the secret does not have *field*.\n" +
+		"\n" +
+		"#### `vaultKVField` examples\n" +
+		"\n" +
+		"    {{ vaultKVField \"secret\" \"<path>\" \"password\" }}\n" +
+		"    {{ vaultKVField \"secret\" \"<path>\" \"password\" 2 }}\n")
 }
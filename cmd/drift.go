@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/fsnotify/fsnotify"
+	vfs "github.com/twpayne/go-vfs"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// Drift policies.
+const (
+	driftPolicyLog    = "log"
+	driftPolicyReadd  = "readd"
+	driftPolicyRevert = "revert"
+)
+
+type driftConfig struct {
+	Policy string
+	Paths  []driftPathConfig
+}
+
+type driftPathConfig struct {
+	Pattern string
+	Policy  string
+}
+
+// policy returns the policy for targetName, which is the policy of the first
+// path pattern that matches it, or the default policy if none do.
+func (dc *driftConfig) policy(targetName string) string {
+	for _, path := range dc.Paths {
+		if ok, _ := doublestar.PathMatch(path.Pattern, targetName); ok {
+			return path.Policy
+		}
+	}
+	if dc.Policy == "" {
+		return driftPolicyLog
+	}
+	return dc.Policy
+}
+
+// validate returns an error if dc contains any invalid patterns or policies.
+func (dc *driftConfig) validate() error {
+	policies := []string{dc.Policy}
+	for _, path := range dc.Paths {
+		if _, err := doublestar.PathMatch(path.Pattern, ""); err != nil {
+			return fmt.Errorf("%s: %w", path.Pattern, err)
+		}
+		policies = append(policies, path.Policy)
+	}
+	for _, policy := range policies {
+		switch policy {
+		case "", driftPolicyLog, driftPolicyReadd, driftPolicyRevert:
+		default:
+			return fmt.Errorf("%s: unknown drift policy", policy)
+		}
+	}
+	return nil
+}
+
+// newDriftWatcher returns a new watcher that watches the directories
+// containing the managed files and symlinks in ts.
+func (c *Config) newDriftWatcher(ts *chezmoi.TargetState) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool)
+	for _, entry := range driftEntries(ts) {
+		dirs[filepath.Dir(ts.TargetPath(entry))] = true
+	}
+	for dir := range dirs {
+		rawDir, err := c.fs.RawPath(dir)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		// Directories that do not exist yet cannot be watched, so their
+		// targets have not been applied and cannot have drifted.
+		if err := watcher.Add(rawDir); err != nil && !os.IsNotExist(err) {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return watcher, nil
+}
+
+// watchDrift handles changes to managed targets reported by watcher, according
+// to their drift policies, until stop receives a value.
+func (c *Config) watchDrift(watcher *fsnotify.Watcher, persistentState chezmoi.PersistentState, stop <-chan os.Signal) error {
+	if err := c.Drift.validate(); err != nil {
+		return err
+	}
+	ts, err := c.getTargetState(nil)
+	if err != nil {
+		return err
+	}
+	// Watched directories, like the destination directory itself, can contain
+	// many files that are not managed, so changes to them are ignored early.
+	// managed maps the raw paths of managed targets to their target paths.
+	managed := make(map[string]string)
+	for _, entry := range driftEntries(ts) {
+		targetPath := ts.TargetPath(entry)
+		rawTargetPath, err := c.fs.RawPath(targetPath)
+		if err != nil {
+			return err
+		}
+		managed[rawTargetPath] = targetPath
+	}
+	changedTargetPaths := make(map[string]bool)
+	return c.watchEvents(watcher, stop, func(event fsnotify.Event) error {
+		if targetPath, ok := managed[event.Name]; ok {
+			changedTargetPaths[targetPath] = true
+		}
+		return nil
+	}, func() error {
+		if len(changedTargetPaths) == 0 {
+			return nil
+		}
+		defer func() {
+			changedTargetPaths = make(map[string]bool)
+		}()
+		return c.handleDrift(changedTargetPaths, persistentState)
+	})
+}
+
+// handleDrift applies the drift policy of each managed target in
+// changedTargetPaths that no longer matches its target state.
+func (c *Config) handleDrift(changedTargetPaths map[string]bool, persistentState chezmoi.PersistentState) error {
+	ts, err := c.getTargetState(nil)
+	if err != nil {
+		return err
+	}
+	applyOptions, err := c.getApplyOptions(ts, persistentState)
+	if err != nil {
+		return err
+	}
+
+	var drifted []chezmoi.Entry
+	for _, entry := range driftEntries(ts) {
+		if !changedTargetPaths[ts.TargetPath(entry)] || ts.Ignored(entry) {
+			continue
+		}
+		// An entry has drifted if applying it would change anything.
+		mutator := chezmoi.NewAnyMutator(chezmoi.NullMutator{})
+		if err := ts.ApplyEntry(vfs.NewReadOnlyFS(c.fs), mutator, c.Follow, entry, applyOptions); err != nil {
+			return err
+		}
+		if mutator.Mutated() {
+			drifted = append(drifted, entry)
+		}
+	}
+
+	var revert []chezmoi.Entry
+	var revertArgs []string
+	readded := false
+	for _, entry := range drifted {
+		targetPath := ts.TargetPath(entry)
+		policy := c.Drift.policy(entry.TargetName())
+		switch policy {
+		case driftPolicyReadd:
+			reason, err := c.readdDrift(ts, entry, targetPath)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				c.reportDrift(ts, entry, driftPolicyLog, reason)
+				continue
+			}
+			readded = true
+		case driftPolicyRevert:
+			revert = append(revert, entry)
+			revertArgs = append(revertArgs, targetPath)
+		}
+		c.reportDrift(ts, entry, policy, "")
+	}
+
+	if readded {
+		if err := c.autoCommitAndAutoPush(nil, nil); err != nil {
+			return err
+		}
+	}
+	if len(revert) == 0 {
+		return nil
+	}
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		return c.withEvents(ts, revertArgs, mutator, applyOptions, func(mutator chezmoi.Mutator) error {
+			return c.applyEntries(ts, revert, mutator, applyOptions)
+		})
+	})
+}
+
+// readdDrift adds the target of entry at targetPath to the source state. If
+// it cannot be re-added then it returns the reason why.
+func (c *Config) readdDrift(ts *chezmoi.TargetState, entry chezmoi.Entry, targetPath string) (string, error) {
+	if chezmoi.EntryTypeOf(entry)&chezmoi.EntryTypeTemplates != 0 {
+		return "not re-added because it is a template", nil
+	}
+	info, err := c.fs.Lstat(targetPath)
+	switch {
+	case os.IsNotExist(err):
+		return "not re-added because it was removed", nil
+	case err != nil:
+		return "", err
+	}
+	// The target is re-added to the source layer that it is in.
+	layerTS, err := c.getLayerTargetState(ts.SourceDirOf(entry), nil)
+	if err != nil {
+		return "", err
+	}
+	c.sourceLayerChanged(layerTS.SourceDir)
+	addOptions := chezmoi.AddOptions{
+		Ownership: os.Geteuid() == 0,
+	}
+	if file, ok := entry.(*chezmoi.File); ok {
+		addOptions.Empty = file.Empty
+		addOptions.Encrypt = file.Encrypted
+	}
+	return "", layerTS.Add(c.fs, addOptions, targetPath, info, c.Follow, c.mutator)
+}
+
+// reportDrift reports that entry drifted and that policy was applied, with an
+// optional reason.
+func (c *Config) reportDrift(ts *chezmoi.TargetState, entry chezmoi.Entry, policy, reason string) {
+	targetPath := ts.TargetPath(entry)
+	if c.OutputFormat == outputFormatJSON {
+		newEventWriter(c.Stdout, c.getRedactor()).emit(&chezmoi.Event{
+			Type:       chezmoi.EventTypeDrift,
+			Action:     policy,
+			TargetPath: targetPath,
+			SourcePath: ts.SourcePath(entry),
+			Reason:     reason,
+		})
+		return
+	}
+	var message string
+	switch {
+	case reason != "":
+		message = "changed, " + reason
+	case policy == driftPolicyReadd:
+		message = "changed, re-added to source state"
+	case policy == driftPolicyRevert:
+		message = "changed, reverting"
+	default:
+		message = "changed"
+	}
+	fmt.Fprintf(c.Stdout, "%s: %s\n", targetPath, message)
+}
+
+// driftEntries returns the files and symlinks in ts, sorted by target path.
+func driftEntries(ts *chezmoi.TargetState) []chezmoi.Entry {
+	var entries []chezmoi.Entry
+	for _, entry := range ts.AllEntries() {
+		switch entry.(type) {
+		case *chezmoi.File, *chezmoi.Symlink:
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return ts.TargetPath(entries[i]) < ts.TargetPath(entries[j])
+	})
+	return entries
+}
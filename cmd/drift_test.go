@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+func TestDriftConfigPolicy(t *testing.T) {
+	dc := &driftConfig{
+		Paths: []driftPathConfig{
+			{Pattern: ".ssh/**", Policy: driftPolicyRevert},
+			{Pattern: ".bashrc", Policy: driftPolicyReadd},
+			{Pattern: ".*rc", Policy: driftPolicyRevert},
+		},
+	}
+	assert.NoError(t, dc.validate())
+	for targetName, expected := range map[string]string{
+		".bashrc":      driftPolicyReadd,
+		".inputrc":     driftPolicyRevert,
+		".ssh/config":  driftPolicyRevert,
+		".config/foo":  driftPolicyLog,
+		".ssh":         driftPolicyLog,
+		"dir/.inputrc": driftPolicyLog,
+	} {
+		assert.Equal(t, expected, dc.policy(targetName), targetName)
+	}
+
+	dc.Policy = "fix"
+	assert.Error(t, dc.validate())
+}
+
+func TestHandleDrift(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".bashrc":  "# edited .bashrc\n",
+			".inputrc": "# edited .inputrc\n",
+			".profile": "# edited .profile\n",
+			".vimrc":   "# contents of .vimrc\n",
+			".zshrc":   "# edited .zshrc\n",
+			".local/share/chezmoi": map[string]interface{}{
+				"dot_bashrc":       "# contents of .bashrc\n",
+				"dot_inputrc":      "# contents of .inputrc\n",
+				"dot_profile.tmpl": "# contents of .profile\n",
+				"dot_vimrc":        "# contents of .vimrc\n",
+				"dot_zshrc":        "# contents of .zshrc\n",
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	stdout := &bytes.Buffer{}
+	c := newTestConfig(fs, withStdout(stdout))
+	c.Drift = driftConfig{
+		Policy: driftPolicyRevert,
+		Paths: []driftPathConfig{
+			{Pattern: ".bashrc", Policy: driftPolicyReadd},
+			{Pattern: ".profile", Policy: driftPolicyReadd},
+			{Pattern: ".inputrc", Policy: driftPolicyLog},
+		},
+	}
+	persistentState, err := c.getPersistentState(nil)
+	require.NoError(t, err)
+	defer persistentState.Close()
+
+	require.NoError(t, c.handleDrift(map[string]bool{
+		"/home/user/.bashrc":  true,
+		"/home/user/.inputrc": true,
+		"/home/user/.profile": true,
+		"/home/user/.vimrc":   true,
+		"/home/user/.zshrc":   true,
+	}, persistentState))
+
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.local/share/chezmoi/dot_bashrc",
+			vfst.TestContentsString("# edited .bashrc\n"),
+		),
+		vfst.TestPath("/home/user/.inputrc",
+			vfst.TestContentsString("# edited .inputrc\n"),
+		),
+		vfst.TestPath("/home/user/.local/share/chezmoi/dot_profile.tmpl",
+			vfst.TestContentsString("# contents of .profile\n"),
+		),
+		vfst.TestPath("/home/user/.profile",
+			vfst.TestContentsString("# edited .profile\n"),
+		),
+		vfst.TestPath("/home/user/.zshrc",
+			vfst.TestContentsString("# contents of .zshrc\n"),
+		),
+	)
+	assert.Contains(t, stdout.String(), "/home/user/.bashrc: changed, re-added to source state\n")
+	assert.Contains(t, stdout.String(), "/home/user/.inputrc: changed\n")
+	assert.Contains(t, stdout.String(), "/home/user/.profile: changed, not re-added because it is a template\n")
+	assert.Contains(t, stdout.String(), "/home/user/.zshrc: changed, reverting\n")
+	assert.NotContains(t, stdout.String(), ".vimrc")
+}
+
+func TestWatchDrift(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".bashrc": "# contents of .bashrc\n",
+			".local/share/chezmoi": map[string]interface{}{
+				"dot_bashrc": "# contents of .bashrc\n",
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	c := newTestConfig(fs, withWatchCmdConfig(watchCmdConfig{
+		debounce: 10 * time.Millisecond,
+	}))
+	c.Drift.Policy = driftPolicyRevert
+	persistentState, err := c.getPersistentState(nil)
+	require.NoError(t, err)
+	defer persistentState.Close()
+	ts, err := c.getTargetState(nil)
+	require.NoError(t, err)
+	watcher, err := c.newDriftWatcher(ts)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	stop := make(chan os.Signal, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.watchDrift(watcher, persistentState, stop)
+	}()
+
+	require.NoError(t, fs.WriteFile("/home/user/.bashrc", []byte("# edited .bashrc\n"), 0o666))
+	assert.Eventually(t, func() bool {
+		contents, err := fs.ReadFile("/home/user/.bashrc")
+		return err == nil && string(contents) == "# contents of .bashrc\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stop <- os.Interrupt
+	require.NoError(t, <-errCh)
+}
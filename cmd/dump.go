@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
 )
 
 type dumpCmdConfig struct {
@@ -28,6 +29,7 @@ func init() {
 	persistentFlags := dumpCmd.PersistentFlags()
 	persistentFlags.StringVarP(&config.dump.format, "format", "f", "json", "format (JSON, TOML, or YAML)")
 	persistentFlags.BoolVarP(&config.dump.recursive, "recursive", "r", true, "recursive")
+	addFilterFlags(dumpCmd)
 
 	markRemainingZshCompPositionalArgumentsAsFiles(dumpCmd, 1)
 }
@@ -37,13 +39,20 @@ func (c *Config) runDumpCmd(cmd *cobra.Command, args []string) error {
 	if !ok {
 		return fmt.Errorf("%s: unknown format", c.dump.format)
 	}
+	filter, err := c.getFilter()
+	if err != nil {
+		return err
+	}
 	ts, err := c.getTargetState(nil)
 	if err != nil {
 		return err
 	}
 	var concreteValue interface{}
 	if len(args) == 0 {
-		concreteValue, err = ts.ConcreteValue(c.dump.recursive)
+		if err := ts.Evaluate(); err != nil {
+			return err
+		}
+		concreteValue, err = ts.ConcreteValue(filter, c.dump.recursive)
 		if err != nil {
 			return err
 		}
@@ -52,15 +61,16 @@ func (c *Config) runDumpCmd(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		if err := ts.EvaluateEntries(entries); err != nil {
+			return err
+		}
 		var concreteValues []interface{}
 		for _, entry := range entries {
-			entryConcreteValue, err := entry.ConcreteValue(ts.TargetIgnore.Match, ts.SourceDir, os.FileMode(c.Umask), c.dump.recursive)
+			entryConcreteValue, err := ts.EntryConcreteValue(entry, filter, c.dump.recursive)
 			if err != nil {
 				return err
 			}
-			if entryConcreteValue != nil {
-				concreteValues = append(concreteValues, entryConcreteValue)
-			}
+			concreteValues = chezmoi.AppendConcreteValue(concreteValues, entryConcreteValue)
 		}
 		concreteValue = concreteValues
 	}
@@ -26,6 +26,7 @@ var editCmd = &cobra.Command{
 type editCmdConfig struct {
 	apply  bool
 	diff   bool
+	layer  string
 	prompt bool
 }
 
@@ -35,6 +36,7 @@ func init() {
 	persistentFlags := editCmd.PersistentFlags()
 	persistentFlags.BoolVarP(&config.edit.apply, "apply", "a", false, "apply edit after editing")
 	persistentFlags.BoolVarP(&config.edit.diff, "diff", "d", false, "print diff after editing")
+	persistentFlags.StringVar(&config.edit.layer, "layer", "", "edit in source layer")
 	persistentFlags.BoolVarP(&config.edit.prompt, "prompt", "p", false, "prompt before applying (implies --diff)")
 
 	markRemainingZshCompPositionalArgumentsAsFiles(editCmd, 1)
@@ -58,16 +60,29 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 		if c.edit.prompt {
 			cmd.Printf("warning: --prompt is currently ignored when edit is run with no arguments\n")
 		}
-		return c.runEditor(c.SourceDir)
+		sourceDir, err := c.getSourceLayer(c.edit.layer)
+		if err != nil {
+			return err
+		}
+		c.sourceLayerChanged(sourceDir)
+		return c.runEditor(sourceDir)
 	}
 
 	if c.edit.prompt {
 		c.edit.diff = true
 	}
 
-	ts, err := c.getTargetState(&chezmoi.PopulateOptions{
+	// By default, edit each target in the source layer that it is in.
+	populateOptions := &chezmoi.PopulateOptions{
 		ExecuteTemplates: false,
-	})
+	}
+	var ts *chezmoi.TargetState
+	var err error
+	if c.edit.layer == "" {
+		ts, err = c.getTargetState(populateOptions)
+	} else {
+		ts, err = c.getLayerTargetState(c.edit.layer, populateOptions)
+	}
 	if err != nil {
 		return err
 	}
@@ -83,7 +98,7 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 	argv := make([]string, len(entries))
 	var encryptedFiles []encryptedFile
 	for i, entry := range entries {
-		argv[i] = filepath.Join(c.SourceDir, entry.SourceName())
+		argv[i] = ts.SourcePath(entry)
 		if file, ok := entry.(*chezmoi.File); ok {
 			if file.Encrypted {
 				ef := encryptedFile{
@@ -127,6 +142,9 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 	if err := c.runEditor(argv...); err != nil {
 		return err
 	}
+	for _, entry := range entries {
+		c.sourceLayerChanged(ts.SourcePath(entry))
+	}
 
 	// Re-encrypt any encrypted files.
 	for _, ef := range encryptedFiles {
@@ -159,6 +177,7 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 		DestDir:           ts.DestDir,
 		DryRun:            c.DryRun,
 		Ignore:            ts.TargetIgnore.Match,
+		Redactor:          c.getRedactor(),
 		ScriptStateBucket: c.scriptStateBucket,
 		Stdout:            c.Stdout,
 		Umask:             ts.Umask,
@@ -168,7 +187,7 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 		anyMutator := chezmoi.NewAnyMutator(chezmoi.NullMutator{})
 		var mutator chezmoi.Mutator = anyMutator
 		if c.edit.diff {
-			mutator = chezmoi.NewVerboseMutator(c.Stdout, mutator, c.colored, c.maxDiffDataSize)
+			mutator = chezmoi.NewVerboseMutator(c.Stdout, mutator, c.colored, c.maxDiffDataSize, c.getRedactor())
 		}
 		if err := entry.Apply(readOnlyFS, mutator, c.Follow, &applyOptions); err != nil {
 			return err
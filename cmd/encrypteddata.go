@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// Encryptions of encrypted data files.
+const (
+	encryptionAge  = "age"
+	encryptionGPG  = "gpg"
+	encryptionSops = "sops"
+)
+
+type ageConfig struct {
+	Command  string
+	Identity string
+}
+
+type sopsConfig struct {
+	Command string
+}
+
+// An encryptedDataConfig describes an encrypted data file that is added to the
+// template data.
+type encryptedDataConfig struct {
+	Path       string
+	Format     string
+	Encryption string
+}
+
+var (
+	encryptionExtensions = map[string]string{
+		".age": encryptionAge,
+		".asc": encryptionGPG,
+		".gpg": encryptionGPG,
+	}
+
+	dataFormatExtensions = map[string]string{
+		".json": "json",
+		".toml": "toml",
+		".yaml": "yaml",
+		".yml":  "yaml",
+	}
+
+	unmarshalFuncs = map[string]func([]byte, interface{}) error{
+		"json": json.Unmarshal,
+		"toml": toml.Unmarshal,
+		"yaml": yaml.Unmarshal,
+	}
+)
+
+func init() {
+	config.Age.Command = "age"
+	config.Sops.Command = "sops"
+}
+
+// getLazyTemplateData returns functions that read and decrypt each configured
+// encrypted data file, keyed by the template data key under which its contents
+// are available.
+func (c *Config) getLazyTemplateData() map[string]func() (interface{}, error) {
+	lazyTemplateData := make(map[string]func() (interface{}, error), len(c.EncryptedData))
+	for key, encryptedData := range c.EncryptedData {
+		key, encryptedData := key, encryptedData
+		lazyTemplateData[key] = func() (interface{}, error) {
+			// Decryption may prompt the user, so serialize it with secret
+			// managers.
+			c.secretMutex.Lock()
+			defer c.secretMutex.Unlock()
+			value, err := c.readEncryptedData(encryptedData)
+			if err != nil {
+				return nil, err
+			}
+			c.redactor.AddStrings(value)
+			return value, nil
+		}
+	}
+	return lazyTemplateData
+}
+
+// readEncryptedData reads, decrypts, and parses the data file described by
+// encryptedData.
+func (c *Config) readEncryptedData(encryptedData encryptedDataConfig) (map[string]interface{}, error) {
+	if encryptedData.Path == "" {
+		return nil, fmt.Errorf("path not set")
+	}
+	path := encryptedData.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.SourceDir, path)
+	}
+
+	// Infer the encryption and format from the path's extensions, for example
+	// secrets.yaml.asc is GPG-encrypted YAML.
+	name := filepath.Base(path)
+	encryption := encryptedData.Encryption
+	if encryption == "" {
+		ext := strings.ToLower(filepath.Ext(name))
+		var ok bool
+		encryption, ok = encryptionExtensions[ext]
+		if !ok {
+			return nil, fmt.Errorf("%s: cannot determine encryption", path)
+		}
+	}
+	if ext := strings.ToLower(filepath.Ext(name)); encryptionExtensions[ext] != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	format := encryptedData.Format
+	if format == "" {
+		format = dataFormatExtensions[strings.ToLower(filepath.Ext(name))]
+	}
+	unmarshal, ok := unmarshalFuncs[format]
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot determine format", path)
+	}
+
+	ciphertext, err := c.fs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plaintext []byte
+	switch encryption {
+	case encryptionAge:
+		plaintext, err = c.ageDecrypt(ciphertext)
+	case encryptionGPG:
+		plaintext, err = c.getGPG().Decrypt(name, ciphertext)
+	case encryptionSops:
+		plaintext, err = c.sopsDecrypt(path, format)
+	default:
+		return nil, fmt.Errorf("%s: %s: unknown encryption", path, encryption)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var data map[string]interface{}
+	if err := unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return normalizeDataMap(data), nil
+}
+
+// ageDecrypt decrypts ciphertext with age.
+func (c *Config) ageDecrypt(ciphertext []byte) ([]byte, error) {
+	args := []string{"--decrypt"}
+	if c.Age.Identity != "" {
+		args = append(args, "--identity", c.Age.Identity)
+	}
+	//nolint:gosec
+	cmd := exec.Command(c.Age.Command, args...)
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	cmd.Stderr = c.Stderr
+	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", c.Age.Command, chezmoi.ShellQuoteArgs(args), err)
+	}
+	return output, nil
+}
+
+// sopsDecrypt decrypts the sops file at path, which is in format.
+func (c *Config) sopsDecrypt(path, format string) ([]byte, error) {
+	args := []string{"--decrypt", "--input-type", format, "--output-type", format, path}
+	//nolint:gosec
+	cmd := exec.Command(c.Sops.Command, args...)
+	cmd.Stderr = c.Stderr
+	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", c.Sops.Command, chezmoi.ShellQuoteArgs(args), err)
+	}
+	return output, nil
+}
+
+// normalizeDataMap returns data with all nested map[interface{}]interface{}s,
+// as returned by the YAML parser, converted to map[string]interface{}s so that
+// they can be used by all template functions.
+func normalizeDataMap(data map[string]interface{}) map[string]interface{} {
+	for key, value := range data {
+		data[key] = normalizeDataValue(value)
+	}
+	return data
+}
+
+func normalizeDataValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = normalizeDataValue(v)
+		}
+		return result
+	case map[string]interface{}:
+		return normalizeDataMap(value)
+	case []interface{}:
+		for i, v := range value {
+			value[i] = normalizeDataValue(v)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// Output formats.
+const (
+	outputFormatJSON = "json"
+	outputFormatText = "text"
+)
+
+// Reasons for skipping entries.
+const (
+	skipReasonFiltered     = "filtered"
+	skipReasonIgnored      = "ignored"
+	skipReasonParentFailed = "parentFailed"
+	skipReasonUpToDate     = "upToDate"
+)
+
+// An eventWriter writes events as JSON lines.
+type eventWriter struct {
+	encoder     *json.Encoder
+	redactor    *chezmoi.Redactor
+	sourcePaths map[string]string
+	touched     map[string]bool
+	summary     chezmoi.EventSummary
+	err         error
+}
+
+// withEvents calls f with mutator, wrapped so that every change is written to
+// c.Stdout as an event if the output format is JSON. applyOptions is updated
+// so that scripts are also reported. After f returns, entries in args that
+// were not changed, any error, and a summary are written.
+func (c *Config) withEvents(ts *chezmoi.TargetState, args []string, mutator chezmoi.Mutator, applyOptions *chezmoi.ApplyOptions, f func(chezmoi.Mutator) error) error {
+	if c.OutputFormat != outputFormatJSON {
+		return f(mutator)
+	}
+
+	entries := allTargetStateEntries(ts)
+	w := newEventWriter(c.Stdout, c.getRedactor())
+	w.summary.DryRun = c.DryRun
+	for _, entry := range entries {
+		w.sourcePaths[ts.TargetPath(entry)] = ts.SourcePath(entry)
+	}
+	applyOptions.Events = w.emit
+
+	err := f(chezmoi.NewEventMutator(mutator, w.emit))
+	var applyErrors chezmoi.ApplyErrors
+	failed := make(map[string]bool)
+	if errors.As(err, &applyErrors) {
+		for _, entryError := range applyErrors {
+			// Errors in destination roots are recorded with their target
+			// paths.
+			targetPath := entryError.TargetName
+			if !filepath.IsAbs(targetPath) {
+				targetPath = filepath.Join(ts.DestDir, targetPath)
+			}
+			failed[targetPath] = true
+			w.emit(&chezmoi.Event{
+				Type:       chezmoi.EventTypeError,
+				TargetPath: targetPath,
+				Error:      entryError.Err.Error(),
+			})
+		}
+	}
+	switch {
+	case err == nil || applyErrors != nil:
+		argPaths := make([]string, 0, len(args))
+		for _, arg := range args {
+			argPath, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			argPaths = append(argPaths, argPath)
+		}
+		for _, entry := range entries {
+			targetPath := ts.TargetPath(entry)
+			if w.touched[targetPath] || failed[targetPath] || !matchesRelPaths(targetPath, argPaths) {
+				continue
+			}
+			reason := skipReasonUpToDate
+			switch {
+			case ts.Ignored(entry):
+				reason = skipReasonIgnored
+			case !applyOptions.Filter.IncludeEntry(entry):
+				reason = skipReasonFiltered
+			case hasParent(failed, targetPath):
+				reason = skipReasonParentFailed
+			}
+			w.emit(&chezmoi.Event{
+				Type:       chezmoi.EventTypeSkip,
+				TargetPath: targetPath,
+				Reason:     reason,
+			})
+		}
+	case err != errExitFailure:
+		event := &chezmoi.Event{
+			Type:  chezmoi.EventTypeError,
+			Error: err.Error(),
+		}
+		var entryError *chezmoi.EntryError
+		if errors.As(err, &entryError) {
+			event.TargetPath = entryError.TargetName
+			if !filepath.IsAbs(event.TargetPath) {
+				event.TargetPath = filepath.Join(ts.DestDir, event.TargetPath)
+			}
+			event.Error = entryError.Err.Error()
+		}
+		w.emit(event)
+	}
+	w.emit(&chezmoi.Event{
+		Type:    chezmoi.EventTypeSummary,
+		Summary: &w.summary,
+	})
+	if w.err != nil {
+		return w.err
+	}
+	if err != nil {
+		// The error has already been written as an event.
+		return errExitFailure
+	}
+	return nil
+}
+
+// newEventWriter returns a new eventWriter that writes to w, redacting
+// errors with redactor.
+func newEventWriter(w io.Writer, redactor *chezmoi.Redactor) *eventWriter {
+	return &eventWriter{
+		encoder:     json.NewEncoder(w),
+		redactor:    redactor,
+		sourcePaths: make(map[string]string),
+		touched:     make(map[string]bool),
+	}
+}
+
+// emit writes event, adding its source path and updating the summary. The
+// first write error is recorded in w.err.
+func (w *eventWriter) emit(event *chezmoi.Event) {
+	if event.SourcePath == "" {
+		event.SourcePath = w.sourcePaths[event.TargetPath]
+	}
+	event.Error = w.redactor.RedactString(event.Error)
+	switch event.Type {
+	case chezmoi.EventTypeAction:
+		w.touched[event.TargetPath] = true
+		w.summary.Actions++
+	case chezmoi.EventTypeError:
+		w.summary.Errors++
+	case chezmoi.EventTypeScript:
+		w.touched[event.TargetPath] = true
+		w.summary.Scripts++
+	case chezmoi.EventTypeSkip:
+		w.summary.Skipped++
+	}
+	if err := w.encoder.Encode(event); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// hasParent returns whether any parent directory of targetPath is in dirs.
+func hasParent(dirs map[string]bool, targetPath string) bool {
+	for dir := filepath.Dir(targetPath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if dirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// allTargetStateEntries returns all entries in ts and its roots, including
+// scripts, in the order in which they are applied.
+func allTargetStateEntries(ts *chezmoi.TargetState) []chezmoi.Entry {
+	allEntries := appendSortedEntries(nil, ts.Entries)
+	rootNames := make([]string, 0, len(ts.Roots))
+	for rootName := range ts.Roots {
+		rootNames = append(rootNames, rootName)
+	}
+	sort.Strings(rootNames)
+	for _, rootName := range rootNames {
+		allEntries = append(allEntries, allTargetStateEntries(ts.Roots[rootName])...)
+	}
+	return allEntries
+}
+
+// appendSortedEntries appends entries and all their descendants to
+// allEntries, sorted by name.
+func appendSortedEntries(allEntries []chezmoi.Entry, entries map[string]chezmoi.Entry) []chezmoi.Entry {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry := entries[name]
+		allEntries = append(allEntries, entry)
+		if dir, ok := entry.(*chezmoi.Dir); ok {
+			allEntries = appendSortedEntries(allEntries, dir.Entries)
+		}
+	}
+	return allEntries
+}
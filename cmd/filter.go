@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+type filterConfig struct {
+	include []string
+	exclude []string
+}
+
+// addFilterFlags adds flags to cmd to include and exclude entries by type.
+func addFilterFlags(cmd *cobra.Command) {
+	persistentFlags := cmd.PersistentFlags()
+	persistentFlags.StringSliceVarP(&config.filter.include, "include", "i", []string{"all"}, "include entry types")
+	persistentFlags.StringSliceVarP(&config.filter.exclude, "exclude", "x", []string{"none"}, "exclude entry types")
+}
+
+// getFilter returns the filter selected by the --include and --exclude
+// flags. If no entry types are included then all are.
+func (c *Config) getFilter() (*chezmoi.EntryTypeFilter, error) {
+	include := chezmoi.EntryTypesAll
+	if len(c.filter.include) != 0 {
+		var err error
+		include, err = chezmoi.ParseEntryTypeSet(c.filter.include)
+		if err != nil {
+			return nil, err
+		}
+	}
+	exclude, err := chezmoi.ParseEntryTypeSet(c.filter.exclude)
+	if err != nil {
+		return nil, err
+	}
+	return chezmoi.NewEntryTypeFilter(include, exclude), nil
+}
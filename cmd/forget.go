@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"path/filepath"
-
 	"github.com/spf13/cobra"
 )
 
@@ -34,9 +32,11 @@ func (c *Config) runForgetCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	for _, entry := range entries {
-		if err := c.mutator.RemoveAll(filepath.Join(c.SourceDir, entry.SourceName())); err != nil {
+		sourcePath := ts.SourcePath(entry)
+		if err := c.mutator.RemoveAll(sourcePath); err != nil {
 			return err
 		}
+		c.sourceLayerChanged(sourcePath)
 	}
 	return nil
 }
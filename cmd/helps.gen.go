@@ -13,7 +13,9 @@ var helps = map[string]help{
 			"Description:\n" +
 			"  Add *targets* to the source state. If any target is already in the source\n" +
 			"  state, then its source state is replaced with its current state in the\n" +
-			"  destination directory. The `add` command accepts additional flags:\n" +
+			"  destination directory. When run as root, the owner and group of any target\n" +
+			"  that is not owned by root are added to `.chezmoiattributes`. The `add` command\n" +
+			"  accepts additional flags:\n" +
 			"\n" +
 			"  `--autotemplate`\n" +
 			"\n" +
@@ -34,6 +36,11 @@ var helps = map[string]help{
 			"\n" +
 			"  Set the `exact` attribute on added directories.\n" +
 			"\n" +
+			"  `--layer` *directory*\n" +
+			"\n" +
+			"  Add *targets* to the source layer *directory*, which must be the source\n" +
+			"  directory or one of `sourceLayers`, instead of the source directory.\n" +
+			"\n" +
 			"  `-p`, `--prompt`\n" +
 			"\n" +
 			"  Interactively prompt before adding each file.\n" +
@@ -49,17 +56,87 @@ var helps = map[string]help{
 			"  chezmoi add ~/.bashrc\n" +
 			"  chezmoi add ~/.gitconfig --template\n" +
 			"  chezmoi add ~/.vim --recursive\n" +
-			"  chezmoi add ~/.oh-my-zsh --exact --recursive",
+			"  chezmoi add ~/.oh-my-zsh --exact --recursive\n" +
+			"  chezmoi add ~/.gitconfig --layer=/usr/local/share/dotfiles",
 	},
 	"apply": {
 		long: "" +
 			"Description:\n" +
 			"  Ensure that *targets* are in the target state, updating them if necessary. If\n" +
-			"  no targets are specified, the state of all targets are ensured.",
+			"  no targets are specified, the state of all targets are ensured.\n" +
+			"\n" +
+			"  `-k`, `--keep-going`\n" +
+			"\n" +
+			"  Keep going after a target cannot be applied, for example because its template\n" +
+			"  fails, and apply all other targets. Targets inside a directory that could not\n" +
+			"  be created are skipped. When finished, chezmoi prints a summary of the targets\n" +
+			"  that could not be applied and exits with a non-zero exit code. This can be set\n" +
+			"  with the `apply.keepGoing` variable in the configuration file.\n" +
+			"\n" +
+			"  `--transactional`\n" +
+			"\n" +
+			"  Record the state of every file, directory, and symlink before it is changed.\n" +
+			"  If any target cannot be applied then all changes already made are reverted.\n" +
+			"  This can be set with the `apply.transactional` variable in the configuration\n" +
+			"  file.\n" +
+			"\n" +
+			"  The record of a successful transactional apply is kept in\n" +
+			"  `chezmoijournal.json`, next to chezmoi's persistent state, so that it can\n" +
+			"  later be undone with `--rollback-last`. It contains the previous contents of\n" +
+			"  changed files, which may include secrets, so it is only readable by you. The\n" +
+			"  effects of scripts are not recorded and are never reverted.\n" +
+			"\n" +
+			"  `--rollback-last`\n" +
+			"\n" +
+			"  Revert the changes made by the most recent successful transactional apply.\n" +
+			"\n" +
+			"  `--plan` *filename*\n" +
+			"\n" +
+			"  Write a plan of every change that `apply` would make to *filename*, or to the\n" +
+			"  standard output if *filename* is `-`, instead of making the changes. Each step\n" +
+			"  of the plan records the expected state of its target before the change, and\n" +
+			"  steps that write files include the new contents and their SHA256 hash. Scripts\n" +
+			"  that would be run are included with their contents. Plans can contain secrets,\n" +
+			"  so they are written with permissions `0600`.\n" +
+			"\n" +
+			"  `--from-plan` *filename*\n" +
+			"\n" +
+			"  Make exactly the changes in the plan in *filename*, or read from the standard\n" +
+			"  input if *filename* is `-`, without reading the source state. Execution stops\n" +
+			"  at the first step whose target is no longer in the state that the plan\n" +
+			"  expects. `run_once_` scripts in the plan that have already been run, for\n" +
+			"  example by an earlier `apply`, are skipped. `--from-plan` cannot be used with `--\n" +
+			"  plan`.\n" +
+			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only apply entries of type *types*. *types* is a comma-separated list of types\n" +
+			"  of entry to include. Valid types are `all` (the default), `scripts`, and\n" +
+			"  `dirs`, `files`, `symlinks`, `encrypted`, `templates`, and `once-scripts`,\n" +
+			"  which can be abbreviated to `d`, `f`, `s`, `e`, `t`, and `o` respectively. An\n" +
+			"  entry is included if it has any of the included types, so `--include=encrypted`\n" +
+			"  includes only encrypted files and `--include=templates` includes files,\n" +
+			"  symlinks, and scripts that are templates.\n" +
+			"\n" +
+			"  Directories that are not included are neither created nor modified, but\n" +
+			"  included entries inside them are still applied if the directory already\n" +
+			"  exists.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Do not apply entries of any of the types *types*, even if they are included.\n" +
+			"  *types* takes the same values as `--include`, plus `none`, which is the default.\n" +
+			"  For example, `--exclude=scripts` applies everything except scripts.",
 		example: "" +
 			"  chezmoi apply\n" +
 			"  chezmoi apply --dry-run --verbose\n" +
-			"  chezmoi apply ~/.bashrc",
+			"  chezmoi apply ~/.bashrc\n" +
+			"  chezmoi apply --keep-going\n" +
+			"  chezmoi apply --transactional\n" +
+			"  chezmoi apply --rollback-last\n" +
+			"  chezmoi apply --plan=plan.json\n" +
+			"  chezmoi apply --from-plan=plan.json\n" +
+			"  chezmoi apply --exclude=scripts",
 	},
 	"archive": {
 		long: "" +
@@ -67,12 +144,23 @@ var helps = map[string]help{
 			"  Generate a tar archive of the target state. This can be piped into `tar` to\n" +
 			"  inspect the target state.\n" +
 			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only include entries of type *types*, as for `apply --include`. Directories that\n" +
+			"  are not included are omitted from the archive, but included entries inside\n" +
+			"  them are not.\n" +
+			"\n" +
 			"  `--output`, `-o` *filename*\n" +
 			"\n" +
-			"  Write the output to *filename* instead of stdout.",
+			"  Write the output to *filename* instead of stdout.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Exclude entries of type *types*, as for `apply --exclude`.",
 		example: "" +
 			"  chezmoi archive | tar tvf -\n" +
-			"  chezmoi archive --output=dotfiles.tar",
+			"  chezmoi archive --output=dotfiles.tar\n" +
+			"  chezmoi archive --exclude=encrypted",
 	},
 	"cat": {
 		long: "" +
@@ -170,13 +258,24 @@ var helps = map[string]help{
 			"  version 2.0.0 of chezmoi, `git` format diffs will become the default and\n" +
 			"  include scripts and the `chezmoi` format will be removed.\n" +
 			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only print the differences for entries of type *types*, as for `apply --\n" +
+			"  include`.\n" +
+			"\n" +
 			"  `--no-pager`\n" +
 			"\n" +
-			"  Do not use the pager.",
+			"  Do not use the pager.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Do not print the differences for entries of type *types*, as for `apply --\n" +
+			"  exclude`.",
 		example: "" +
 			"  chezmoi diff\n" +
 			"  chezmoi diff ~/.bashrc\n" +
-			"  chezmoi diff --format=git",
+			"  chezmoi diff --format=git\n" +
+			"  chezmoi diff --include=encrypted",
 	},
 	"docs": {
 		long: "" +
@@ -204,17 +303,29 @@ var helps = map[string]help{
 			"  `-f`, `--format` *format*\n" +
 			"\n" +
 			"  Print the target state in the given format. The accepted formats are `json`\n" +
-			"  (JSON) and `yaml` (YAML).",
+			"  (JSON) and `yaml` (YAML).\n" +
+			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only dump entries of type *types*, as for `apply --include`. Directories that\n" +
+			"  are not included are omitted, and included entries inside them are dumped in\n" +
+			"  their place.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Do not dump entries of type *types*, as for `apply --exclude`.",
 		example: "" +
 			"  chezmoi dump ~/.bashrc\n" +
-			"  chezmoi dump --format=yaml",
+			"  chezmoi dump --format=yaml\n" +
+			"  chezmoi dump --include=templates",
 	},
 	"edit": {
 		long: "" +
 			"Description:\n" +
-			"  Edit the source state of *targets*, which must be files or symlinks. If no\n" +
-			"  targets are given the the source directory itself is opened with `$EDITOR`.\n" +
-			"  The `edit` command accepts additional arguments:\n" +
+			"  Edit the source state of *targets*, which must be files or symlinks, in the\n" +
+			"  source layer that they are in. If no targets are given the the source\n" +
+			"  directory itself is opened with `$EDITOR`. The `edit` command accepts\n" +
+			"  additional arguments:\n" +
 			"\n" +
 			"  `-a`, `--apply`\n" +
 			"\n" +
@@ -225,12 +336,19 @@ var helps = map[string]help{
 			"  Print the difference between the target state and the actual state after\n" +
 			"  editing.. Ignored if there are no targets.\n" +
 			"\n" +
+			"  `--layer` *directory*\n" +
+			"\n" +
+			"  Edit the source state of *targets* in the source layer *directory*, which must\n" +
+			"  be the source directory or one of `sourceLayers`. If no targets are given then\n" +
+			"  *directory* is opened with `$EDITOR`.\n" +
+			"\n" +
 			"  `-p`, `--prompt`\n" +
 			"\n" +
 			"  Prompt before applying each target.. Ignored if there are no targets.",
 		example: "" +
 			"  chezmoi edit ~/.bashrc\n" +
 			"  chezmoi edit ~/.bashrc --apply --prompt\n" +
+			"  chezmoi edit ~/.bashrc --layer=/usr/local/share/dotfiles\n" +
 			"  chezmoi edit",
 	},
 	"edit-config": {
@@ -362,13 +480,18 @@ var helps = map[string]help{
 			"  Only list entries of type *types*. *types* is a comma-separated list of types\n" +
 			"  of entry to include. Valid types are `dirs`, `files`, and `symlinks` which can\n" +
 			"  be abbreviated to `d`, `f`, and `s` respectively. By default, `manage` will\n" +
-			"  list entries of all types.",
+			"  list entries of all types.\n" +
+			"\n" +
+			"  `--show-layer`\n" +
+			"\n" +
+			"  Print the source layer of each entry after its path, separated by a tab.",
 		example: "" +
 			"  chezmoi managed\n" +
 			"  chezmoi managed --include=files\n" +
 			"  chezmoi managed --include=files,symlinks\n" +
 			"  chezmoi managed -i d\n" +
-			"  chezmoi managed -i d,f",
+			"  chezmoi managed -i d,f\n" +
+			"  chezmoi managed --show-layer",
 	},
 	"merge": {
 		long: "" +
@@ -405,6 +528,34 @@ var helps = map[string]help{
 			"\n" +
 			"  Remove without prompting.",
 	},
+	"restore": {
+		long: "" +
+			"Description:\n" +
+			"  Restore *targets* from the most recent backup that contains them. Backups are\n" +
+			"  only made if the `backup.dir` variable is set in the configuration file, in\n" +
+			"  which case every target that is overwritten or removed, including by `apply`\n" +
+			"  and by `restore` itself, is first copied to a new subdirectory of `backup.dir`\n" +
+			"  named after the current time. Targets in destination roots are backed up in\n" +
+			"  the same way in `backup.dir/roots/`*source*, where *source* is the destination\n" +
+			"  root's source subdirectory. Old backups are removed according to the\n" +
+			"  `backup.maxAge` and `backup.maxCount` variables.\n" +
+			"\n" +
+			"  `-l`, `--list`\n" +
+			"\n" +
+			"  List the time and path of every file and symlink in every backup, newest first\n" +
+			"  for the destination directory and for each destination root. If *targets* are\n" +
+			"  given, only list backups of *targets* and their contents.\n" +
+			"\n" +
+			"  `-t`, `--time` *time*\n" +
+			"\n" +
+			"  Ignore backups made after *time*, which can be either a time as printed by `--\n" +
+			"  list` or an RFC3339 https://tools.ietf.org/html/rfc3339 time.",
+		example: "" +
+			"  chezmoi restore --list\n" +
+			"  chezmoi restore --list ~/.bashrc\n" +
+			"  chezmoi restore ~/.bashrc\n" +
+			"  chezmoi restore --time=2020-01-02T03:04:05Z ~/.config",
+	},
 	"rm": {
 		long: "" +
 			"Description:\n" +
@@ -419,18 +570,68 @@ var helps = map[string]help{
 			"  Note that if you want to pass flags to the secret manager's CLI you will need\n" +
 			"  to separate them with `--` to prevent chezmoi from interpreting them.\n" +
 			"\n" +
+			"  chezmoi also has its own secret store, which is stored in `.chezmoisecrets` in\n" +
+			"  the source directory and encrypted with GPG. It can be used on machines that\n" +
+			"  do not have a secret manager installed. The following commands manage it:\n" +
+			"\n" +
+			"       COMMAND     |             EFFECT\n" +
+			"  -----------------+---------------------------------\n" +
+			"    secret get key | Print the value of key.\n" +
+			"    secret list    | List all keys.\n" +
+			"    secret rm keys | Remove keys.\n" +
+			"    secret set key | Set the value of key,\n" +
+			"                   | prompting for it unless\n" +
+			"                   | --value is given.\n" +
+			"\n" +
+			"  If `secretCache.ttl` is set then the results of secret manager lookups are\n" +
+			"  cached in chezmoi's persistent state for that duration, so that subsequent\n" +
+			"  runs do not need to invoke or unlock the secret manager. Cached values are\n" +
+			"  encrypted with a key that is stored in your keyring. Values are added to the\n" +
+			"  cache by every command, including `chezmoi diff`, `chezmoi verify`, and `--dry-\n" +
+			"  run`, which add them when they finish. `chezmoi secret cache flush` removes\n" +
+			"  all cached values.\n" +
+			"\n" +
+			"  `chezmoi secret audit` [*targets*] reports which targets use which secrets,\n" +
+			"  without executing any templates or invoking any secret manager. It parses\n" +
+			"  every template in the source state, including `.chezmoiattributes`,\n" +
+			"  `.chezmoiignore`, `.chezmoiremove`, and templates in `.chezmoitemplates` that\n" +
+			"  they include, and lists each call to a secret template function, for example\n" +
+			"  `pass`, `onepassword`, `bitwarden`, `vault`, `keyring`, `lastpass`, `gopass`,\n" +
+			"  `keepassxc`, or `secret`, with its arguments. Arguments that are not literals,\n" +
+			"  and so cannot be known without executing the template, are reported as `null`.\n" +
+			"  Encrypted templates are not audited. The output format can be set with `--\n" +
+			"  format`, either `json` (the default) or `yaml`.\n" +
+			"\n" +
+			"  Secret providers configured in the `secretProviders` section of the\n" +
+			"  configuration file are available as `secret` *name* subcommands, for example\n" +
+			"  `chezmoi secret` *name* `get` *id*, `chezmoi secret` *name* `get-field` *id*\n" +
+			"  *field*, and `chezmoi secret` *name* `list`. See Secret provider protocol.\n" +
+			"\n" +
 			"  To get a full list of available commands run:\n" +
 			"\n" +
 			"    chezmoi secret help",
 		example: "" +
+			"  chezmoi secret set --value=token github\n" +
+			"  chezmoi secret get github\n" +
+			"  chezmoi secret list\n" +
+			"  chezmoi secret rm github\n" +
+			"  chezmoi secret cache flush\n" +
+			"  chezmoi secret audit\n" +
+			"  chezmoi secret audit --format=yaml ~/.netrc\n" +
 			"  chezmoi secret bitwarden list items\n" +
 			"  chezmoi secret keyring set --service service --user user\n" +
 			"  chezmoi secret keyring get --service service --user user\n" +
+			"  chezmoi secret keyring set --service service --user user --password-file=-\n" +
+			"  chezmoi secret keyring delete --service service --user user\n" +
+			"  chezmoi secret keyring import passwords.json\n" +
 			"  chezmoi secret lastpass ls\n" +
 			"  chezmoi secret lastpass -- show --format=json id\n" +
 			"  chezmoi secret onepassword list items\n" +
 			"  chezmoi secret onepassword get item id\n" +
 			"  chezmoi secret pass show id\n" +
+			"  chezmoi secret myvault get id\n" +
+			"  chezmoi secret myvault get-field id username\n" +
+			"  chezmoi secret myvault list\n" +
 			"  chezmoi secret vault -- kv get -format=json id",
 	},
 	"source": {
@@ -500,9 +701,87 @@ var helps = map[string]help{
 			"Description:\n" +
 			"  Verify that all *targets* match their target state. chezmoi exits with code 0\n" +
 			"  (success) if all targets match their target state, or 1 (failure) otherwise.\n" +
-			"  If no targets are specified then all targets are checked.",
+			"  If no targets are specified then all targets are checked.\n" +
+			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only verify entries of type *types*, as for `apply --include`.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Do not verify entries of type *types*, as for `apply --exclude`.",
 		example: "" +
 			"  chezmoi verify\n" +
-			"  chezmoi verify ~/.bashrc",
+			"  chezmoi verify ~/.bashrc\n" +
+			"  chezmoi verify --exclude=scripts",
+	},
+	"watch": {
+		long: "" +
+			"Description:\n" +
+			"  Watch the source directory for changes and apply them as they are made,\n" +
+			"  printing the differences, until interrupted with Ctrl-C. `.git` directories and\n" +
+			"  directories whose targets are ignored by `.chezmoiignore` are not watched.\n" +
+			"\n" +
+			"  After each change, chezmoi waits until no further changes have been made for a\n" +
+			"  short time, then reads the source state again and applies only the targets\n" +
+			"  whose source files or directories changed. Changes to special files, like\n" +
+			"  `.chezmoiignore` or templates in `.chezmoitemplates`, apply all targets.\n" +
+			"  Errors, for example in templates, are printed and chezmoi keeps watching.\n" +
+			"  Removing a file from the source directory does not remove its target.\n" +
+			"\n" +
+			"  `--debounce` *duration*\n" +
+			"\n" +
+			"  Wait until no changes have been made for *duration* before applying them. The\n" +
+			"  default is `100ms`.\n" +
+			"\n" +
+			"  `--drift`\n" +
+			"\n" +
+			"  Instead of the source directory, watch the managed files and symlinks in the\n" +
+			"  destination directory. When a target is changed so that it no longer matches\n" +
+			"  its target state, chezmoi applies its drift policy, which is one of:\n" +
+			"\n" +
+			"    POLICY |             ACTION\n" +
+			"  ---------+---------------------------------\n" +
+			"    log    | Print that the target changed\n" +
+			"    readd  | Add the target to the source\n" +
+			"           | state, as with chezmoi add\n" +
+			"    revert | Apply the target state to the\n" +
+			"           | target again, printing the\n" +
+			"           | differences\n" +
+			"\n" +
+			"  Targets generated from templates and targets that were removed cannot be re-\n" +
+			"  added, so the `readd` policy only prints that they changed. Re-added targets\n" +
+			"  are committed and pushed according to the `sourceVCS.autoCommit` and\n" +
+			"  `sourceVCS.autoPush` variables. In `json` output format, each changed target\n" +
+			"  is reported as a `drift` event whose `action` is the policy that was applied.\n" +
+			"\n" +
+			"  The default policy is set with the `drift.policy` variable in the\n" +
+			"  configuration file. Policies for individual targets are set with\n" +
+			"  `drift.paths`, a list of `pattern`s and `policy`s. Patterns match target paths\n" +
+			"  relative to the destination directory, as in `.chezmoiignore`, and the first\n" +
+			"  matching pattern is used. For example:\n" +
+			"\n" +
+			"    [drift]\n" +
+			"      policy = \"log\"\n" +
+			"      [[drift.paths]]\n" +
+			"        pattern = \".ssh/**\"\n" +
+			"        policy = \"revert\"\n" +
+			"      [[drift.paths]]\n" +
+			"        pattern = \".bashrc\"\n" +
+			"        policy = \"readd\"\n" +
+			"\n" +
+			"  Only directories that contain targets when chezmoi starts are watched.\n" +
+			"\n" +
+			"  `-i`, `--include` *types*\n" +
+			"\n" +
+			"  Only apply entries of type *types*, as for `apply --include`.\n" +
+			"\n" +
+			"  `-x`, `--exclude` *types*\n" +
+			"\n" +
+			"  Do not apply entries of type *types*, as for `apply --exclude`.",
+		example: "" +
+			"  chezmoi watch\n" +
+			"  chezmoi watch --debounce=1s --exclude=scripts\n" +
+			"  chezmoi watch --drift",
 	},
 }
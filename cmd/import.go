@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -43,7 +42,9 @@ func init() {
 }
 
 func (c *Config) runImportCmd(cmd *cobra.Command, args []string) error {
-	ts, err := c.getTargetState(nil)
+	// Archives are always imported in to the source directory, not in to
+	// any of the source layers beneath it.
+	ts, err := c.getLayerTargetState("", nil)
 	if err != nil {
 		return err
 	}
@@ -76,7 +77,7 @@ func (c *Config) runImportCmd(cmd *cobra.Command, args []string) error {
 		entry, err := ts.Get(c.fs, c._import.importTAROptions.DestinationDir)
 		switch {
 		case err == nil:
-			if err := c.mutator.RemoveAll(filepath.Join(c.SourceDir, entry.SourceName())); err != nil {
+			if err := c.mutator.RemoveAll(ts.SourcePath(entry)); err != nil {
 				return err
 			}
 		case os.IsNotExist(err):
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
 	"sort"
 
 	"github.com/spf13/cobra"
@@ -21,7 +20,8 @@ var managedCmd = &cobra.Command{
 }
 
 type managedCmdConfig struct {
-	include []string
+	include   []string
+	showLayer bool
 }
 
 func init() {
@@ -29,6 +29,7 @@ func init() {
 
 	persistentFlags := managedCmd.PersistentFlags()
 	persistentFlags.StringSliceVarP(&config.managed.include, "include", "i", []string{"dirs", "files", "symlinks"}, "include")
+	persistentFlags.BoolVar(&config.managed.showLayer, "show-layer", false, "show the source layer of each target")
 }
 
 func (c *Config) runManagedCmd(cmd *cobra.Command, args []string) error {
@@ -57,7 +58,7 @@ func (c *Config) runManagedCmd(cmd *cobra.Command, args []string) error {
 
 	allEntries := ts.AllEntries()
 
-	targetNames := make([]string, 0, len(allEntries))
+	entries := make([]chezmoi.Entry, 0, len(allEntries))
 	for _, entry := range allEntries {
 		if _, ok := entry.(*chezmoi.Dir); ok && !includeDirs {
 			continue
@@ -68,15 +69,22 @@ func (c *Config) runManagedCmd(cmd *cobra.Command, args []string) error {
 		if _, ok := entry.(*chezmoi.Symlink); ok && !includeSymlinks {
 			continue
 		}
-		targetNames = append(targetNames, entry.TargetName())
+		entries = append(entries, entry)
 	}
 
-	sort.Strings(targetNames)
-	for _, targetName := range targetNames {
-		if ts.TargetIgnore.Match(targetName) {
+	sort.Slice(entries, func(i, j int) bool {
+		return ts.TargetPath(entries[i]) < ts.TargetPath(entries[j])
+	})
+	for _, entry := range entries {
+		if ts.Ignored(entry) {
 			continue
 		}
-		fmt.Fprintln(c.Stdout, filepath.Join(ts.DestDir, targetName))
+		targetPath := ts.TargetPath(entry)
+		if c.managed.showLayer {
+			fmt.Fprintf(c.Stdout, "%s\t%s\n", targetPath, ts.SourceDirOf(entry))
+		} else {
+			fmt.Fprintln(c.Stdout, targetPath)
+		}
 	}
 
 	return nil
@@ -53,7 +53,7 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string) error {
 	defer os.RemoveAll(tempDir)
 
 	for i, entry := range entries {
-		if err := c.runMergeCommand(cmd, args[i], entry, tempDir); err != nil {
+		if err := c.runMergeCommand(cmd, ts, args[i], entry, tempDir); err != nil {
 			return err
 		}
 	}
@@ -61,7 +61,7 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (c *Config) runMergeCommand(cmd *cobra.Command, arg string, entry chezmoi.Entry, tempDir string) error {
+func (c *Config) runMergeCommand(cmd *cobra.Command, ts *chezmoi.TargetState, arg string, entry chezmoi.Entry, tempDir string) error {
 	file, ok := entry.(*chezmoi.File)
 	if !ok {
 		return fmt.Errorf("%s: not a file", arg)
@@ -71,8 +71,8 @@ func (c *Config) runMergeCommand(cmd *cobra.Command, arg string, entry chezmoi.E
 	// source state.
 	args := append(
 		append([]string{}, c.Merge.Args...),
-		filepath.Join(c.DestDir, file.TargetName()),
-		filepath.Join(c.SourceDir, file.SourceName()),
+		ts.TargetPath(file),
+		ts.SourcePath(file),
 	)
 
 	// Try to evaluate the target state. If this succeeds, perform a three-way
@@ -3,9 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
 )
 
 type removeCmdConfig struct {
@@ -42,30 +43,35 @@ func (c *Config) runRemoveCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return nil
 	}
-	for _, entry := range entries {
-		destDirPath := filepath.Join(c.DestDir, entry.TargetName())
-		sourceDirPath := filepath.Join(c.SourceDir, entry.SourceName())
-		if !c.remove.force {
-			choice, err := c.prompt(fmt.Sprintf("Remove %s and %s", destDirPath, sourceDirPath), "ynqa")
-			if err != nil {
+	// Targets are backed up before they are removed, but source files are
+	// not.
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		for _, entry := range entries {
+			destDirPath := ts.TargetPath(entry)
+			sourceDirPath := ts.SourcePath(entry)
+			if !c.remove.force {
+				choice, err := c.prompt(fmt.Sprintf("Remove %s and %s", destDirPath, sourceDirPath), "ynqa")
+				if err != nil {
+					return err
+				}
+				switch choice {
+				case 'y':
+				case 'n':
+					continue
+				case 'q':
+					return nil
+				case 'a':
+					c.remove.force = true
+				}
+			}
+			if err := mutator.RemoveAll(destDirPath); err != nil && !os.IsNotExist(err) {
 				return err
 			}
-			switch choice {
-			case 'y':
-			case 'n':
-				continue
-			case 'q':
-				return nil
-			case 'a':
-				c.remove.force = true
+			if err := c.mutator.RemoveAll(sourceDirPath); err != nil && !os.IsNotExist(err) {
+				return err
 			}
+			c.sourceLayerChanged(sourceDirPath)
 		}
-		if err := c.mutator.RemoveAll(destDirPath); err != nil && !os.IsNotExist(err) {
-			return err
-		}
-		if err := c.mutator.RemoveAll(sourceDirPath); err != nil && !os.IsNotExist(err) {
-			return err
-		}
-	}
-	return nil
+		return nil
+	})
 }
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:     "restore [targets...]",
+	Short:   "Restore targets from backups",
+	Long:    mustGetLongHelp("restore"),
+	Example: getExample("restore"),
+	PreRunE: config.ensureNoError,
+	RunE:    config.runRestoreCmd,
+}
+
+type backupConfig struct {
+	Dir      string
+	MaxAge   time.Duration
+	MaxCount int
+}
+
+type restoreCmdConfig struct {
+	list bool
+	time string
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	persistentFlags := restoreCmd.PersistentFlags()
+	persistentFlags.BoolVarP(&config.restore.list, "list", "l", false, "list backups")
+	persistentFlags.StringVarP(&config.restore.time, "time", "t", "", "restore the most recent backup at or before time")
+
+	markRemainingZshCompPositionalArgumentsAsFiles(restoreCmd, 1)
+}
+
+// A backupLocation is a destination directory and the directory that its
+// backups are stored in.
+type backupLocation struct {
+	destDir   string
+	backupDir string
+}
+
+// A restoreTarget is a target to restore and its backup location.
+type restoreTarget struct {
+	arg      string
+	location *backupLocation
+	relPath  string
+}
+
+func (c *Config) runRestoreCmd(cmd *cobra.Command, args []string) error {
+	if c.Backup.Dir == "" {
+		return errors.New("backup.dir not set")
+	}
+
+	locations, err := c.getBackupLocations()
+	if err != nil {
+		return err
+	}
+
+	restoreTargets := make([]restoreTarget, 0, len(args))
+	for _, arg := range args {
+		targetPath, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		// Roots may be inside the destination directory, so use the location
+		// with the longest destination directory that contains targetPath.
+		var target *restoreTarget
+		for _, location := range locations {
+			relPath, err := filepath.Rel(location.destDir, targetPath)
+			if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+				continue
+			}
+			if target == nil || len(location.destDir) > len(target.location.destDir) {
+				target = &restoreTarget{
+					arg:      arg,
+					location: location,
+					relPath:  relPath,
+				}
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("%s: not in destination directory", arg)
+		}
+		restoreTargets = append(restoreTargets, *target)
+	}
+
+	if c.restore.list {
+		for _, location := range locations {
+			var relPaths []string
+			for _, target := range restoreTargets {
+				if target.location == location {
+					relPaths = append(relPaths, target.relPath)
+				}
+			}
+			if len(args) != 0 && len(relPaths) == 0 {
+				continue
+			}
+			if err := c.listBackups(location, relPaths); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(restoreTargets) == 0 {
+		return errors.New("no targets specified")
+	}
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		for _, target := range restoreTargets {
+			if err := c.restoreLatestBackup(mutator, target.location, target.relPath); err != nil {
+				return fmt.Errorf("%s: %w", target.arg, err)
+			}
+		}
+		return nil
+	})
+}
+
+// getBackupLocations returns the backup locations of the destination directory
+// and of every root. The backups of each root are stored in a subdirectory of
+// c.Backup.Dir named after the root's source subdirectory.
+func (c *Config) getBackupLocations() ([]*backupLocation, error) {
+	locations := make([]*backupLocation, 0, len(c.Roots)+1)
+	locations = append(locations, &backupLocation{
+		destDir:   c.DestDir,
+		backupDir: c.Backup.Dir,
+	})
+	for _, root := range c.Roots {
+		source, destDir, err := root.resolve()
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, &backupLocation{
+			destDir:   destDir,
+			backupDir: filepath.Join(c.Backup.Dir, "roots", source),
+		})
+	}
+	return locations, nil
+}
+
+// listBackups prints the time and target path of every file and symlink in
+// the backups in location that matches relPaths, newest first.
+func (c *Config) listBackups(location *backupLocation, relPaths []string) error {
+	backups, err := c.readBackups(location)
+	if err != nil {
+		return err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		paths, err := backups[i].Paths(c.fs)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if !matchesRelPaths(path, relPaths) {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Stdout, "%s %s\n", backups[i].Time.Format(chezmoi.BackupTimeFormat), filepath.Join(location.destDir, path)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readBackups returns the backups in location, oldest first, ignoring backups
+// made after the time given with --time.
+func (c *Config) readBackups(location *backupLocation) ([]*chezmoi.Backup, error) {
+	backups, err := chezmoi.ReadBackups(c.fs, location.backupDir)
+	if err != nil {
+		return nil, err
+	}
+	if c.restore.time != "" {
+		t, err := parseBackupTime(c.restore.time)
+		if err != nil {
+			return nil, err
+		}
+		for len(backups) > 0 && backups[len(backups)-1].Time.After(t) {
+			backups = backups[:len(backups)-1]
+		}
+	}
+	return backups, nil
+}
+
+// restoreLatestBackup restores relPath from the newest backup in location
+// that contains it.
+func (c *Config) restoreLatestBackup(mutator chezmoi.Mutator, location *backupLocation, relPath string) error {
+	backups, err := c.readBackups(location)
+	if err != nil {
+		return err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		ok, err := backups[i].Contains(c.fs, relPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return backups[i].Restore(c.fs, mutator, location.destDir, relPath)
+		}
+	}
+	return errors.New("no backup")
+}
+
+// withBackups calls f with c.mutator wrapped so that targets in the
+// destination directory and in every root are backed up before they are
+// overwritten or removed, and then prunes old backups. If backups are not
+// configured then f is called with c.mutator.
+func (c *Config) withBackups(f func(chezmoi.Mutator) error) error {
+	if c.Backup.Dir == "" || c.DryRun {
+		return f(c.mutator)
+	}
+	locations, err := c.getBackupLocations()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	mutator := c.mutator
+	for _, location := range locations {
+		mutator = chezmoi.NewBackupMutator(mutator, c.fs, location.destDir, location.backupDir, now)
+	}
+	err = f(mutator)
+	for _, location := range locations {
+		if pruneErr := chezmoi.PruneBackups(c.fs, location.backupDir, c.Backup.MaxAge, c.Backup.MaxCount, now); err == nil {
+			err = pruneErr
+		}
+	}
+	return err
+}
+
+// matchesRelPaths returns whether path is, or is in, any of relPaths. All
+// paths match if relPaths is empty.
+func matchesRelPaths(path string, relPaths []string) bool {
+	if len(relPaths) == 0 {
+		return true
+	}
+	for _, relPath := range relPaths {
+		if path == relPath || strings.HasPrefix(path, relPath+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBackupTime parses s as either the name of a backup or as an RFC3339
+// time.
+func parseBackupTime(s string) (time.Time, error) {
+	if t, err := time.Parse(chezmoi.BackupTimeFormat, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: invalid time", s)
+	}
+	return t, nil
+}
@@ -30,11 +30,12 @@ var (
 )
 
 var rootCmd = &cobra.Command{
-	Use:               "chezmoi",
-	Short:             "Manage your dotfiles across multiple machines, securely",
-	SilenceErrors:     true,
-	SilenceUsage:      true,
-	PersistentPreRunE: config.persistentPreRunRootE,
+	Use:                "chezmoi",
+	Short:              "Manage your dotfiles across multiple machines, securely",
+	SilenceErrors:      true,
+	SilenceUsage:       true,
+	PersistentPreRunE:  config.persistentPreRunRootE,
+	PersistentPostRunE: config.persistentPostRunRootE,
 }
 
 var (
@@ -80,9 +81,14 @@ func init() {
 	persistentFlags.StringVar(&config.Color, "color", "auto", "colorize diffs")
 	panicOnError(viper.BindPFlag("color", persistentFlags.Lookup("color")))
 
+	persistentFlags.StringVar(&config.OutputFormat, "output-format", outputFormatText, "output format, \"text\" or \"json\"")
+	panicOnError(viper.BindPFlag("output-format", persistentFlags.Lookup("output-format")))
+
 	persistentFlags.BoolVar(&config.Debug, "debug", false, "write debug logs")
 	panicOnError(viper.BindPFlag("debug", persistentFlags.Lookup("debug")))
 
+	persistentFlags.BoolVar(&config.noRedact, "no-redact", false, "do not redact secrets in output")
+
 	cobra.OnInitialize(func() {
 		_, err := os.Stat(config.configFile)
 		switch {
@@ -95,6 +101,9 @@ func init() {
 			if config.err == nil {
 				config.err = config.validateData()
 			}
+			if config.err == nil {
+				config.err = config.validateSecretProviders()
+			}
 			if config.err != nil {
 				rootCmd.Printf("warning: %s: %v\n", config.configFile, config.err)
 			}
@@ -149,6 +158,21 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// persistentPostRunRootE writes any pending secret cache entries and closes
+// the persistent state, which the secret cache may have opened.
+func (c *Config) persistentPostRunRootE(cmd *cobra.Command, args []string) error {
+	if err := c.flushSecretCache(); err != nil {
+		return err
+	}
+	if c.persistentState != nil {
+		if err := c.persistentState.Close(); err != nil {
+			return err
+		}
+		c.persistentState = nil
+	}
+	return nil
+}
+
 func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error {
 	if colored, err := strconv.ParseBool(c.Color); err == nil {
 		c.colored = colored
@@ -171,6 +195,12 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		}
 	}
 
+	switch c.OutputFormat {
+	case outputFormatJSON, outputFormatText:
+	default:
+		return fmt.Errorf("invalid --output-format value: %s", c.OutputFormat)
+	}
+
 	if c.colored {
 		if err := enableVirtualTerminalProcessingOnWindows(c.Stdout); err != nil {
 			return err
@@ -183,10 +213,11 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		c.mutator = chezmoi.NullMutator{}
 	}
 	if c.Debug {
-		c.mutator = chezmoi.NewDebugMutator(c.mutator)
+		c.mutator = chezmoi.NewDebugMutator(c.mutator, c.getRedactor())
 	}
-	if c.Verbose {
-		c.mutator = chezmoi.NewVerboseMutator(c.Stdout, c.mutator, c.colored, c.maxDiffDataSize)
+	// In JSON output mode, changes are reported as events instead.
+	if c.Verbose && c.OutputFormat == outputFormatText {
+		c.mutator = chezmoi.NewVerboseMutator(c.Stdout, c.mutator, c.colored, c.maxDiffDataSize, c.getRedactor())
 	}
 
 	info, err := c.fs.Stat(c.SourceDir)
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// A rootConfig maps a subdirectory of the source directory to a destination
+// directory other than the destination directory.
+type rootConfig struct {
+	Source      string
+	Destination string
+	Umask       *permValue
+	Privileged  bool
+}
+
+// getRoots returns the destination roots configured in c.Roots, keyed by
+// source subdirectory and created with options, and the source subdirectories
+// of inactive roots. Privileged roots are inactive unless chezmoi is running
+// as root.
+func (c *Config) getRoots(options []chezmoi.TargetStateOption) (map[string]*chezmoi.TargetState, map[string]bool, error) {
+	if len(c.Roots) == 0 {
+		return nil, nil, nil
+	}
+	roots := make(map[string]*chezmoi.TargetState, len(c.Roots))
+	inactiveRoots := make(map[string]bool)
+	sources := make(map[string]bool, len(c.Roots))
+	for _, root := range c.Roots {
+		source, destDir, err := root.resolve()
+		if err != nil {
+			return nil, nil, err
+		}
+		if sources[source] {
+			return nil, nil, fmt.Errorf("%s: duplicate root source", root.Source)
+		}
+		sources[source] = true
+		if root.Privileged && os.Geteuid() != 0 {
+			inactiveRoots[source] = true
+			continue
+		}
+		umask := os.FileMode(c.Umask)
+		if root.Umask != nil {
+			umask = os.FileMode(*root.Umask)
+		}
+		rootOptions := make([]chezmoi.TargetStateOption, 0, len(options)+2)
+		rootOptions = append(rootOptions, options...)
+		rootOptions = append(rootOptions,
+			chezmoi.WithDestDir(destDir),
+			chezmoi.WithUmask(umask),
+		)
+		roots[source] = chezmoi.NewTargetState(rootOptions...)
+	}
+	return roots, inactiveRoots, nil
+}
+
+// resolve returns root's cleaned source subdirectory, which must be a
+// top-level subdirectory of the source directory, and absolute destination
+// directory.
+func (root *rootConfig) resolve() (string, string, error) {
+	source := filepath.Clean(root.Source)
+	switch {
+	case root.Source == "" || filepath.IsAbs(source) || strings.HasPrefix(source, string(os.PathSeparator)):
+		return "", "", fmt.Errorf("%s: invalid root source", root.Source)
+	case source == "." || source == ".." || strings.HasPrefix(source, ".."+string(os.PathSeparator)):
+		return "", "", fmt.Errorf("%s: invalid root source", root.Source)
+	case strings.ContainsRune(source, os.PathSeparator):
+		return "", "", fmt.Errorf("%s: root source is not a top-level subdirectory", root.Source)
+	case root.Destination == "":
+		return "", "", fmt.Errorf("%s: root has no destination", root.Source)
+	}
+	destDir, err := filepath.Abs(root.Destination)
+	if err != nil {
+		return "", "", err
+	}
+	return source, destDir, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRoots(t *testing.T) {
+	umask := permValue(0o77)
+	c := &Config{
+		Umask: 0o22,
+		Roots: []rootConfig{
+			{Source: "etc/", Destination: "/etc", Umask: &umask},
+			{Source: "opt", Destination: "/opt/company"},
+		},
+	}
+	roots, inactiveRoots, err := c.getRoots(nil)
+	require.NoError(t, err)
+	require.Len(t, roots, 2)
+	assert.Empty(t, inactiveRoots)
+	etcDir, err := filepath.Abs("/etc")
+	require.NoError(t, err)
+	optCompanyDir, err := filepath.Abs("/opt/company")
+	require.NoError(t, err)
+	assert.Equal(t, etcDir, roots["etc"].DestDir)
+	assert.Equal(t, os.FileMode(0o77), roots["etc"].Umask)
+	assert.Equal(t, optCompanyDir, roots["opt"].DestDir)
+	assert.Equal(t, os.FileMode(0o22), roots["opt"].Umask)
+
+	for _, roots := range [][]rootConfig{
+		{{Source: "", Destination: "/etc"}},
+		{{Source: "/etc", Destination: "/etc"}},
+		{{Source: "../etc", Destination: "/etc"}},
+		{{Source: "system/etc", Destination: "/etc"}},
+		{{Source: "etc"}},
+		{{Source: "etc", Destination: "/etc"}, {Source: "etc/", Destination: "/usr/local/etc"}},
+	} {
+		c.Roots = roots
+		_, _, err := c.getRoots(nil)
+		assert.Error(t, err, roots)
+	}
+}
+
+func TestGetRootsPrivileged(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("running as root")
+	}
+	c := &Config{
+		Roots: []rootConfig{
+			{Source: "etc", Destination: "/etc", Privileged: true},
+		},
+	}
+	roots, inactiveRoots, err := c.getRoots(nil)
+	require.NoError(t, err)
+	assert.Empty(t, roots)
+	assert.Equal(t, map[string]bool{"etc": true}, inactiveRoots)
+}
@@ -4,10 +4,12 @@ import "github.com/spf13/cobra"
 
 var secretCmd = &cobra.Command{
 	Use:     "secret",
-	Args:    cobra.NoArgs,
+	Args:    cobra.ArbitraryArgs,
 	Short:   "Interact with a secret manager",
 	Long:    mustGetLongHelp("secret"),
 	Example: getExample("secret"),
+	PreRunE: config.ensureNoError,
+	RunE:    config.runSecretCmd,
 }
 
 func init() {
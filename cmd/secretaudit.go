@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	vfs "github.com/twpayne/go-vfs"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+var secretAuditCmd = &cobra.Command{
+	Use:     "audit [targets...]",
+	Short:   "Report which targets use which secrets, without executing any templates",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runSecretAuditCmd,
+}
+
+type secretAuditCmdConfig struct {
+	format string
+}
+
+// A secretAuditTarget is the secret usage of a single target.
+type secretAuditTarget struct {
+	TargetPath string            `json:"targetPath" yaml:"targetPath"`
+	SourcePath string            `json:"sourcePath" yaml:"sourcePath"`
+	Calls      []secretAuditCall `json:"calls" yaml:"calls"`
+}
+
+// A secretAuditCall is a single call to a secret template function. Args that
+// cannot be known without executing the template are nil.
+type secretAuditCall struct {
+	Template string    `json:"template,omitempty" yaml:"template,omitempty"`
+	Func     string    `json:"func" yaml:"func"`
+	Args     []*string `json:"args" yaml:"args"`
+	Text     string    `json:"text" yaml:"text"`
+}
+
+// secretAuditPatternNames are the names of files in the source state that are
+// executed as templates but are not targets.
+var secretAuditPatternNames = map[string]bool{
+	".chezmoiattributes": true,
+	".chezmoiignore":     true,
+	".chezmoiremove":     true,
+}
+
+func init() {
+	secretCmd.AddCommand(secretAuditCmd)
+
+	persistentFlags := secretAuditCmd.PersistentFlags()
+	persistentFlags.StringVarP(&config.secretAudit.format, "format", "f", "json", "format (JSON or YAML)")
+
+	markRemainingZshCompPositionalArgumentsAsFiles(secretAuditCmd, 1)
+}
+
+func (c *Config) runSecretAuditCmd(cmd *cobra.Command, args []string) error {
+	// TOML cannot represent the top-level list of targets.
+	formatName := strings.ToLower(c.secretAudit.format)
+	format, ok := formatMap[formatName]
+	if !ok || formatName == "toml" {
+		return fmt.Errorf("%s: unknown format", c.secretAudit.format)
+	}
+
+	// Do not execute any templates, including .chezmoiignore and
+	// .chezmoiremove, so that no secret manager is invoked.
+	ts, err := c.getTargetState(&chezmoi.PopulateOptions{
+		ExecuteTemplates: false,
+		SkipPatterns:     true,
+	})
+	if err != nil {
+		return err
+	}
+
+	var entries []chezmoi.Entry
+	if len(args) == 0 {
+		entries = ts.AllEntries()
+	} else {
+		targetEntries, err := c.getEntries(ts, args)
+		if err != nil {
+			return err
+		}
+		for _, entry := range targetEntries {
+			entries = entry.AppendAllEntries(entries)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].TargetName() < entries[j].TargetName()
+	})
+
+	auditTargets := []secretAuditTarget{}
+	for _, entry := range entries {
+		if !isTemplateEntry(entry) {
+			continue
+		}
+		auditTarget, err := c.auditTemplate(ts, ts.TargetPath(entry), ts.SourcePath(entry))
+		if err != nil {
+			return err
+		}
+		if auditTarget != nil {
+			auditTargets = append(auditTargets, *auditTarget)
+		}
+	}
+
+	if len(args) == 0 {
+		for _, sourceDir := range ts.SourceDirs() {
+			if err := vfs.Walk(c.fs, sourceDir, func(path string, info os.FileInfo, err error) error {
+				switch {
+				case os.IsNotExist(err) && path == sourceDir:
+					return nil
+				case err != nil:
+					return err
+				case info.IsDir() && info.Name() == ".git":
+					return filepath.SkipDir
+				case !secretAuditPatternNames[info.Name()]:
+					return nil
+				}
+				auditTarget, err := c.auditTemplate(ts, "", path)
+				if err != nil {
+					return err
+				}
+				if auditTarget != nil {
+					auditTargets = append(auditTargets, *auditTarget)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return format(c.Stdout, auditTargets)
+}
+
+// auditTemplate returns the secret usage of the template at sourcePath, or nil
+// if it does not use any secrets.
+func (c *Config) auditTemplate(ts *chezmoi.TargetState, targetPath, sourcePath string) (*secretAuditTarget, error) {
+	data, err := c.fs.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(sourcePath).Option(ts.TemplateOptions...).Funcs(ts.TemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	for name, t := range ts.Templates {
+		if tmpl, err = tmpl.AddParseTree(name, t.Tree); err != nil {
+			return nil, err
+		}
+	}
+	templateCalls := chezmoi.FindTemplateCalls(tmpl, sourcePath, c.secretFuncNames)
+	if len(templateCalls) == 0 {
+		return nil, nil
+	}
+	auditTarget := &secretAuditTarget{
+		TargetPath: targetPath,
+		SourcePath: sourcePath,
+		Calls:      make([]secretAuditCall, 0, len(templateCalls)),
+	}
+	for _, templateCall := range templateCalls {
+		auditCall := secretAuditCall{
+			Func: templateCall.Func,
+			Args: templateCall.Args,
+			Text: templateCall.Text,
+		}
+		if templateCall.Template != sourcePath {
+			auditCall.Template = templateCall.Template
+		}
+		auditTarget.Calls = append(auditTarget.Calls, auditCall)
+	}
+	return auditTarget, nil
+}
+
+// isTemplateEntry returns whether entry's source is a template that can be
+// audited. Encrypted files are not audited as they cannot be read without
+// decrypting them.
+func isTemplateEntry(entry chezmoi.Entry) bool {
+	switch entry := entry.(type) {
+	case *chezmoi.File:
+		return entry.Template && !entry.Encrypted
+	case *chezmoi.Script:
+		return entry.Template
+	case *chezmoi.Symlink:
+		return entry.Template
+	default:
+		return false
+	}
+}
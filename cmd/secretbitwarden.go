@@ -23,11 +23,21 @@ type bitwardenCmdConfig struct {
 	Command string
 }
 
-var bitwardenCache = make(map[string]interface{})
+type bitwardenAttachmentCacheKey struct {
+	name string
+	item string
+}
+
+var (
+	bitwardenCache           = make(map[string]interface{})
+	bitwardenAttachmentCache = make(map[bitwardenAttachmentCacheKey]string)
+)
 
 func init() {
 	config.Bitwarden.Command = "bw"
-	config.addTemplateFunc("bitwarden", config.bitwardenFunc)
+	config.addSecretTemplateFunc("bitwarden", config.bitwardenFunc)
+	config.addSecretTemplateFunc("bitwardenAttachment", config.bitwardenAttachmentFunc)
+	config.addSecretTemplateFunc("bitwardenFields", config.bitwardenFieldsFunc)
 
 	secretCmd.AddCommand(bitwardenCmd)
 }
@@ -36,24 +46,81 @@ func (c *Config) runBitwardenCmd(cmd *cobra.Command, args []string) error {
 	return c.run("", c.Bitwarden.Command, args...)
 }
 
+func (c *Config) bitwardenOutput(args []string) ([]byte, error) {
+	name := c.Bitwarden.Command
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := c.secretCmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output)
+	}
+	return output, nil
+}
+
 func (c *Config) bitwardenFunc(args ...string) interface{} {
 	key := strings.Join(args, "\x00")
 	if data, ok := bitwardenCache[key]; ok {
 		return data
 	}
-	name := c.Bitwarden.Command
 	args = append([]string{"get"}, args...)
-	cmd := exec.Command(name, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.bitwardenOutput(args)
 	if err != nil {
-		panic(fmt.Errorf("bitwarden: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		panic(fmt.Errorf("bitwarden: %w", err))
 	}
 	var data interface{}
 	if err := json.Unmarshal(output, &data); err != nil {
-		panic(fmt.Errorf("bitwarden: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		panic(fmt.Errorf("bitwarden: %s %s: %w\n%s", c.Bitwarden.Command, chezmoi.ShellQuoteArgs(args), err, output))
 	}
 	bitwardenCache[key] = data
 	return data
 }
+
+// bitwardenAttachmentFunc returns the contents of the attachment name of item,
+// which may be an item ID or any other search term accepted by bw get item.
+func (c *Config) bitwardenAttachmentFunc(name, item string) string {
+	key := bitwardenAttachmentCacheKey{
+		name: name,
+		item: item,
+	}
+	if data, ok := bitwardenAttachmentCache[key]; ok {
+		return data
+	}
+	data, ok := c.bitwardenFunc("item", item).(map[string]interface{})
+	if !ok {
+		panic(fmt.Errorf("bitwardenAttachment: %s: not an item", item))
+	}
+	itemID, ok := data["id"].(string)
+	if !ok {
+		panic(fmt.Errorf("bitwardenAttachment: %s: no item ID", item))
+	}
+	output, err := c.bitwardenOutput([]string{"get", "attachment", name, "--itemid", itemID, "--raw"})
+	if err != nil {
+		panic(fmt.Errorf("bitwardenAttachment: %w", err))
+	}
+	bitwardenAttachmentCache[key] = string(output)
+	return string(output)
+}
+
+// bitwardenFieldsFunc returns the custom fields of the item identified by args
+// as a map keyed by field name.
+func (c *Config) bitwardenFieldsFunc(args ...string) map[string]interface{} {
+	data, ok := c.bitwardenFunc(append([]string{"item"}, args...)...).(map[string]interface{})
+	if !ok {
+		panic(fmt.Errorf("bitwardenFields: %s: not an item", chezmoi.ShellQuoteArgs(args)))
+	}
+	result := make(map[string]interface{})
+	rawFields, _ := data["fields"].([]interface{})
+	for _, rawField := range rawFields {
+		field, ok := rawField.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := field["name"].(string)
+		if !ok {
+			continue
+		}
+		result[name] = field
+	}
+	return result
+}
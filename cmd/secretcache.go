@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	keyring "github.com/zalando/go-keyring"
+	"golang.org/x/crypto/nacl/secretbox"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// The secret cache's encryption key is stored in the keyring so that it is
+// never stored alongside the entries that it protects.
+const (
+	secretCacheKeyringService = "chezmoi"
+	secretCacheKeyringUser    = "secretCache"
+)
+
+const secretCacheNonceSize = 24
+
+var secretCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Args:  cobra.NoArgs,
+	Short: "Interact with the secret cache",
+}
+
+var secretCacheFlushCmd = &cobra.Command{
+	Use:     "flush",
+	Args:    cobra.NoArgs,
+	Short:   "Remove all entries from the secret cache",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runSecretCacheFlushCmd,
+}
+
+type secretCacheConfig struct {
+	TTL time.Duration
+}
+
+// A secretCacheEntry is a cached secret.
+type secretCacheEntry struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Value     []byte    `json:"value"`
+}
+
+var secretCacheEncryptionKey *[32]byte
+
+func init() {
+	secretCmd.AddCommand(secretCacheCmd)
+	secretCacheCmd.AddCommand(secretCacheFlushCmd)
+}
+
+func (c *Config) runSecretCacheFlushCmd(cmd *cobra.Command, args []string) error {
+	persistentState, err := c.getPersistentState(nil)
+	if err != nil {
+		return err
+	}
+	defer persistentState.Close()
+
+	var keys [][]byte
+	if err := persistentState.ForEach(c.secretCacheBucket, func(k, v []byte) error {
+		keys = append(keys, k)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := persistentState.Delete(c.secretCacheBucket, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secretCmdOutput returns the output of cmd, using the secret cache if it is
+// enabled.
+func (c *Config) secretCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	return c.secretCacheOutput(cmd.Args, func() ([]byte, error) {
+		return c.mutator.IdempotentCmdOutput(cmd)
+	})
+}
+
+// secretCacheOutput returns the value cached for key if the secret cache is
+// enabled, otherwise it calls f and caches its output. f is not called at all
+// on a cache hit, so it can prompt the user for passwords.
+func (c *Config) secretCacheOutput(key []string, f func() ([]byte, error)) ([]byte, error) {
+	if output, ok, err := c.secretCacheGet(key); err != nil {
+		return nil, err
+	} else if ok {
+		return output, nil
+	}
+	output, err := f()
+	if err != nil {
+		return output, err
+	}
+	if err := c.secretCacheSet(key, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// secretCacheGet returns the value cached for key and whether it was found.
+// Entries that have expired or that cannot be decrypted are treated as
+// missing.
+func (c *Config) secretCacheGet(key []string) ([]byte, bool, error) {
+	if c.SecretCache.TTL <= 0 {
+		return nil, false, nil
+	}
+	persistentState, _, err := c.getSecretCachePersistentState()
+	if err != nil {
+		return nil, false, err
+	}
+	encryptionKey, err := getSecretCacheEncryptionKey()
+	if err != nil {
+		return nil, false, err
+	}
+	data, ok := c.secretCachePending[string(secretCacheKey(key))]
+	if !ok {
+		data, err = persistentState.Get(c.secretCacheBucket, secretCacheKey(key))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if len(data) < secretCacheNonceSize {
+		return nil, false, nil
+	}
+	var nonce [secretCacheNonceSize]byte
+	copy(nonce[:], data[:secretCacheNonceSize])
+	plaintext, ok := secretbox.Open(nil, data[secretCacheNonceSize:], &nonce, encryptionKey)
+	if !ok {
+		return nil, false, nil
+	}
+	var entry secretCacheEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// secretCacheSet caches value for key. If the persistent state is read-only,
+// for example when running chezmoi diff, then the value is written by
+// flushSecretCache when the command finishes.
+func (c *Config) secretCacheSet(key []string, value []byte) error {
+	if c.SecretCache.TTL <= 0 {
+		return nil
+	}
+	persistentState, readOnly, err := c.getSecretCachePersistentState()
+	if err != nil {
+		return err
+	}
+	encryptionKey, err := getSecretCacheEncryptionKey()
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(&secretCacheEntry{
+		ExpiresAt: time.Now().Add(c.SecretCache.TTL),
+		Value:     value,
+	})
+	if err != nil {
+		return err
+	}
+	var nonce [secretCacheNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	data := secretbox.Seal(nonce[:], plaintext, &nonce, encryptionKey)
+	if readOnly {
+		if c.secretCachePending == nil {
+			c.secretCachePending = make(map[string][]byte)
+		}
+		c.secretCachePending[string(secretCacheKey(key))] = data
+		return nil
+	}
+	return persistentState.Set(c.secretCacheBucket, secretCacheKey(key), data)
+}
+
+// flushSecretCache writes the secret cache entries that could not be written
+// while the persistent state was read-only. The persistent state is opened
+// read-write even if --dry-run is set, as the secret cache does not affect the
+// destination directory.
+func (c *Config) flushSecretCache() error {
+	if len(c.secretCachePending) == 0 {
+		return nil
+	}
+	if c.persistentState != nil {
+		if err := c.persistentState.Close(); err != nil {
+			return err
+		}
+		c.persistentState = nil
+	}
+	persistentState, err := chezmoi.NewBoltPersistentState(c.fs, c.getPersistentStateFile(), os.FileMode(c.Umask), nil)
+	if err != nil {
+		return err
+	}
+	defer persistentState.Close()
+	for key, data := range c.secretCachePending {
+		if err := persistentState.Set(c.secretCacheBucket, []byte(key), data); err != nil {
+			return err
+		}
+	}
+	c.secretCachePending = nil
+	return nil
+}
+
+// getSecretCachePersistentState returns the persistent state used by the
+// secret cache and whether it is read-only, opening it if no command has
+// already done so.
+func (c *Config) getSecretCachePersistentState() (chezmoi.PersistentState, bool, error) {
+	if c.persistentState == nil {
+		if _, err := c.getPersistentState(nil); err != nil {
+			return nil, false, err
+		}
+	}
+	return c.persistentState, c.persistentStateReadOnly, nil
+}
+
+// getSecretCacheEncryptionKey returns the secret cache's encryption key,
+// generating and storing a new one in the keyring if needed.
+func getSecretCacheEncryptionKey() (*[32]byte, error) {
+	if secretCacheEncryptionKey != nil {
+		return secretCacheEncryptionKey, nil
+	}
+	var encryptionKey [32]byte
+	encodedKey, err := keyring.Get(secretCacheKeyringService, secretCacheKeyringUser)
+	switch {
+	case err == nil:
+		keyData, err := hex.DecodeString(encodedKey)
+		if err != nil || len(keyData) != len(encryptionKey) {
+			return nil, fmt.Errorf("secret cache: keyring %q %q: invalid key", secretCacheKeyringService, secretCacheKeyringUser)
+		}
+		copy(encryptionKey[:], keyData)
+	case errors.Is(err, keyring.ErrNotFound):
+		if _, err := rand.Read(encryptionKey[:]); err != nil {
+			return nil, err
+		}
+		if err := keyring.Set(secretCacheKeyringService, secretCacheKeyringUser, hex.EncodeToString(encryptionKey[:])); err != nil {
+			return nil, fmt.Errorf("secret cache: keyring %q %q: %w", secretCacheKeyringService, secretCacheKeyringUser, err)
+		}
+	default:
+		return nil, fmt.Errorf("secret cache: keyring %q %q: %w", secretCacheKeyringService, secretCacheKeyringUser, err)
+	}
+	secretCacheEncryptionKey = &encryptionKey
+	return secretCacheEncryptionKey, nil
+}
+
+// secretCacheKey returns the persistent state key for key. Keys are hashed so
+// that the names of secrets are not stored in plaintext.
+func secretCacheKey(key []string) []byte {
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	return []byte(hex.EncodeToString(sum[:]))
+}
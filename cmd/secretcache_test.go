@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+	keyring "github.com/zalando/go-keyring"
+)
+
+func TestSecretCache(t *testing.T) {
+	keyring.MockInit()
+
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.config/chezmoi": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	key := []string{"secret", "id"}
+	value := []byte("value")
+
+	c := newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Hour,
+	}))
+	_, ok, err := c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, c.secretCacheSet(key, value))
+	require.NoError(t, c.persistentState.Close())
+
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.config/chezmoi/chezmoistate.boltdb",
+			vfst.TestModeIsRegular,
+			vfst.TestModePerm(0o600),
+		),
+	)
+
+	c = newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Hour,
+	}))
+	actualValue, ok, err := c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, value, actualValue)
+	require.NoError(t, c.runSecretCacheFlushCmd(nil, nil))
+
+	c = newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Hour,
+	}))
+	_, ok, err = c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, c.persistentState.Close())
+}
+
+func TestSecretCacheExpiry(t *testing.T) {
+	keyring.MockInit()
+
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.config/chezmoi": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	key := []string{"secret", "id"}
+
+	c := newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Nanosecond,
+	}))
+	require.NoError(t, c.secretCacheSet(key, []byte("value")))
+	time.Sleep(time.Millisecond)
+	_, ok, err := c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, c.persistentState.Close())
+}
+
+func TestSecretCacheReadOnly(t *testing.T) {
+	keyring.MockInit()
+
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.config/chezmoi": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	key := []string{"secret", "id"}
+	value := []byte("value")
+
+	c := newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Hour,
+	}))
+	c.DryRun = true
+	require.NoError(t, c.secretCacheSet(key, value))
+	actualValue, ok, err := c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, value, actualValue)
+	require.NoError(t, c.persistentPostRunRootE(nil, nil))
+	assert.Nil(t, c.persistentState)
+
+	c = newTestConfig(fs, withSecretCacheConfig(secretCacheConfig{
+		TTL: time.Hour,
+	}))
+	actualValue, ok, err = c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, value, actualValue)
+	require.NoError(t, c.persistentPostRunRootE(nil, nil))
+}
+
+func TestSecretCacheDisabled(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.config/chezmoi": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	key := []string{"secret", "id"}
+
+	c := newTestConfig(fs)
+	require.NoError(t, c.secretCacheSet(key, []byte("value")))
+	_, ok, err := c.secretCacheGet(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, c.persistentState)
+}
@@ -30,8 +30,8 @@ var (
 )
 
 func init() {
-	config.addTemplateFunc("secret", config.secretFunc)
-	config.addTemplateFunc("secretJSON", config.secretJSONFunc)
+	config.addSecretTemplateFunc("secret", config.secretFunc)
+	config.addSecretTemplateFunc("secretJSON", config.secretJSONFunc)
 
 	secretCmd.AddCommand(genericSecretCmd)
 }
@@ -49,7 +49,7 @@ func (c *Config) secretFunc(args ...string) string {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		panic(fmt.Errorf("secret: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
 	}
@@ -67,7 +67,7 @@ func (c *Config) secretJSONFunc(args ...string) interface{} {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		panic(fmt.Errorf("secretJSON: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
 	}
@@ -27,7 +27,7 @@ func init() {
 	secretCmd.AddCommand(gopassCmd)
 
 	config.Gopass.Command = "gopass"
-	config.addTemplateFunc("gopass", config.gopassFunc)
+	config.addSecretTemplateFunc("gopass", config.gopassFunc)
 }
 
 func (c *Config) runSecretGopassCmd(cmd *cobra.Command, args []string) error {
@@ -41,7 +41,7 @@ func (c *Config) gopassFunc(id string) string {
 	name := c.Gopass.Command
 	args := []string{"show", id}
 	cmd := exec.Command(name, args...)
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		panic(fmt.Errorf("gopass: %s %s: %w", name, chezmoi.ShellQuoteArgs(args), err))
 	}
@@ -28,6 +28,8 @@ type keePassXCCmdConfig struct {
 	Command  string
 	Database string
 	Args     []string
+	Mode     string
+	KeyFile  string
 }
 
 type keePassXCAttributeCacheKey struct {
@@ -35,10 +37,17 @@ type keePassXCAttributeCacheKey struct {
 	attribute string
 }
 
+// KeePassXC modes.
+const (
+	keePassXCModeBuiltin = "builtin"
+	keePassXCModeCLI     = "cli"
+)
+
 var (
 	keePassXCVersion                     *semver.Version
 	keePassXCCache                       = make(map[string]map[string]string)
 	keePassXCAttributeCache              = make(map[keePassXCAttributeCacheKey]string)
+	keePassXCAttachmentCache             = make(map[keePassXCAttributeCacheKey]string)
 	keePassXCPairRegexp                  = regexp.MustCompile(`^([^:]+): (.*)$`)
 	keePassXCPassword                    string
 	keePassXCNeedShowProtectedArgVersion = semver.Version{Major: 2, Minor: 5, Patch: 1}
@@ -46,8 +55,9 @@ var (
 
 func init() {
 	config.KeePassXC.Command = "keepassxc-cli"
-	config.addTemplateFunc("keepassxc", config.keePassXCFunc)
-	config.addTemplateFunc("keepassxcAttribute", config.keePassXCAttributeFunc)
+	config.addSecretTemplateFunc("keepassxc", config.keePassXCFunc)
+	config.addSecretTemplateFunc("keepassxcAttachment", config.keePassXCAttachmentFunc)
+	config.addSecretTemplateFunc("keepassxcAttribute", config.keePassXCAttributeFunc)
 
 	secretCmd.AddCommand(keePassXCCmd)
 }
@@ -81,6 +91,14 @@ func (c *Config) keePassXCFunc(entry string) map[string]string {
 	if c.KeePassXC.Database == "" {
 		panic(errors.New("keepassxc: keepassxc.database not set"))
 	}
+	if c.useKeePassXCBuiltin() {
+		data, err := c.keePassXCBuiltinEntryValues(entry)
+		if err != nil {
+			panic(fmt.Errorf("keepassxc: %w", err))
+		}
+		keePassXCCache[entry] = data
+		return data
+	}
 	name := c.KeePassXC.Command
 	args := []string{"show"}
 	if c.getKeePassXCVersion().Compare(keePassXCNeedShowProtectedArgVersion) >= 0 {
@@ -111,6 +129,19 @@ func (c *Config) keePassXCAttributeFunc(entry, attribute string) string {
 	if c.KeePassXC.Database == "" {
 		panic(errors.New("keepassxc: keepassxc.database not set"))
 	}
+	if c.useKeePassXCBuiltin() {
+		data, err := c.keePassXCBuiltinEntryValues(entry)
+		if err != nil {
+			panic(fmt.Errorf("keepassxcAttribute: %w", err))
+		}
+		value, ok := data[attribute]
+		if !ok {
+			panic(fmt.Errorf("keepassxcAttribute: %s: %s: attribute not found", entry, attribute))
+		}
+		outputStr := strings.TrimSpace(value)
+		keePassXCAttributeCache[key] = outputStr
+		return outputStr
+	}
 	name := c.KeePassXC.Command
 	args := []string{"show", "--attributes", attribute, "--quiet"}
 	if c.getKeePassXCVersion().Compare(keePassXCNeedShowProtectedArgVersion) >= 0 {
@@ -127,20 +158,66 @@ func (c *Config) keePassXCAttributeFunc(entry, attribute string) string {
 	return outputStr
 }
 
-func (c *Config) runKeePassXCCLICommand(name string, args []string) ([]byte, error) {
+// keePassXCAttachmentFunc returns the contents of the attachment name of entry.
+func (c *Config) keePassXCAttachmentFunc(entry, name string) string {
+	key := keePassXCAttributeCacheKey{
+		entry:     entry,
+		attribute: name,
+	}
+	if data, ok := keePassXCAttachmentCache[key]; ok {
+		return data
+	}
+	if c.KeePassXC.Database == "" {
+		panic(errors.New("keepassxcAttachment: keepassxc.database not set"))
+	}
+	var data []byte
+	if c.useKeePassXCBuiltin() {
+		var err error
+		data, err = c.keePassXCBuiltinAttachment(entry, name)
+		if err != nil {
+			panic(fmt.Errorf("keepassxcAttachment: %w", err))
+		}
+	} else {
+		command := c.KeePassXC.Command
+		args := []string{"attachment-export", "--stdout", "--quiet"}
+		args = append(args, c.KeePassXC.Args...)
+		args = append(args, c.KeePassXC.Database, entry, name)
+		var err error
+		data, err = c.runKeePassXCCLICommand(command, args)
+		if err != nil {
+			panic(fmt.Errorf("keepassxcAttachment: %s %s: %w", command, chezmoi.ShellQuoteArgs(args), err))
+		}
+	}
+	keePassXCAttachmentCache[key] = string(data)
+	return string(data)
+}
+
+// getKeePassXCPassword returns the database password, prompting for it the
+// first time it is needed.
+func (c *Config) getKeePassXCPassword() (string, error) {
 	if keePassXCPassword == "" {
 		fmt.Printf("Insert password to unlock %s: ", c.KeePassXC.Database)
 		password, err := terminal.ReadPassword(int(os.Stdout.Fd()))
 		fmt.Println()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		keePassXCPassword = string(password)
 	}
-	cmd := exec.Command(name, args...)
-	cmd.Stdin = bytes.NewBufferString(keePassXCPassword + "\n")
-	cmd.Stderr = c.Stderr
-	return c.mutator.IdempotentCmdOutput(cmd)
+	return keePassXCPassword, nil
+}
+
+func (c *Config) runKeePassXCCLICommand(name string, args []string) ([]byte, error) {
+	return c.secretCacheOutput(append([]string{name}, args...), func() ([]byte, error) {
+		password, err := c.getKeePassXCPassword()
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = bytes.NewBufferString(password + "\n")
+		cmd.Stderr = c.Stderr
+		return c.mutator.IdempotentCmdOutput(cmd)
+	})
 }
 
 func parseKeyPassXCOutput(output []byte) (map[string]string, error) {
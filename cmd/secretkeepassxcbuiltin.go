@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// keePassXCBuiltinDatabase is the unlocked database, read the first time that
+// it is needed.
+var keePassXCBuiltinDatabase *gokeepasslib.Database
+
+// useKeePassXCBuiltin returns whether chezmoi should read KeePassXC databases
+// itself, rather than using keepassxc-cli.
+func (c *Config) useKeePassXCBuiltin() bool {
+	switch c.KeePassXC.Mode {
+	case "", keePassXCModeCLI:
+		return false
+	case keePassXCModeBuiltin:
+		return true
+	default:
+		panic(fmt.Errorf("keepassxc: %s: unknown keepassxc.mode", c.KeePassXC.Mode))
+	}
+}
+
+func (c *Config) keePassXCBuiltinAttachment(entryPath, name string) ([]byte, error) {
+	db, err := c.getKeePassXCBuiltinDatabase()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := findKeePassXCBuiltinEntry(db, entryPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, binaryReference := range entry.Binaries {
+		if binaryReference.Name != name {
+			continue
+		}
+		binary := db.FindBinary(binaryReference.Value.ID)
+		if binary == nil {
+			return nil, fmt.Errorf("%s: %s: attachment data not found", entryPath, name)
+		}
+		return binary.GetContentBytes()
+	}
+	return nil, fmt.Errorf("%s: %s: attachment not found", entryPath, name)
+}
+
+// keePassXCBuiltinEntryValues returns the values of the entry at entryPath,
+// keyed by the same names as keepassxc-cli uses, for example UserName and
+// Password.
+func (c *Config) keePassXCBuiltinEntryValues(entryPath string) (map[string]string, error) {
+	db, err := c.getKeePassXCBuiltinDatabase()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := findKeePassXCBuiltinEntry(db, entryPath)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(entry.Values))
+	for _, value := range entry.Values {
+		values[value.Key] = value.Value.Content
+	}
+	return values, nil
+}
+
+func (c *Config) getKeePassXCBuiltinDatabase() (*gokeepasslib.Database, error) {
+	if keePassXCBuiltinDatabase != nil {
+		return keePassXCBuiltinDatabase, nil
+	}
+	data, err := c.fs.ReadFile(c.KeePassXC.Database)
+	if err != nil {
+		return nil, err
+	}
+	password, err := c.getKeePassXCPassword()
+	if err != nil {
+		return nil, err
+	}
+	var credentials *gokeepasslib.DBCredentials
+	if c.KeePassXC.KeyFile == "" {
+		credentials = gokeepasslib.NewPasswordCredentials(password)
+	} else {
+		keyData, err := c.fs.ReadFile(c.KeePassXC.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if password == "" {
+			credentials, err = gokeepasslib.NewKeyDataCredentials(keyData)
+		} else {
+			credentials, err = gokeepasslib.NewPasswordAndKeyDataCredentials(password, keyData)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.KeePassXC.KeyFile, err)
+		}
+	}
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = credentials
+	if err := gokeepasslib.NewDecoder(bytes.NewReader(data)).Decode(db); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.KeePassXC.Database, err)
+	}
+	if err := db.UnlockProtectedEntries(); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.KeePassXC.Database, err)
+	}
+	keePassXCBuiltinDatabase = db
+	return keePassXCBuiltinDatabase, nil
+}
+
+// findKeePassXCBuiltinEntry returns the entry at entryPath in db. Like
+// keepassxc-cli, paths are relative to the root group, for example
+// Group/Title, and the leading slash is optional.
+func findKeePassXCBuiltinEntry(db *gokeepasslib.Database, entryPath string) (*gokeepasslib.Entry, error) {
+	if db.Content == nil || db.Content.Root == nil {
+		return nil, fmt.Errorf("%s: entry not found", entryPath)
+	}
+	path := "/" + strings.TrimPrefix(entryPath, "/")
+	for i := range db.Content.Root.Groups {
+		if entry := findKeePassXCBuiltinEntryInGroup(&db.Content.Root.Groups[i], "", path); entry != nil {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: entry not found", entryPath)
+}
+
+func findKeePassXCBuiltinEntryInGroup(group *gokeepasslib.Group, prefix, path string) *gokeepasslib.Entry {
+	for i := range group.Entries {
+		if prefix+"/"+group.Entries[i].GetTitle() == path {
+			return &group.Entries[i]
+		}
+	}
+	for i := range group.Groups {
+		subgroup := &group.Groups[i]
+		if entry := findKeePassXCBuiltinEntryInGroup(subgroup, prefix+"/"+subgroup.Name, path); entry != nil {
+			return entry
+		}
+	}
+	return nil
+}
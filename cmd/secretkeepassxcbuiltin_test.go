@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+func TestKeePassXCBuiltin(t *testing.T) {
+	db := &gokeepasslib.Database{
+		Header:      gokeepasslib.NewHeader(),
+		Credentials: gokeepasslib.NewPasswordCredentials("password"),
+		Content: &gokeepasslib.DBContent{
+			Meta: gokeepasslib.NewMetaData(),
+		},
+	}
+
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "example.com"}},
+		gokeepasslib.ValueData{Key: "UserName", Value: gokeepasslib.V{Content: "examplelogin"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "examplepassword", Protected: wrappers.NewBoolWrapper(true)}},
+	)
+	binary := db.AddBinary([]byte("attachment contents"))
+	entry.Binaries = append(entry.Binaries, binary.CreateReference("id_rsa"))
+
+	subgroup := gokeepasslib.NewGroup()
+	subgroup.Name = "Group"
+	subgroup.Entries = append(subgroup.Entries, entry)
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Groups = append(root.Groups, subgroup)
+	db.Content.Root = &gokeepasslib.RootData{
+		Groups: []gokeepasslib.Group{root},
+	}
+
+	require.NoError(t, db.LockProtectedEntries())
+	b := &bytes.Buffer{}
+	require.NoError(t, gokeepasslib.NewEncoder(b).Encode(db))
+
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/secrets.kdbx": b.Bytes(),
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	defer func() {
+		keePassXCPassword = ""
+		keePassXCBuiltinDatabase = nil
+	}()
+	keePassXCPassword = "password"
+
+	c := newTestConfig(fs)
+	c.KeePassXC.Database = "/home/user/secrets.kdbx"
+	c.KeePassXC.Mode = keePassXCModeBuiltin
+
+	values, err := c.keePassXCBuiltinEntryValues("/Group/example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "examplelogin", values["UserName"])
+	assert.Equal(t, "examplepassword", values["Password"])
+
+	values, err = c.keePassXCBuiltinEntryValues("Group/example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "examplelogin", values["UserName"])
+
+	_, err = c.keePassXCBuiltinEntryValues("example.com")
+	assert.Error(t, err)
+
+	attachment, err := c.keePassXCBuiltinAttachment("Group/example.com", "id_rsa")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("attachment contents"), attachment)
+
+	_, err = c.keePassXCBuiltinAttachment("Group/example.com", "missing")
+	assert.Error(t, err)
+}
@@ -14,9 +14,10 @@ var keyringCmd = &cobra.Command{
 }
 
 type keyringCmdConfig struct {
-	service  string
-	user     string
-	password string
+	service      string
+	user         string
+	password     string
+	passwordFile string
 }
 
 type keyringKey struct {
@@ -29,15 +30,19 @@ var keyringCache = make(map[keyringKey]string)
 func init() {
 	secretCmd.AddCommand(keyringCmd)
 
-	persistentFlags := keyringCmd.PersistentFlags()
+	config.addSecretTemplateFunc("keyring", config.keyringFunc)
+}
+
+// addKeyringServiceAndUserFlags adds the required --service and --user flags to
+// cmd.
+func addKeyringServiceAndUserFlags(cmd *cobra.Command) {
+	persistentFlags := cmd.PersistentFlags()
 
 	persistentFlags.StringVar(&config.keyring.service, "service", "", "service")
-	panicOnError(keyringCmd.MarkPersistentFlagRequired("service"))
+	panicOnError(cmd.MarkPersistentFlagRequired("service"))
 
 	persistentFlags.StringVar(&config.keyring.user, "user", "", "user")
-	panicOnError(keyringCmd.MarkPersistentFlagRequired("user"))
-
-	config.addTemplateFunc("keyring", config.keyringFunc)
+	panicOnError(cmd.MarkPersistentFlagRequired("user"))
 }
 
 func (*Config) keyringFunc(service, user string) string {
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+	keyring "github.com/zalando/go-keyring"
+)
+
+func TestKeyringCmds(t *testing.T) {
+	keyring.MockInit()
+
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/password": "filepassword\n",
+		"/home/user/import.json": `[` +
+			`{"service":"service1","user":"user1","password":"password1"},` +
+			`{"service":"service2","user":"user2","password":"password2"}` +
+			`]`,
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	c := newTestConfig(fs)
+	c.keyring = keyringCmdConfig{
+		service:      "service",
+		user:         "user",
+		passwordFile: "/home/user/password",
+	}
+	require.NoError(t, c.runKeyringSetCmd(nil, nil))
+	assert.Equal(t, "filepassword", c.keyringFunc("service", "user"))
+
+	c = newTestConfig(fs, withStdin(bytes.NewBufferString("stdinpassword\n")))
+	c.keyring = keyringCmdConfig{
+		service:      "service",
+		user:         "user",
+		passwordFile: "-",
+	}
+	require.NoError(t, c.runKeyringSetCmd(nil, nil))
+	password, err := keyring.Get("service", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "stdinpassword", password)
+
+	require.NoError(t, c.runKeyringDeleteCmd(nil, nil))
+	_, err = keyring.Get("service", "user")
+	assert.Equal(t, keyring.ErrNotFound, err)
+
+	require.NoError(t, c.runKeyringImportCmd(nil, []string{"/home/user/import.json"}))
+	for service, user := range map[string]string{
+		"service1": "user1",
+		"service2": "user2",
+	} {
+		password, err := keyring.Get(service, user)
+		require.NoError(t, err)
+		assert.Equal(t, "password"+service[len("service"):], password)
+	}
+}
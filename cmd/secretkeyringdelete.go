@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	keyring "github.com/zalando/go-keyring"
+)
+
+var keyringDeleteCmd = &cobra.Command{
+	Use:     "delete",
+	Args:    cobra.NoArgs,
+	Short:   "Delete a password from keyring",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runKeyringDeleteCmd,
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringDeleteCmd)
+
+	addKeyringServiceAndUserFlags(keyringDeleteCmd)
+}
+
+func (c *Config) runKeyringDeleteCmd(cmd *cobra.Command, args []string) error {
+	return keyring.Delete(c.keyring.service, c.keyring.user)
+}
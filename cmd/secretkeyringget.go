@@ -17,6 +17,8 @@ var keyringGetCmd = &cobra.Command{
 
 func init() {
 	keyringCmd.AddCommand(keyringGetCmd)
+
+	addKeyringServiceAndUserFlags(keyringGetCmd)
 }
 
 func (c *Config) runKeyringGetCmd(cmd *cobra.Command, args []string) error {
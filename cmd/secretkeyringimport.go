@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	keyring "github.com/zalando/go-keyring"
+)
+
+var keyringImportCmd = &cobra.Command{
+	Use:     "import [filename]",
+	Args:    cobra.MaximumNArgs(1),
+	Short:   "Import passwords into keyring",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runKeyringImportCmd,
+}
+
+// A keyringImportEntry is a password to be imported into the keyring.
+type keyringImportEntry struct {
+	Service  string `json:"service"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringImportCmd)
+
+	panicOnError(keyringImportCmd.MarkZshCompPositionalArgumentFile(1, "*.json"))
+}
+
+func (c *Config) runKeyringImportCmd(cmd *cobra.Command, args []string) error {
+	filename := "-"
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	data, err := c.readFileOrStdin(filename)
+	if err != nil {
+		return err
+	}
+	var entries []keyringImportEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	// Validate all entries before setting any so that a malformed file does
+	// not leave the keyring partially imported.
+	for i, entry := range entries {
+		if entry.Service == "" || entry.User == "" {
+			return fmt.Errorf("%s: entry %d: service and user must be set", filename, i+1)
+		}
+	}
+	for _, entry := range entries {
+		if err := keyring.Set(entry.Service, entry.User, entry.Password); err != nil {
+			return fmt.Errorf("%s %s: %w", entry.Service, entry.User, err)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	keyring "github.com/zalando/go-keyring"
@@ -20,13 +21,26 @@ var keyringSetCmd = &cobra.Command{
 func init() {
 	keyringCmd.AddCommand(keyringSetCmd)
 
+	addKeyringServiceAndUserFlags(keyringSetCmd)
+
 	persistentFlags := keyringSetCmd.PersistentFlags()
 	persistentFlags.StringVar(&config.keyring.password, "password", "", "password")
+	persistentFlags.StringVar(&config.keyring.passwordFile, "password-file", "", "read password from file, or - for stdin")
 }
 
 func (c *Config) runKeyringSetCmd(cmd *cobra.Command, args []string) error {
 	passwordString := c.keyring.password
-	if passwordString == "" {
+	switch {
+	case passwordString != "" && c.keyring.passwordFile != "":
+		return fmt.Errorf("--password and --password-file are mutually exclusive")
+	case c.keyring.passwordFile != "":
+		data, err := c.readFileOrStdin(c.keyring.passwordFile)
+		if err != nil {
+			return err
+		}
+		// Strip a single trailing newline, as added by most editors and echo.
+		passwordString = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	case passwordString == "":
 		fmt.Print("Password: ")
 		password, err := terminal.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
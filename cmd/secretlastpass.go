@@ -42,8 +42,8 @@ var lastPassCache = make(map[string][]map[string]interface{})
 
 func init() {
 	config.Lastpass.Command = "lpass"
-	config.addTemplateFunc("lastpass", config.lastpassFunc)
-	config.addTemplateFunc("lastpassRaw", config.lastpassRawFunc)
+	config.addSecretTemplateFunc("lastpass", config.lastpassFunc)
+	config.addSecretTemplateFunc("lastpassRaw", config.lastpassRawFunc)
 
 	secretCmd.AddCommand(lastpassCmd)
 }
@@ -57,7 +57,7 @@ func (c *Config) lastpassOutput(args ...string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, err
 	}
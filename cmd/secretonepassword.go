@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -22,15 +23,23 @@ type onepasswordCmdConfig struct {
 	Command string
 }
 
+type onepasswordCacheKey struct {
+	item    string
+	vault   string
+	account string
+}
+
 var (
-	onepasswordCache         = make(map[string]interface{})
-	onepasswordDocumentCache = make(map[string]string)
+	onepasswordCache         = make(map[onepasswordCacheKey]interface{})
+	onepasswordDocumentCache = make(map[onepasswordCacheKey]string)
+	onepasswordSessionTokens = make(map[string]string)
 )
 
 func init() {
 	config.Onepassword.Command = "op"
-	config.addTemplateFunc("onepassword", config.onepasswordFunc)
-	config.addTemplateFunc("onepasswordDocument", config.onepasswordDocumentFunc)
+	config.addSecretTemplateFunc("onepassword", config.onepasswordFunc)
+	config.addSecretTemplateFunc("onepasswordDocument", config.onepasswordDocumentFunc)
+	config.addSecretTemplateFunc("onepasswordFields", config.onepasswordFieldsFunc)
 
 	secretCmd.AddCommand(onepasswordCmd)
 }
@@ -39,40 +48,166 @@ func (c *Config) runOnepasswordCmd(cmd *cobra.Command, args []string) error {
 	return c.run("", c.Onepassword.Command, args...)
 }
 
-func (c *Config) onepasswordFunc(item string) interface{} {
-	if data, ok := onepasswordCache[item]; ok {
+func (c *Config) onepasswordFunc(item string, vaultAndAccount ...string) interface{} {
+	key, err := newOnepasswordCacheKey(item, vaultAndAccount)
+	if err != nil {
+		panic(fmt.Errorf("onepassword: %w", err))
+	}
+	if data, ok := onepasswordCache[key]; ok {
 		return data
 	}
-	name := c.Onepassword.Command
-	args := []string{"get", "item", item}
-	cmd := exec.Command(name, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.onepasswordOutput([]string{"get", "item", item}, key)
 	if err != nil {
-		panic(fmt.Errorf("onepassword: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		panic(fmt.Errorf("onepassword: %w", err))
 	}
 	var data interface{}
 	if err := json.Unmarshal(output, &data); err != nil {
-		panic(fmt.Errorf("onepassword: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		panic(fmt.Errorf("onepassword: %s: %w\n%s", item, err, output))
 	}
-	onepasswordCache[item] = data
+	onepasswordCache[key] = data
 	return data
 }
 
-func (c *Config) onepasswordDocumentFunc(item string) interface{} {
-	if output, ok := onepasswordDocumentCache[item]; ok {
+func (c *Config) onepasswordDocumentFunc(item string, vaultAndAccount ...string) interface{} {
+	key, err := newOnepasswordCacheKey(item, vaultAndAccount)
+	if err != nil {
+		panic(fmt.Errorf("onepasswordDocument: %w", err))
+	}
+	if output, ok := onepasswordDocumentCache[key]; ok {
 		return output
 	}
+	output, err := c.onepasswordOutput([]string{"get", "document", item}, key)
+	if err != nil {
+		panic(fmt.Errorf("onepasswordDocument: %w", err))
+	}
+	onepasswordDocumentCache[key] = string(output)
+	return string(output)
+}
+
+// onepasswordFieldsFunc returns the fields of item as a flat map. Fields in the
+// item's details are keyed by designation, or by name if they have no
+// designation, and fields in the item's sections are keyed by label.
+func (c *Config) onepasswordFieldsFunc(item string, vaultAndAccount ...string) map[string]interface{} {
+	data, ok := c.onepasswordFunc(item, vaultAndAccount...).(map[string]interface{})
+	if !ok {
+		panic(fmt.Errorf("onepasswordFields: %s: not an item", item))
+	}
+	details, _ := data["details"].(map[string]interface{})
+	fields := make(map[string]interface{})
+	if password, ok := details["password"]; ok {
+		fields["password"] = password
+	}
+	detailsFields, _ := details["fields"].([]interface{})
+	for _, rawField := range detailsFields {
+		field, ok := rawField.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := field["designation"].(string)
+		if key == "" {
+			key, _ = field["name"].(string)
+		}
+		if key != "" {
+			fields[key] = field["value"]
+		}
+	}
+	sections, _ := details["sections"].([]interface{})
+	for _, rawSection := range sections {
+		section, ok := rawSection.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sectionFields, _ := section["fields"].([]interface{})
+		for _, rawField := range sectionFields {
+			field, ok := rawField.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if label, ok := field["t"].(string); ok && label != "" {
+				fields[label] = field["v"]
+			}
+		}
+	}
+	return fields
+}
+
+// onepasswordOutput runs op with args in the vault and account of key, signing
+// in first if needed.
+func (c *Config) onepasswordOutput(args []string, key onepasswordCacheKey) ([]byte, error) {
 	name := c.Onepassword.Command
-	args := []string{"get", "document", item}
+	if key.vault != "" {
+		args = append(args, "--vault", key.vault)
+	}
+	if key.account != "" {
+		args = append(args, "--account", key.account)
+	}
+	// The session token changes on every sign in, so exclude it from the
+	// secret cache key.
+	return c.secretCacheOutput(append([]string{name}, args...), func() ([]byte, error) {
+		sessionToken, err := c.onepasswordSessionToken(key.account)
+		if err != nil {
+			return nil, err
+		}
+		cmdArgs := args
+		if sessionToken != "" {
+			cmdArgs = append([]string{"--session", sessionToken}, args...)
+		}
+		cmd := exec.Command(name, cmdArgs...)
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+		output, err := c.mutator.IdempotentCmdOutput(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output)
+		}
+		return output, nil
+	})
+}
+
+// onepasswordSessionToken returns a session token for account, signing in with
+// op signin if there is no existing session. It returns an empty string if
+// there is already a session in the environment.
+func (c *Config) onepasswordSessionToken(account string) (string, error) {
+	if sessionToken, ok := onepasswordSessionTokens[account]; ok {
+		return sessionToken, nil
+	}
+	for _, env := range os.Environ() {
+		if account == "" && strings.HasPrefix(env, "OP_SESSION_") ||
+			account != "" && strings.HasPrefix(env, "OP_SESSION_"+account+"=") {
+			return "", nil
+		}
+	}
+	name := c.Onepassword.Command
+	args := []string{"signin"}
+	if account != "" {
+		args = append(args, account)
+	}
+	args = append(args, "--raw")
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	output, err := c.mutator.IdempotentCmdOutput(cmd)
 	if err != nil {
-		panic(fmt.Errorf("onepassword: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		return "", fmt.Errorf("%s %s: %w", name, chezmoi.ShellQuoteArgs(args), err)
 	}
-	onepasswordDocumentCache[item] = string(output)
-	return string(output)
+	sessionToken := strings.TrimSpace(string(output))
+	c.redactor.AddSecret(sessionToken)
+	onepasswordSessionTokens[account] = sessionToken
+	return sessionToken, nil
+}
+
+func newOnepasswordCacheKey(item string, vaultAndAccount []string) (onepasswordCacheKey, error) {
+	key := onepasswordCacheKey{
+		item: item,
+	}
+	switch len(vaultAndAccount) {
+	case 0:
+	case 1:
+		key.vault = vaultAndAccount[0]
+	case 2:
+		key.vault = vaultAndAccount[0]
+		key.account = vaultAndAccount[1]
+	default:
+		return onepasswordCacheKey{}, fmt.Errorf("expected 1, 2, or 3 arguments, got %d", 1+len(vaultAndAccount))
+	}
+	return key, nil
 }
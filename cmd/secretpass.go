@@ -1,9 +1,10 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -21,13 +22,22 @@ type passCmdConfig struct {
 	Command string
 }
 
-var passCache = make(map[string]string)
+var (
+	passCache       = make(map[string]string)
+	passFieldsCache = make(map[string]map[string]string)
+	passOTPCache    = make(map[string]string)
+	passRawCache    = make(map[string]string)
+	passFieldRegexp = regexp.MustCompile(`\A([^:]+):\s+(.*)\z`)
+)
 
 func init() {
 	secretCmd.AddCommand(passCmd)
 
 	config.Pass.Command = "pass"
-	config.addTemplateFunc("pass", config.passFunc)
+	config.addSecretTemplateFunc("pass", config.passFunc)
+	config.addSecretTemplateFunc("passFields", config.passFieldsFunc)
+	config.addSecretTemplateFunc("passOTP", config.passOTPFunc)
+	config.addSecretTemplateFunc("passRaw", config.passRawFunc)
 }
 
 func (c *Config) runSecretPassCmd(cmd *cobra.Command, args []string) error {
@@ -38,19 +48,66 @@ func (c *Config) passFunc(id string) string {
 	if s, ok := passCache[id]; ok {
 		return s
 	}
+	output := c.passRawFunc(id)
+	var password string
+	if index := strings.IndexByte(output, '\n'); index != -1 {
+		password = output[:index]
+	} else {
+		password = output
+	}
+	passCache[id] = password
+	return passCache[id]
+}
+
+// passFieldsFunc returns the key: value lines of the pass entry id as a map,
+// with the password from the first line as the password field.
+func (c *Config) passFieldsFunc(id string) map[string]string {
+	if fields, ok := passFieldsCache[id]; ok {
+		return fields
+	}
+	fields := make(map[string]string)
+	for i, line := range strings.Split(c.passRawFunc(id), "\n") {
+		if i == 0 {
+			continue
+		}
+		if m := passFieldRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			fields[strings.TrimSpace(m[1])] = m[2]
+		}
+	}
+	fields["password"] = c.passFunc(id)
+	passFieldsCache[id] = fields
+	return fields
+}
+
+// passOTPFunc returns the current one time password for id. It bypasses the
+// secret cache as one time passwords are short lived.
+func (c *Config) passOTPFunc(id string) string {
+	if s, ok := passOTPCache[id]; ok {
+		return s
+	}
 	name := c.Pass.Command
-	args := []string{"show", id}
+	args := []string{"otp", id}
 	cmd := exec.Command(name, args...)
 	output, err := c.mutator.IdempotentCmdOutput(cmd)
 	if err != nil {
-		panic(fmt.Errorf("pass: %s %s: %w", name, chezmoi.ShellQuoteArgs(args), err))
+		panic(fmt.Errorf("passOTP: %s %s: %w", name, chezmoi.ShellQuoteArgs(args), err))
 	}
-	var password string
-	if index := bytes.IndexByte(output, '\n'); index != -1 {
-		password = string(output[:index])
-	} else {
-		password = string(output)
+	passOTPCache[id] = strings.TrimSpace(string(output))
+	return passOTPCache[id]
+}
+
+// passRawFunc returns the full output of pass show id.
+func (c *Config) passRawFunc(id string) string {
+	if s, ok := passRawCache[id]; ok {
+		return s
 	}
-	passCache[id] = password
-	return passCache[id]
+	name := c.Pass.Command
+	args := []string{"show", id}
+	cmd := exec.Command(name, args...)
+	output, err := c.secretCmdOutput(cmd)
+	if err != nil {
+		panic(fmt.Errorf("pass: %s %s: %w", name, chezmoi.ShellQuoteArgs(args), err))
+	}
+	passRawCache[id] = string(output)
+	return passRawCache[id]
 }
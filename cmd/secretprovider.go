@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+// secretProviderProtocolVersion is the version of the secret provider protocol
+// spoken by chezmoi.
+const secretProviderProtocolVersion = 1
+
+// Secret provider methods.
+const (
+	secretProviderMethodGet      = "get"
+	secretProviderMethodGetField = "get-field"
+	secretProviderMethodList     = "list"
+)
+
+type secretProviderConfig struct {
+	Command string
+	Args    []string
+}
+
+// A secretProviderRequest is a request sent to a secret provider on its
+// standard input.
+type secretProviderRequest struct {
+	Version int    `json:"version"`
+	Method  string `json:"method"`
+	ID      string `json:"id,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// A secretProviderResponse is a response read from a secret provider's
+// standard output.
+type secretProviderResponse struct {
+	Value *string  `json:"value,omitempty"`
+	IDs   []string `json:"ids,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+var secretProviderCache = make(map[secretProviderRequestKey]*secretProviderResponse)
+
+type secretProviderRequestKey struct {
+	name    string
+	request secretProviderRequest
+}
+
+func init() {
+	config.addSecretTemplateFunc("secretProvider", config.secretProviderFunc)
+	config.addSecretTemplateFunc("secretProviderField", config.secretProviderFieldFunc)
+	// secretProviderList returns IDs, not secrets, so they are not redacted.
+	config.addTemplateFunc("secretProviderList", config.secretProviderListFunc)
+}
+
+// runSecretCmd runs a secret provider configured in the config file, as these
+// cannot be registered as subcommands before the config file is read.
+func (c *Config) runSecretCmd(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return cmd.Help()
+	}
+	name := strings.ToLower(args[0])
+	if _, ok := c.SecretProviders[name]; !ok {
+		return fmt.Errorf("unknown command %q for %q", name, cmd.CommandPath())
+	}
+	args = args[1:]
+	var request secretProviderRequest
+	switch {
+	case len(args) == 2 && args[0] == secretProviderMethodGet:
+		request = secretProviderRequest{
+			Method: secretProviderMethodGet,
+			ID:     args[1],
+		}
+	case len(args) == 3 && args[0] == secretProviderMethodGetField:
+		request = secretProviderRequest{
+			Method: secretProviderMethodGetField,
+			ID:     args[1],
+			Field:  args[2],
+		}
+	case len(args) == 1 && args[0] == secretProviderMethodList:
+		request = secretProviderRequest{
+			Method: secretProviderMethodList,
+		}
+	default:
+		return fmt.Errorf("usage: %s %s get id | get-field id field | list", cmd.CommandPath(), name)
+	}
+	response, err := c.secretProviderDo(name, request)
+	if err != nil {
+		return err
+	}
+	if request.Method == secretProviderMethodList {
+		for _, id := range response.IDs {
+			if _, err := fmt.Fprintln(c.Stdout, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err = fmt.Fprintln(c.Stdout, *response.Value)
+	return err
+}
+
+// secretProviderDo sends request to the secret provider name and returns its
+// response.
+func (c *Config) secretProviderDo(name string, request secretProviderRequest) (*secretProviderResponse, error) {
+	// Secret provider names are case insensitive, as viper lowercases all keys.
+	name = strings.ToLower(name)
+	request.Version = secretProviderProtocolVersion
+	key := secretProviderRequestKey{
+		name:    name,
+		request: request,
+	}
+	if response, ok := secretProviderCache[key]; ok {
+		return response, nil
+	}
+	secretProvider, ok := c.SecretProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown secret provider", name)
+	}
+	if secretProvider.Command == "" {
+		return nil, fmt.Errorf("%s: secretProviders.%s.command not set", name, name)
+	}
+	requestData, err := json.Marshal(&request)
+	if err != nil {
+		return nil, err
+	}
+	// The request is sent on the provider's standard input, so include it in
+	// the secret cache key.
+	cacheKey := append([]string{secretProvider.Command}, secretProvider.Args...)
+	cacheKey = append(cacheKey, string(requestData))
+	output, ok, err := c.secretCacheGet(cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		cmd := exec.Command(secretProvider.Command, secretProvider.Args...)
+		cmd.Stdin = bytes.NewReader(requestData)
+		cmd.Stderr = c.Stderr
+		output, err = c.mutator.IdempotentCmdOutput(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s %s: %w", name, secretProvider.Command, chezmoi.ShellQuoteArgs(secretProvider.Args), err)
+		}
+	}
+	var response secretProviderResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("%s: %s %s: %w\n%s", name, secretProvider.Command, chezmoi.ShellQuoteArgs(secretProvider.Args), err, output)
+	}
+	switch {
+	case response.Error != "":
+		return nil, fmt.Errorf("%s: %s", name, response.Error)
+	case request.Method != secretProviderMethodList && response.Value == nil:
+		return nil, fmt.Errorf("%s: %s: response has no value", name, request.Method)
+	}
+	if !ok {
+		if err := c.secretCacheSet(cacheKey, output); err != nil {
+			return nil, err
+		}
+	}
+	secretProviderCache[key] = &response
+	return &response, nil
+}
+
+func (c *Config) secretProviderFunc(name, id string) string {
+	response, err := c.secretProviderDo(name, secretProviderRequest{
+		Method: secretProviderMethodGet,
+		ID:     id,
+	})
+	if err != nil {
+		panic(fmt.Errorf("secretProvider: %w", err))
+	}
+	return *response.Value
+}
+
+func (c *Config) secretProviderFieldFunc(name, id, field string) string {
+	response, err := c.secretProviderDo(name, secretProviderRequest{
+		Method: secretProviderMethodGetField,
+		ID:     id,
+		Field:  field,
+	})
+	if err != nil {
+		panic(fmt.Errorf("secretProviderField: %w", err))
+	}
+	return *response.Value
+}
+
+func (c *Config) secretProviderListFunc(name string) []string {
+	c.secretMutex.Lock()
+	defer c.secretMutex.Unlock()
+	response, err := c.secretProviderDo(name, secretProviderRequest{
+		Method: secretProviderMethodList,
+	})
+	if err != nil {
+		panic(fmt.Errorf("secretProviderList: %w", err))
+	}
+	return response.IDs
+}
+
+// validateSecretProviders ensures that no secret provider shadows a built-in
+// secret command.
+func (c *Config) validateSecretProviders() error {
+	for name := range c.SecretProviders {
+		for _, cmd := range secretCmd.Commands() {
+			if cmd.Name() == name || cmd.HasAlias(name) {
+				return fmt.Errorf("secretProviders.%s: conflicts with built-in command", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// secretStoreName is the name of the secret store in the source directory.
+const secretStoreName = ".chezmoisecrets"
+
+type secretStoreCmdConfig struct {
+	value string
+}
+
+var secretStoreCache map[string]string
+
+func init() {
+	config.addSecretTemplateFunc("secretStore", config.secretStoreFunc)
+}
+
+func (c *Config) getSecretStorePath() string {
+	return filepath.Join(c.SourceDir, secretStoreName)
+}
+
+// readSecretStore returns the decrypted contents of the secret store. If the
+// secret store does not exist then it returns an empty map.
+func (c *Config) readSecretStore() (map[string]string, error) {
+	path := c.getSecretStorePath()
+	ciphertext, err := c.fs.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return make(map[string]string), nil
+	case err != nil:
+		return nil, err
+	}
+	plaintext, err := c.getGPG().Decrypt(path, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return secrets, nil
+}
+
+func (c *Config) secretStoreFunc(key string) string {
+	if secretStoreCache == nil {
+		secrets, err := c.readSecretStore()
+		if err != nil {
+			panic(fmt.Errorf("secretStore: %w", err))
+		}
+		secretStoreCache = secrets
+	}
+	value, ok := secretStoreCache[key]
+	if !ok {
+		panic(fmt.Errorf("secretStore: %s: not found", key))
+	}
+	return value
+}
+
+// writeSecretStore encrypts secrets and writes them to the secret store.
+func (c *Config) writeSecretStore(secrets map[string]string) error {
+	if err := c.ensureSourceDirectory(); err != nil {
+		return err
+	}
+	path := c.getSecretStorePath()
+	currCiphertext, err := c.fs.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	plaintext, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	ciphertext, err := c.getGPG().Encrypt(path, plaintext)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return c.mutator.WriteFile(path, ciphertext, 0o666&^os.FileMode(c.Umask), currCiphertext)
+}
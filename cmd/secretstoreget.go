@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var secretStoreGetCmd = &cobra.Command{
+	Use:     "get key",
+	Args:    cobra.ExactArgs(1),
+	Short:   "Get a value from the secret store",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runSecretStoreGetCmd,
+}
+
+func init() {
+	secretCmd.AddCommand(secretStoreGetCmd)
+}
+
+func (c *Config) runSecretStoreGetCmd(cmd *cobra.Command, args []string) error {
+	secrets, err := c.readSecretStore()
+	if err != nil {
+		return err
+	}
+	value, ok := secrets[args[0]]
+	if !ok {
+		return fmt.Errorf("%s: not found", args[0])
+	}
+	_, err = fmt.Fprintln(c.Stdout, value)
+	return err
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var secretStoreListCmd = &cobra.Command{
+	Use:     "list",
+	Args:    cobra.NoArgs,
+	Short:   "List the keys in the secret store",
+	PreRunE: config.ensureNoError,
+	RunE:    config.runSecretStoreListCmd,
+}
+
+func init() {
+	secretCmd.AddCommand(secretStoreListCmd)
+}
+
+func (c *Config) runSecretStoreListCmd(cmd *cobra.Command, args []string) error {
+	secrets, err := c.readSecretStore()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(c.Stdout, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
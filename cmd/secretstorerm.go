@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var secretStoreRmCmd = &cobra.Command{
+	Use:      "rm keys...",
+	Args:     cobra.MinimumNArgs(1),
+	Short:    "Remove values from the secret store",
+	PreRunE:  config.ensureNoError,
+	RunE:     config.runSecretStoreRmCmd,
+	PostRunE: config.autoCommitAndAutoPush,
+}
+
+func init() {
+	secretCmd.AddCommand(secretStoreRmCmd)
+}
+
+func (c *Config) runSecretStoreRmCmd(cmd *cobra.Command, args []string) error {
+	secrets, err := c.readSecretStore()
+	if err != nil {
+		return err
+	}
+	for _, key := range args {
+		if _, ok := secrets[key]; !ok {
+			return fmt.Errorf("%s: not found", key)
+		}
+		delete(secrets, key)
+	}
+	return c.writeSecretStore(secrets)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var secretStoreSetCmd = &cobra.Command{
+	Use:      "set key",
+	Args:     cobra.ExactArgs(1),
+	Short:    "Set a value in the secret store",
+	PreRunE:  config.ensureNoError,
+	RunE:     config.runSecretStoreSetCmd,
+	PostRunE: config.autoCommitAndAutoPush,
+}
+
+func init() {
+	secretCmd.AddCommand(secretStoreSetCmd)
+
+	persistentFlags := secretStoreSetCmd.PersistentFlags()
+	persistentFlags.StringVar(&config.secretStore.value, "value", "", "value")
+}
+
+func (c *Config) runSecretStoreSetCmd(cmd *cobra.Command, args []string) error {
+	secrets, err := c.readSecretStore()
+	if err != nil {
+		return err
+	}
+	value := c.secretStore.value
+	if value == "" {
+		fmt.Print("Value: ")
+		valueBytes, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+		value = string(valueBytes)
+	}
+	secrets[args[0]] = value
+	return c.writeSecretStore(secrets)
+}
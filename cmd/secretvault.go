@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -19,14 +22,25 @@ var vaultCmd = &cobra.Command{
 }
 
 type vaultCmdConfig struct {
-	Command string
+	Command   string
+	Namespace string
 }
 
-var vaultCache = make(map[string]interface{})
+type vaultKVCacheKey struct {
+	path    string
+	version int
+}
+
+var (
+	vaultCache   = make(map[string]interface{})
+	vaultKVCache = make(map[vaultKVCacheKey]map[string]interface{})
+)
 
 func init() {
 	config.Vault.Command = "vault"
-	config.addTemplateFunc("vault", config.vaultFunc)
+	config.addSecretTemplateFunc("vault", config.vaultFunc)
+	config.addSecretTemplateFunc("vaultKV", config.vaultKVFunc)
+	config.addSecretTemplateFunc("vaultKVField", config.vaultKVFieldFunc)
 
 	secretCmd.AddCommand(vaultCmd)
 }
@@ -39,19 +53,114 @@ func (c *Config) vaultFunc(key string) interface{} {
 	if data, ok := vaultCache[key]; ok {
 		return data
 	}
+	data, err := c.vaultKVGet(key, 0)
+	if err != nil {
+		panic(fmt.Errorf("vault: %w", err))
+	}
+	vaultCache[key] = data
+	return data
+}
+
+// vaultKVFunc returns the data of the secret at path in the KV secrets engine
+// mounted at mount, unwrapping both KV version 1 and version 2 responses. If
+// version is given then that version of a KV version 2 secret is returned.
+func (c *Config) vaultKVFunc(mount, path string, version ...int) map[string]interface{} {
+	data, err := c.vaultKV(mount, path, version)
+	if err != nil {
+		panic(fmt.Errorf("vaultKV: %w", err))
+	}
+	return data
+}
+
+func (c *Config) vaultKVFieldFunc(mount, path, field string, version ...int) interface{} {
+	data, err := c.vaultKV(mount, path, version)
+	if err != nil {
+		panic(fmt.Errorf("vaultKVField: %w", err))
+	}
+	value, ok := data[field]
+	if !ok {
+		panic(fmt.Errorf("vaultKVField: %s/%s: %s: field not found", mount, path, field))
+	}
+	return value
+}
+
+func (c *Config) vaultKV(mount, path string, version []int) (map[string]interface{}, error) {
+	key := vaultKVCacheKey{
+		path: strings.Trim(mount, "/") + "/" + strings.TrimLeft(path, "/"),
+	}
+	switch len(version) {
+	case 0:
+	case 1:
+		key.version = version[0]
+	default:
+		return nil, fmt.Errorf("expected 2 or 3 arguments, got %d", 2+len(version))
+	}
+	if data, ok := vaultKVCache[key]; ok {
+		return data, nil
+	}
+	response, err := c.vaultKVGet(key.path, key.version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := vaultUnwrapKVResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key.path, err)
+	}
+	vaultKVCache[key] = data
+	return data, nil
+}
+
+// vaultKVGet returns the parsed output of vault kv get for path. If version is
+// non-zero then that version of the secret is requested.
+func (c *Config) vaultKVGet(path string, version int) (interface{}, error) {
 	name := c.Vault.Command
-	args := []string{"kv", "get", "-format=json", key}
+	args := []string{"kv", "get", "-format=json"}
+	// Pass the namespace explicitly so that it is part of the secret cache key.
+	if namespace := c.getVaultNamespace(); namespace != "" {
+		args = append(args, "-namespace="+namespace)
+	}
+	if version != 0 {
+		args = append(args, "-version="+strconv.Itoa(version))
+	}
+	args = append(args, path)
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := c.mutator.IdempotentCmdOutput(cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
-		panic(fmt.Errorf("vault: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		return nil, fmt.Errorf("%s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output)
 	}
 	var data interface{}
 	if err := json.Unmarshal(output, &data); err != nil {
-		panic(fmt.Errorf("vault: %s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output))
+		return nil, fmt.Errorf("%s %s: %w\n%s", name, chezmoi.ShellQuoteArgs(args), err, output)
 	}
-	vaultCache[key] = data
-	return data
+	return data, nil
+}
+
+// getVaultNamespace returns the Vault namespace, either from the config file or
+// from the VAULT_NAMESPACE environment variable.
+func (c *Config) getVaultNamespace() string {
+	if c.Vault.Namespace != "" {
+		return c.Vault.Namespace
+	}
+	return os.Getenv("VAULT_NAMESPACE")
+}
+
+// vaultUnwrapKVResponse returns the secret data from response. KV version 2
+// responses wrap the data in a further data field, alongside metadata.
+func vaultUnwrapKVResponse(response interface{}) (map[string]interface{}, error) {
+	responseMap, ok := response.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+	data, ok := responseMap["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("response has no data")
+	}
+	if _, ok := data["metadata"].(map[string]interface{}); ok {
+		if innerData, ok := data["data"].(map[string]interface{}); ok {
+			return innerData, nil
+		}
+	}
+	return data, nil
 }
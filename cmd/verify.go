@@ -19,6 +19,8 @@ var verifyCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(verifyCmd)
 
+	addFilterFlags(verifyCmd)
+
 	markRemainingZshCompPositionalArgumentsAsFiles(verifyCmd, 1)
 }
 
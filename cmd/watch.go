@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	vfs "github.com/twpayne/go-vfs"
+
+	"github.com/twpayne/chezmoi/internal/chezmoi"
+)
+
+var watchCmd = &cobra.Command{
+	Use:     "watch",
+	Args:    cobra.NoArgs,
+	Short:   "Apply changes to the source directory as they are made",
+	Long:    mustGetLongHelp("watch"),
+	Example: getExample("watch"),
+	PreRunE: config.ensureNoError,
+	RunE:    config.runWatchCmd,
+}
+
+type watchCmdConfig struct {
+	debounce time.Duration
+	drift    bool
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	persistentFlags := watchCmd.PersistentFlags()
+	persistentFlags.DurationVar(&config.watch.debounce, "debounce", 100*time.Millisecond, "wait for changes to stop for duration before applying")
+	persistentFlags.BoolVar(&config.watch.drift, "drift", false, "watch targets for changes instead of the source directory")
+	addFilterFlags(watchCmd)
+}
+
+func (c *Config) runWatchCmd(cmd *cobra.Command, args []string) error {
+	persistentState, err := c.getPersistentState(nil)
+	if err != nil {
+		return err
+	}
+	defer persistentState.Close()
+
+	// Diffs are always printed, so the user can see what was changed.
+	if !c.Verbose && c.OutputFormat == outputFormatText {
+		c.mutator = chezmoi.NewVerboseMutator(c.Stdout, c.mutator, c.colored, c.maxDiffDataSize, c.getRedactor())
+	}
+
+	ts, err := c.getTargetState(nil)
+	if err != nil {
+		return err
+	}
+	newWatcher, watch := c.newSourceWatcher, c.watchSource
+	if c.watch.drift {
+		newWatcher, watch = c.newDriftWatcher, c.watchDrift
+	}
+	watcher, err := newWatcher(ts)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	return watch(watcher, persistentState, stop)
+}
+
+// newSourceWatcher returns a new watcher that watches all directories in all
+// source directories, except .git directories and directories whose targets
+// are ignored in ts.
+func (c *Config) newSourceWatcher(ts *chezmoi.TargetState) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ignoredSourcePaths := make(map[string]bool)
+	for _, entry := range allTargetStateEntries(ts) {
+		if ts.Ignored(entry) {
+			ignoredSourcePaths[ts.SourcePath(entry)] = true
+		}
+	}
+	for _, sourceDir := range ts.SourceDirs() {
+		if err := c.addSourceWatches(watcher, sourceDir, ignoredSourcePaths); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return watcher, nil
+}
+
+// addSourceWatches adds watches to watcher for dir and all its subdirectories,
+// except .git directories and those in ignoredSourcePaths.
+func (c *Config) addSourceWatches(watcher *fsnotify.Watcher, dir string, ignoredSourcePaths map[string]bool) error {
+	return vfs.Walk(c.fs, dir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case os.IsNotExist(err):
+			// The directory was removed while it was being walked.
+			return nil
+		case err != nil:
+			return err
+		case !info.IsDir():
+			return nil
+		}
+		if info.Name() == ".git" || ignoredSourcePaths[path] {
+			return filepath.SkipDir
+		}
+		rawPath, err := c.fs.RawPath(path)
+		if err != nil {
+			return err
+		}
+		return watcher.Add(rawPath)
+	})
+}
+
+// watchSource applies changes to the source directories reported by watcher
+// until stop receives a value. Only the targets whose source paths changed are
+// applied.
+func (c *Config) watchSource(watcher *fsnotify.Watcher, persistentState chezmoi.PersistentState, stop <-chan os.Signal) error {
+	sourceDirs := c.getSourceDirs()
+	rawSourceDirs := make([]string, 0, len(sourceDirs))
+	for _, sourceDir := range sourceDirs {
+		rawSourceDir, err := c.fs.RawPath(sourceDir)
+		if err != nil {
+			return err
+		}
+		rawSourceDirs = append(rawSourceDirs, rawSourceDir)
+	}
+	changedSourcePaths := make(map[string]bool)
+	return c.watchEvents(watcher, stop, func(event fsnotify.Event) error {
+		index, relPath, ok := splitSourcePath(rawSourceDirs, event.Name)
+		if !ok || isGitPath(relPath) {
+			return nil
+		}
+		sourcePath := filepath.Join(sourceDirs[index], relPath)
+		if event.Op&fsnotify.Create != 0 {
+			if info, err := c.fs.Stat(sourcePath); err == nil && info.IsDir() {
+				if err := c.addSourceWatches(watcher, sourcePath, nil); err != nil {
+					c.printWatchError(err)
+				}
+			}
+		}
+		changedSourcePaths[sourcePath] = true
+		return nil
+	}, func() error {
+		if len(changedSourcePaths) == 0 {
+			return nil
+		}
+		defer func() {
+			changedSourcePaths = make(map[string]bool)
+		}()
+		return c.applySourceChanges(changedSourcePaths, persistentState)
+	})
+}
+
+// watchEvents calls changed for each event reported by watcher until stop
+// receives a value. Once no events have been reported for c.watch.debounce,
+// apply is called. Errors from apply and from watcher are printed without
+// stopping.
+func (c *Config) watchEvents(watcher *fsnotify.Watcher, stop <-chan os.Signal, changed func(fsnotify.Event) error, apply func() error) error {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if err := changed(event); err != nil {
+				return err
+			}
+			debounce = time.After(c.watch.debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			c.printWatchError(err)
+		case <-debounce:
+			if err := apply(); err != nil {
+				c.printWatchError(err)
+			}
+			debounce = nil
+		}
+	}
+}
+
+// applySourceChanges re-reads the source state and applies the targets whose
+// source paths are in changedSourcePaths. If a file that affects all targets,
+// like .chezmoiignore or a file in .chezmoitemplates, changed then all targets
+// are applied.
+func (c *Config) applySourceChanges(changedSourcePaths map[string]bool, persistentState chezmoi.PersistentState) error {
+	ts, err := c.getTargetState(nil)
+	if err != nil {
+		return err
+	}
+	applyOptions, err := c.getApplyOptions(ts, persistentState)
+	if err != nil {
+		return err
+	}
+
+	applyAll := false
+	for sourcePath := range changedSourcePaths {
+		if _, relPath, ok := splitSourcePath(ts.SourceDirs(), sourcePath); ok && isSpecialSourcePath(relPath) {
+			applyAll = true
+			break
+		}
+	}
+
+	var entries []chezmoi.Entry
+	var args []string
+	if !applyAll {
+		applied := make(map[string]bool)
+		for _, entry := range allTargetStateEntries(ts) {
+			if !changedSourcePaths[ts.SourcePath(entry)] || ts.Ignored(entry) {
+				continue
+			}
+			// Entries are sorted, so directories, which also apply their
+			// entries, are always found before their entries.
+			targetPath := ts.TargetPath(entry)
+			if hasParent(applied, targetPath) {
+				continue
+			}
+			entries = append(entries, entry)
+			args = append(args, targetPath)
+			applied[targetPath] = true
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+
+	return c.withBackups(func(mutator chezmoi.Mutator) error {
+		return c.withEvents(ts, args, mutator, applyOptions, func(mutator chezmoi.Mutator) error {
+			if applyAll {
+				return ts.Apply(vfs.NewReadOnlyFS(c.fs), mutator, c.Follow, applyOptions)
+			}
+			return c.applyEntries(ts, entries, mutator, applyOptions)
+		})
+	})
+}
+
+// printWatchError prints err, which does not stop watching.
+func (c *Config) printWatchError(err error) {
+	if s := err.Error(); s != "" {
+		fmt.Fprintf(c.Stderr, "chezmoi: %s\n", s)
+	}
+}
+
+// isGitPath returns whether relPath is in a .git directory.
+func isGitPath(relPath string) bool {
+	for _, component := range strings.Split(relPath, string(os.PathSeparator)) {
+		if component == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSourcePath returns the index of the source directory in sourceDirs that
+// contains path and the path relative to it. Later source directories take
+// precedence, in case source directories are nested.
+func splitSourcePath(sourceDirs []string, path string) (int, string, bool) {
+	for i := len(sourceDirs) - 1; i >= 0; i-- {
+		relPath, err := filepath.Rel(sourceDirs[i], path)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+			continue
+		}
+		return i, relPath, true
+	}
+	return 0, "", false
+}
+
+// isSpecialSourcePath returns whether relPath is, or is in, a special source
+// file or directory, like .chezmoiignore or .chezmoitemplates, which are not
+// entries but may affect any entry.
+func isSpecialSourcePath(relPath string) bool {
+	for _, component := range strings.Split(relPath, string(os.PathSeparator)) {
+		if strings.HasPrefix(component, ".chezmoi") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+func TestApplySourceChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		changedRelPaths []string
+		tests           []vfst.Test
+	}{
+		{
+			name:            "file",
+			changedRelPaths: []string{"dot_bashrc"},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.bashrc",
+					vfst.TestContentsString("# contents of .bashrc\n"),
+				),
+				vfst.TestPath("/home/user/.zshrc",
+					vfst.TestDoesNotExist,
+				),
+			},
+		},
+		{
+			name:            "dir",
+			changedRelPaths: []string{"dot_config", "dot_config/foo"},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.config/foo",
+					vfst.TestContentsString("# contents of .config/foo\n"),
+				),
+				vfst.TestPath("/home/user/.bashrc",
+					vfst.TestDoesNotExist,
+				),
+			},
+		},
+		{
+			name:            "ignored",
+			changedRelPaths: []string{"dot_ignored"},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.ignored",
+					vfst.TestDoesNotExist,
+				),
+			},
+		},
+		{
+			name:            "not_an_entry",
+			changedRelPaths: []string{"dot_bashrc~", ".git/index"},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.bashrc",
+					vfst.TestDoesNotExist,
+				),
+			},
+		},
+		{
+			name:            "chezmoiignore",
+			changedRelPaths: []string{".chezmoiignore"},
+			tests: []vfst.Test{
+				vfst.TestPath("/home/user/.bashrc",
+					vfst.TestContentsString("# contents of .bashrc\n"),
+				),
+				vfst.TestPath("/home/user/.config/foo",
+					vfst.TestContentsString("# contents of .config/foo\n"),
+				),
+				vfst.TestPath("/home/user/.ignored",
+					vfst.TestDoesNotExist,
+				),
+				vfst.TestPath("/home/user/.zshrc",
+					vfst.TestContentsString("# contents of .zshrc\n"),
+				),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+				"/home/user": map[string]interface{}{
+					".local/share/chezmoi": map[string]interface{}{
+						".chezmoiignore": ".ignored\n",
+						".git/index":     "",
+						"dot_bashrc":     "# contents of .bashrc\n",
+						"dot_config/foo": "# contents of .config/foo\n",
+						"dot_ignored":    "# contents of .ignored\n",
+						"dot_zshrc":      "# contents of .zshrc\n",
+					},
+				},
+			})
+			require.NoError(t, err)
+			defer cleanup()
+
+			c := newTestConfig(fs)
+			persistentState, err := c.getPersistentState(nil)
+			require.NoError(t, err)
+			defer persistentState.Close()
+
+			changedSourcePaths := make(map[string]bool)
+			for _, relPath := range tc.changedRelPaths {
+				changedSourcePaths[filepath.Join(c.SourceDir, relPath)] = true
+			}
+			require.NoError(t, c.applySourceChanges(changedSourcePaths, persistentState))
+			vfst.RunTests(t, fs, "", tc.tests)
+		})
+	}
+}
+
+func TestWatchSource(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".local/share/chezmoi": map[string]interface{}{
+				"dot_bashrc": "# contents of .bashrc\n",
+				"dot_zshrc":  "# contents of .zshrc\n",
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	c := newTestConfig(fs, withWatchCmdConfig(watchCmdConfig{
+		debounce: 10 * time.Millisecond,
+	}))
+	persistentState, err := c.getPersistentState(nil)
+	require.NoError(t, err)
+	defer persistentState.Close()
+	ts, err := c.getTargetState(nil)
+	require.NoError(t, err)
+	watcher, err := c.newSourceWatcher(ts)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	stop := make(chan os.Signal, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.watchSource(watcher, persistentState, stop)
+	}()
+
+	require.NoError(t, fs.WriteFile("/home/user/.local/share/chezmoi/dot_bashrc", []byte("# new contents of .bashrc\n"), 0o666))
+	assert.Eventually(t, func() bool {
+		contents, err := fs.ReadFile("/home/user/.bashrc")
+		return err == nil && string(contents) == "# new contents of .bashrc\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stop <- os.Interrupt
+	require.NoError(t, <-errCh)
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.zshrc",
+			vfst.TestDoesNotExist,
+		),
+	)
+}
@@ -345,6 +345,8 @@ _chezmoi_add()
     flags+=("-x")
     flags+=("--force")
     flags+=("-f")
+    flags+=("--layer=")
+    two_word_flags+=("--layer")
     flags+=("--prompt")
     flags+=("-p")
     flags+=("--recursive")
@@ -363,6 +365,9 @@ _chezmoi_add()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -389,6 +394,20 @@ _chezmoi_apply()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
+    flags+=("--from-plan=")
+    two_word_flags+=("--from-plan")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
+    flags+=("--keep-going")
+    flags+=("-k")
+    flags+=("--plan=")
+    two_word_flags+=("--plan")
+    flags+=("--rollback-last")
+    flags+=("--transactional")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -401,6 +420,9 @@ _chezmoi_apply()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -427,6 +449,12 @@ _chezmoi_archive()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
     flags+=("--output=")
     two_word_flags+=("--output")
     flags_with_completion+=("--output")
@@ -446,6 +474,9 @@ _chezmoi_archive()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -484,6 +515,9 @@ _chezmoi_cat()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -522,6 +556,9 @@ _chezmoi_cd()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -560,6 +597,9 @@ _chezmoi_chattr()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -608,6 +648,9 @@ _chezmoi_completion()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -652,6 +695,9 @@ _chezmoi_data()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -678,9 +724,15 @@ _chezmoi_diff()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
     flags+=("--format=")
     two_word_flags+=("--format")
     two_word_flags+=("-f")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
     flags+=("--no-pager")
     flags+=("--color=")
     two_word_flags+=("--color")
@@ -694,6 +746,9 @@ _chezmoi_diff()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -732,6 +787,9 @@ _chezmoi_docs()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -770,6 +828,9 @@ _chezmoi_doctor()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -796,9 +857,15 @@ _chezmoi_dump()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
     flags+=("--format=")
     two_word_flags+=("--format")
     two_word_flags+=("-f")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
     flags+=("--recursive")
     flags+=("-r")
     flags+=("--color=")
@@ -813,6 +880,9 @@ _chezmoi_dump()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -843,6 +913,8 @@ _chezmoi_edit()
     flags+=("-a")
     flags+=("--diff")
     flags+=("-d")
+    flags+=("--layer=")
+    two_word_flags+=("--layer")
     flags+=("--prompt")
     flags+=("-p")
     flags+=("--color=")
@@ -857,6 +929,9 @@ _chezmoi_edit()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -895,6 +970,9 @@ _chezmoi_edit-config()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -941,6 +1019,9 @@ _chezmoi_execute-template()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -979,6 +1060,9 @@ _chezmoi_forget()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1017,6 +1101,9 @@ _chezmoi_git()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1055,6 +1142,9 @@ _chezmoi_hg()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1099,6 +1189,9 @@ _chezmoi_import()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1138,6 +1231,9 @@ _chezmoi_init()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1167,6 +1263,7 @@ _chezmoi_managed()
     flags+=("--include=")
     two_word_flags+=("--include")
     two_word_flags+=("-i")
+    flags+=("--show-layer")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -1179,6 +1276,9 @@ _chezmoi_managed()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1217,6 +1317,9 @@ _chezmoi_merge()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1257,6 +1360,9 @@ _chezmoi_purge()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1297,6 +1403,99 @@ _chezmoi_remove()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_restore()
+{
+    last_command="chezmoi_restore"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--list")
+    flags+=("-l")
+    flags+=("--time=")
+    two_word_flags+=("--time")
+    two_word_flags+=("-t")
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_audit()
+{
+    last_command="chezmoi_secret_audit"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--format=")
+    two_word_flags+=("--format")
+    two_word_flags+=("-f")
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1335,6 +1534,92 @@ _chezmoi_secret_bitwarden()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_cache_flush()
+{
+    last_command="chezmoi_secret_cache_flush"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_cache()
+{
+    last_command="chezmoi_secret_cache"
+
+    command_aliases=()
+
+    commands=()
+    commands+=("flush")
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1373,6 +1658,50 @@ _chezmoi_secret_generic()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_get()
+{
+    last_command="chezmoi_secret_get"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1411,6 +1740,9 @@ _chezmoi_secret_gopass()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1449,6 +1781,9 @@ _chezmoi_secret_keepassxc()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1461,9 +1796,9 @@ _chezmoi_secret_keepassxc()
     noun_aliases=()
 }
 
-_chezmoi_secret_keyring_get()
+_chezmoi_secret_keyring_delete()
 {
-    last_command="chezmoi_secret_keyring_get"
+    last_command="chezmoi_secret_keyring_delete"
 
     command_aliases=()
 
@@ -1475,6 +1810,10 @@ _chezmoi_secret_keyring_get()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--service=")
+    two_word_flags+=("--service")
+    flags+=("--user=")
+    two_word_flags+=("--user")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -1487,14 +1826,103 @@ _chezmoi_secret_keyring_get()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
-    flags+=("--service=")
-    two_word_flags+=("--service")
     flags+=("--source=")
     two_word_flags+=("--source")
     two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_flag+=("--service=")
+    must_have_one_flag+=("--user=")
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_keyring_get()
+{
+    last_command="chezmoi_secret_keyring_get"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--service=")
+    two_word_flags+=("--service")
     flags+=("--user=")
     two_word_flags+=("--user")
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_flag+=("--service=")
+    must_have_one_flag+=("--user=")
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_keyring_import()
+{
+    last_command="chezmoi_secret_keyring_import"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
     flags+=("--verbose")
     flags+=("-v")
 
@@ -1519,6 +1947,12 @@ _chezmoi_secret_keyring_set()
 
     flags+=("--password=")
     two_word_flags+=("--password")
+    flags+=("--password-file=")
+    two_word_flags+=("--password-file")
+    flags+=("--service=")
+    two_word_flags+=("--service")
+    flags+=("--user=")
+    two_word_flags+=("--user")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -1531,18 +1965,19 @@ _chezmoi_secret_keyring_set()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
-    flags+=("--service=")
-    two_word_flags+=("--service")
     flags+=("--source=")
     two_word_flags+=("--source")
     two_word_flags+=("-S")
-    flags+=("--user=")
-    two_word_flags+=("--user")
     flags+=("--verbose")
     flags+=("-v")
 
     must_have_one_flag=()
+    must_have_one_flag+=("--service=")
+    must_have_one_flag+=("--user=")
     must_have_one_noun=()
     noun_aliases=()
 }
@@ -1554,7 +1989,9 @@ _chezmoi_secret_keyring()
     command_aliases=()
 
     commands=()
+    commands+=("delete")
     commands+=("get")
+    commands+=("import")
     commands+=("set")
 
     flags=()
@@ -1563,10 +2000,6 @@ _chezmoi_secret_keyring()
     flags_with_completion=()
     flags_completion=()
 
-    flags+=("--service=")
-    two_word_flags+=("--service")
-    flags+=("--user=")
-    two_word_flags+=("--user")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -1579,6 +2012,9 @@ _chezmoi_secret_keyring()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1587,8 +2023,6 @@ _chezmoi_secret_keyring()
     flags+=("-v")
 
     must_have_one_flag=()
-    must_have_one_flag+=("--service=")
-    must_have_one_flag+=("--user=")
     must_have_one_noun=()
     noun_aliases=()
 }
@@ -1619,6 +2053,50 @@ _chezmoi_secret_lastpass()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_list()
+{
+    last_command="chezmoi_secret_list"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1657,6 +2135,9 @@ _chezmoi_secret_onepassword()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1695,6 +2176,93 @@ _chezmoi_secret_pass()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_rm()
+{
+    last_command="chezmoi_secret_rm"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_secret_set()
+{
+    last_command="chezmoi_secret_set"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--value=")
+    two_word_flags+=("--value")
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1733,6 +2301,9 @@ _chezmoi_secret_vault()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1752,14 +2323,20 @@ _chezmoi_secret()
     command_aliases=()
 
     commands=()
+    commands+=("audit")
     commands+=("bitwarden")
+    commands+=("cache")
     commands+=("generic")
+    commands+=("get")
     commands+=("gopass")
     commands+=("keepassxc")
     commands+=("keyring")
     commands+=("lastpass")
+    commands+=("list")
     commands+=("onepassword")
     commands+=("pass")
+    commands+=("rm")
+    commands+=("set")
     commands+=("vault")
 
     flags=()
@@ -1780,6 +2357,9 @@ _chezmoi_secret()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1818,6 +2398,9 @@ _chezmoi_source()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1856,6 +2439,9 @@ _chezmoi_source-path()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1894,6 +2480,9 @@ _chezmoi_unmanaged()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1934,6 +2523,9 @@ _chezmoi_update()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -1983,6 +2575,9 @@ _chezmoi_upgrade()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -2009,6 +2604,62 @@ _chezmoi_verify()
     flags_with_completion=()
     flags_completion=()
 
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
+    flags+=("--color=")
+    two_word_flags+=("--color")
+    flags+=("--config=")
+    two_word_flags+=("--config")
+    two_word_flags+=("-c")
+    flags+=("--debug")
+    flags+=("--destination=")
+    two_word_flags+=("--destination")
+    two_word_flags+=("-D")
+    flags+=("--dry-run")
+    flags+=("-n")
+    flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
+    flags+=("--remove")
+    flags+=("--source=")
+    two_word_flags+=("--source")
+    two_word_flags+=("-S")
+    flags+=("--verbose")
+    flags+=("-v")
+
+    must_have_one_flag=()
+    must_have_one_noun=()
+    noun_aliases=()
+}
+
+_chezmoi_watch()
+{
+    last_command="chezmoi_watch"
+
+    command_aliases=()
+
+    commands=()
+
+    flags=()
+    two_word_flags=()
+    local_nonpersistent_flags=()
+    flags_with_completion=()
+    flags_completion=()
+
+    flags+=("--debounce=")
+    two_word_flags+=("--debounce")
+    flags+=("--drift")
+    flags+=("--exclude=")
+    two_word_flags+=("--exclude")
+    two_word_flags+=("-x")
+    flags+=("--include=")
+    two_word_flags+=("--include")
+    two_word_flags+=("-i")
     flags+=("--color=")
     two_word_flags+=("--color")
     flags+=("--config=")
@@ -2021,6 +2672,9 @@ _chezmoi_verify()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -2076,6 +2730,7 @@ _chezmoi_root_command()
         command_aliases+=("rm")
         aliashash["rm"]="remove"
     fi
+    commands+=("restore")
     commands+=("secret")
     commands+=("source")
     commands+=("source-path")
@@ -2083,6 +2738,7 @@ _chezmoi_root_command()
     commands+=("update")
     commands+=("upgrade")
     commands+=("verify")
+    commands+=("watch")
 
     flags=()
     two_word_flags=()
@@ -2102,6 +2758,9 @@ _chezmoi_root_command()
     flags+=("--dry-run")
     flags+=("-n")
     flags+=("--follow")
+    flags+=("--no-redact")
+    flags+=("--output-format=")
+    two_word_flags+=("--output-format")
     flags+=("--remove")
     flags+=("--source=")
     two_word_flags+=("--source")
@@ -11,7 +11,7 @@ require (
 	github.com/charmbracelet/glamour v0.1.0
 	github.com/coreos/go-semver v0.3.0
 	github.com/dlclark/regexp2 v1.2.0 // indirect
-	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-git/go-git/v5 v5.0.1-0.20200501143051-8543c83ab70a
 	github.com/golang/protobuf v1.4.2 // indirect
 	github.com/google/go-github/v26 v26.1.3
@@ -38,6 +38,7 @@ require (
 	github.com/spf13/viper v1.7.0
 	github.com/stretchr/objx v0.2.0 // indirect
 	github.com/stretchr/testify v1.5.1
+	github.com/tobischo/gokeepasslib/v3 v3.4.0
 	github.com/twpayne/go-shell v0.2.0
 	github.com/twpayne/go-vfs v1.4.2
 	github.com/twpayne/go-vfsafero v1.0.0
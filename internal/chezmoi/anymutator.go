@@ -26,6 +26,12 @@ func (m *AnyMutator) Chmod(name string, mode os.FileMode) error {
 	return m.m.Chmod(name, mode)
 }
 
+// Chown implements Mutator.Chown.
+func (m *AnyMutator) Chown(name string, uid, gid int) error {
+	m.mutated = true
+	return m.m.Chown(name, uid, gid)
+}
+
 // IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
 func (m *AnyMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
 	return m.m.IdempotentCmdOutput(cmd)
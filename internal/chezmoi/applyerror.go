@@ -0,0 +1,66 @@
+package chezmoi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An EntryError is an error applying a single target.
+type EntryError struct {
+	TargetName string
+	Err        error
+}
+
+// An ApplyErrors is the errors from applying targets when
+// ApplyOptions.KeepGoing is set.
+type ApplyErrors []*EntryError
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.TargetName, e.Err)
+}
+
+// Unwrap returns e's underlying error.
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+func (e ApplyErrors) Error() string {
+	sb := &strings.Builder{}
+	if len(e) == 1 {
+		sb.WriteString("1 target could not be applied:")
+	} else {
+		fmt.Fprintf(sb, "%d targets could not be applied:", len(e))
+	}
+	for _, entryError := range e {
+		sb.WriteString("\n    ")
+		sb.WriteString(entryError.Error())
+	}
+	return sb.String()
+}
+
+// Err returns all errors recorded by o, or nil if there were none.
+func (o *ApplyOptions) Err() error {
+	if len(o.errors) == 0 {
+		return nil
+	}
+	return o.errors
+}
+
+// HandleError wraps err, if it is not already an *EntryError, in an
+// *EntryError for targetName. If o.KeepGoing is not set then it returns the
+// wrapped error. Otherwise, it records the wrapped error, so that other targets
+// can still be applied, and returns nil.
+func (o *ApplyOptions) HandleError(targetName string, err error) error {
+	entryError, ok := err.(*EntryError)
+	if !ok {
+		entryError = &EntryError{
+			TargetName: targetName,
+			Err:        err,
+		}
+	}
+	if !o.KeepGoing {
+		return entryError
+	}
+	o.errors = append(o.errors, entryError)
+	return nil
+}
@@ -0,0 +1,356 @@
+package chezmoi
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	vfs "github.com/twpayne/go-vfs"
+)
+
+// BackupTimeFormat is the format of the names of backup directories.
+const BackupTimeFormat = "20060102T150405.000000000Z"
+
+// A BackupMutator wraps a Mutator and copies every target to a backup
+// directory before it is overwritten or removed.
+type BackupMutator struct {
+	m         Mutator
+	fs        vfs.FS
+	destDir   string
+	backupDir string
+	dir       string
+	backedUp  map[string]bool
+}
+
+// A Backup is a copy of targets taken at a point in time.
+type Backup struct {
+	Time time.Time
+	Dir  string
+}
+
+// NewBackupMutator returns a new BackupMutator that makes changes with m and
+// copies targets in destDir from fs to a new backup in backupDir named after
+// t.
+func NewBackupMutator(m Mutator, fs vfs.FS, destDir, backupDir string, t time.Time) *BackupMutator {
+	return &BackupMutator{
+		m:         m,
+		fs:        fs,
+		destDir:   destDir,
+		backupDir: backupDir,
+		dir:       filepath.Join(backupDir, t.UTC().Format(BackupTimeFormat)),
+		backedUp:  make(map[string]bool),
+	}
+}
+
+// Chmod implements Mutator.Chmod.
+func (m *BackupMutator) Chmod(name string, mode os.FileMode) error {
+	return m.m.Chmod(name, mode)
+}
+
+// Chown implements Mutator.Chown.
+func (m *BackupMutator) Chown(name string, uid, gid int) error {
+	return m.m.Chown(name, uid, gid)
+}
+
+// IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
+func (m *BackupMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	return m.m.IdempotentCmdOutput(cmd)
+}
+
+// Mkdir implements Mutator.Mkdir.
+func (m *BackupMutator) Mkdir(name string, perm os.FileMode) error {
+	return m.m.Mkdir(name, perm)
+}
+
+// RemoveAll implements Mutator.RemoveAll.
+func (m *BackupMutator) RemoveAll(name string) error {
+	if err := m.backup(name); err != nil {
+		return err
+	}
+	return m.m.RemoveAll(name)
+}
+
+// Rename implements Mutator.Rename.
+func (m *BackupMutator) Rename(oldpath, newpath string) error {
+	if err := m.backup(newpath); err != nil {
+		return err
+	}
+	return m.m.Rename(oldpath, newpath)
+}
+
+// RunCmd implements Mutator.RunCmd.
+func (m *BackupMutator) RunCmd(cmd *exec.Cmd) error {
+	return m.m.RunCmd(cmd)
+}
+
+// Stat implements Mutator.Stat.
+func (m *BackupMutator) Stat(name string) (os.FileInfo, error) {
+	return m.m.Stat(name)
+}
+
+// WriteFile implements Mutator.WriteFile.
+func (m *BackupMutator) WriteFile(name string, data []byte, perm os.FileMode, currData []byte) error {
+	if err := m.backup(name); err != nil {
+		return err
+	}
+	return m.m.WriteFile(name, data, perm, currData)
+}
+
+// WriteSymlink implements Mutator.WriteSymlink.
+func (m *BackupMutator) WriteSymlink(oldname, newname string) error {
+	if err := m.backup(newname); err != nil {
+		return err
+	}
+	return m.m.WriteSymlink(oldname, newname)
+}
+
+// ReadBackups returns all backups in backupDir, oldest first.
+func ReadBackups(fs vfs.FS, backupDir string) ([]*Backup, error) {
+	infos, err := fs.ReadDir(backupDir)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var backups []*Backup
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		t, err := time.Parse(BackupTimeFormat, info.Name())
+		if err != nil {
+			continue
+		}
+		backups = append(backups, &Backup{
+			Time: t,
+			Dir:  filepath.Join(backupDir, info.Name()),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// PruneBackups removes backups in backupDir that are older than maxAge or that
+// are not among the newest maxCount backups. A zero maxAge or maxCount
+// disables the corresponding limit.
+func PruneBackups(fs vfs.FS, backupDir string, maxAge time.Duration, maxCount int, now time.Time) error {
+	backups, err := ReadBackups(fs, backupDir)
+	if err != nil {
+		return err
+	}
+	for i, backup := range backups {
+		expired := maxAge > 0 && now.Sub(backup.Time) > maxAge
+		excess := maxCount > 0 && i < len(backups)-maxCount
+		if !expired && !excess {
+			continue
+		}
+		if err := fs.RemoveAll(backup.Dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Paths returns the relative paths of all files and symlinks in b.
+func (b *Backup) Paths(fs vfs.FS) ([]string, error) {
+	var paths []string
+	if err := vfs.Walk(fs, b.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(b.Dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, relPath)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Contains returns whether b contains relPath.
+func (b *Backup) Contains(fs vfs.FS, relPath string) (bool, error) {
+	_, err := fs.Lstat(filepath.Join(b.Dir, relPath))
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Restore restores relPath from b to destDir using m. fs is used to read b and
+// the current state of destDir.
+func (b *Backup) Restore(fs vfs.FS, m Mutator, destDir, relPath string) error {
+	targetPath := filepath.Join(destDir, relPath)
+	if _, err := fs.Lstat(targetPath); err == nil {
+		if err := m.RemoveAll(targetPath); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := mkdirAll(fs, m, filepath.Dir(targetPath)); err != nil {
+		return err
+	}
+	return restoreBackup(fs, m, filepath.Join(b.Dir, relPath), targetPath)
+}
+
+// backup copies name to m's backup directory, if it exists and is in m's
+// destination directory.
+func (m *BackupMutator) backup(name string) error {
+	relPath, err := filepath.Rel(m.destDir, name)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+		return nil
+	}
+	// Earlier backups in this run recorded the state before any changes were
+	// made, so they must not be overwritten.
+	for p := relPath; p != "."; p = filepath.Dir(p) {
+		if m.backedUp[p] {
+			return nil
+		}
+	}
+	if _, err := m.fs.Lstat(name); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	backupPath := filepath.Join(m.dir, relPath)
+	// Backups may contain secrets, so they are private.
+	if err := vfs.MkdirAll(m.fs, filepath.Dir(backupPath), 0o700); err != nil {
+		return err
+	}
+	if err := m.copy(name, backupPath); err != nil {
+		return err
+	}
+	m.backedUp[relPath] = true
+	return nil
+}
+
+// copy copies src to dst, recursively, skipping the backup directory.
+func (m *BackupMutator) copy(src, dst string) error {
+	if src == m.backupDir {
+		return nil
+	}
+	info, err := m.fs.Lstat(src)
+	if err != nil {
+		return err
+	}
+	// Only directories are merged with existing backups, as they may have
+	// been created to contain an earlier backup of one of their children.
+	dstInfo, err := m.fs.Lstat(dst)
+	switch {
+	case err == nil && !(info.IsDir() && dstInfo.IsDir()):
+		return nil
+	case err != nil && !os.IsNotExist(err):
+		return err
+	}
+	switch {
+	case info.Mode().IsRegular():
+		data, err := m.fs.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := m.fs.WriteFile(dst, data, 0o600); err != nil {
+			return err
+		}
+		return m.fs.Chmod(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeType == os.ModeSymlink:
+		linkname, err := m.fs.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return m.fs.Symlink(linkname, dst)
+	case info.IsDir():
+		if dstInfo == nil {
+			if err := m.fs.Mkdir(dst, 0o700); err != nil {
+				return err
+			}
+		}
+		infos, err := m.fs.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if err := m.copy(filepath.Join(src, info.Name()), filepath.Join(dst, info.Name())); err != nil {
+				return err
+			}
+		}
+		return m.fs.Chmod(dst, info.Mode().Perm())
+	default:
+		return nil
+	}
+}
+
+// mkdirAll creates dir and any missing parents using m.
+func mkdirAll(fs vfs.FS, m Mutator, dir string) error {
+	info, err := fs.Stat(dir)
+	switch {
+	case err == nil && info.IsDir():
+		return nil
+	case err == nil:
+		return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+	case !os.IsNotExist(err):
+		return err
+	}
+	if parentDir := filepath.Dir(dir); parentDir != dir {
+		if err := mkdirAll(fs, m, parentDir); err != nil {
+			return err
+		}
+	}
+	return m.Mkdir(dir, 0o777)
+}
+
+// restoreBackup copies src from fs to dst using m, recursively.
+func restoreBackup(fs vfs.FS, m Mutator, src, dst string) error {
+	info, err := fs.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode().IsRegular():
+		data, err := fs.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := m.WriteFile(dst, data, info.Mode().Perm(), nil); err != nil {
+			return err
+		}
+		return m.Chmod(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeType == os.ModeSymlink:
+		linkname, err := fs.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return m.WriteSymlink(linkname, dst)
+	case info.IsDir():
+		if err := m.Mkdir(dst, 0o700); err != nil {
+			return err
+		}
+		infos, err := fs.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if err := restoreBackup(fs, m, filepath.Join(src, info.Name()), filepath.Join(dst, info.Name())); err != nil {
+				return err
+			}
+		}
+		return m.Chmod(dst, info.Mode().Perm())
+	default:
+		return nil
+	}
+}
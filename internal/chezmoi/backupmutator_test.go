@@ -0,0 +1,104 @@
+package chezmoi
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+var _ Mutator = &BackupMutator{}
+
+func TestBackupMutator(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".backups": &vfst.Dir{Perm: 0o700},
+			"dir": map[string]interface{}{
+				"file":  "# contents of dir/file\n",
+				"file2": "# contents of dir/file2\n",
+			},
+			"file":    "# contents of file\n",
+			"symlink": &vfst.Symlink{Target: "file"},
+		},
+		"/tmp/outside": "# contents of outside\n",
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewBackupMutator(NewFSMutator(fs), fs, "/home/user", "/home/user/.backups", t1)
+	assert.NoError(t, m.WriteFile("/home/user/dir/file", []byte("# new contents of dir/file\n"), 0o644, nil))
+	assert.NoError(t, m.RemoveAll("/home/user/dir"))
+	assert.NoError(t, m.WriteFile("/home/user/file", []byte("# new contents of file\n"), 0o644, nil))
+	assert.NoError(t, m.WriteFile("/home/user/file", []byte("# newer contents of file\n"), 0o644, nil))
+	assert.NoError(t, m.WriteSymlink("dir", "/home/user/symlink"))
+	assert.NoError(t, m.WriteFile("/home/user/new", []byte("# contents of new\n"), 0o644, nil))
+	assert.NoError(t, m.WriteFile("/tmp/outside", []byte("# new contents of outside\n"), 0o644, nil))
+
+	backupDir := "/home/user/.backups/20200102T030405.000000000Z"
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath(backupDir+"/dir/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of dir/file\n"),
+		),
+		vfst.TestPath(backupDir+"/dir/file2",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of dir/file2\n"),
+		),
+		vfst.TestPath(backupDir+"/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of file\n"),
+		),
+		vfst.TestPath(backupDir+"/symlink",
+			vfst.TestModeType(os.ModeSymlink),
+			vfst.TestSymlinkTarget("file"),
+		),
+		vfst.TestPath(backupDir+"/new",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath(backupDir+"/.backups",
+			vfst.TestDoesNotExist,
+		),
+	)
+
+	backups, err := ReadBackups(fs, "/home/user/.backups")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.True(t, t1.Equal(backups[0].Time))
+	paths, err := backups[0].Paths(fs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir/file", "dir/file2", "file", "symlink"}, paths)
+
+	t2 := t1.Add(time.Hour)
+	m = NewBackupMutator(NewFSMutator(fs), fs, "/home/user", "/home/user/.backups", t2)
+	require.NoError(t, backups[0].Restore(fs, m, "/home/user", "dir"))
+	require.NoError(t, backups[0].Restore(fs, m, "/home/user", "file"))
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/dir/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of dir/file\n"),
+		),
+		vfst.TestPath("/home/user/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of file\n"),
+		),
+		vfst.TestPath("/home/user/.backups/20200102T040405.000000000Z/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# newer contents of file\n"),
+		),
+	)
+
+	require.NoError(t, PruneBackups(fs, "/home/user/.backups", 0, 1, t2))
+	backups, err = ReadBackups(fs, "/home/user/.backups")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.True(t, t2.Equal(backups[0].Time))
+
+	require.NoError(t, PruneBackups(fs, "/home/user/.backups", time.Minute, 0, t2.Add(time.Hour)))
+	backups, err = ReadBackups(fs, "/home/user/.backups")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
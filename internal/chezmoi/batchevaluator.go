@@ -0,0 +1,59 @@
+package chezmoi
+
+// A batchEvaluator evaluates the entries that are about to be applied
+// concurrently, in batches that end at each script. Scripts are evaluated when
+// they are applied, and the entries after a script are only evaluated after it
+// has been run, so templates that depend on the effects of earlier scripts see
+// them. A nil *batchEvaluator does not evaluate anything.
+type batchEvaluator struct {
+	ts          *TargetState
+	leafEntries []Entry
+	indexes     map[Entry]int
+	next        int
+}
+
+// newBatchEvaluator returns a new batchEvaluator for the leaf entries of
+// entries that are included by filter, or nil if ts.Parallelism is less than
+// two.
+func (ts *TargetState) newBatchEvaluator(entries []Entry, filter *EntryTypeFilter) *batchEvaluator {
+	if ts.Parallelism < 2 {
+		return nil
+	}
+	var leafEntries []Entry
+	for _, entry := range entries {
+		leafEntries = ts.appendLeafEntries(leafEntries, entry, filter)
+	}
+	indexes := make(map[Entry]int, len(leafEntries))
+	for index, entry := range leafEntries {
+		indexes[entry] = index
+	}
+	return &batchEvaluator{
+		ts:          ts,
+		leafEntries: leafEntries,
+		indexes:     indexes,
+	}
+}
+
+// evaluate evaluates the batch of entries starting at entry, if entry is a leaf
+// entry that has not been evaluated yet. It must be called before entry is
+// applied. Errors are remembered by each entry and returned when the entry is
+// applied, so changes are made and errors are reported in the same order as
+// when entries are evaluated serially.
+func (be *batchEvaluator) evaluate(entry Entry) {
+	if be == nil {
+		return
+	}
+	index, ok := be.indexes[entry]
+	if !ok || index < be.next {
+		return
+	}
+	end := index
+	for end < len(be.leafEntries) {
+		if _, ok := be.leafEntries[end].(*Script); ok {
+			break
+		}
+		end++
+	}
+	_ = be.ts.evaluateLeafEntries(be.leafEntries[index:end])
+	be.next = end + 1
+}
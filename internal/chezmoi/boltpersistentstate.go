@@ -67,6 +67,22 @@ func (b *BoltPersistentState) Delete(bucket, key []byte) error {
 	})
 }
 
+// ForEach calls fn for each key and value in bucket. fn must not modify b.
+func (b *BoltPersistentState) ForEach(bucket []byte, fn func(k, v []byte) error) error {
+	if b.db == nil {
+		return nil
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(copyBytes(k), copyBytes(v))
+		})
+	})
+}
+
 // Get returns the value associated with key in bucket.
 func (b *BoltPersistentState) Get(bucket, key []byte) ([]byte, error) {
 	var value []byte
@@ -78,11 +94,7 @@ func (b *BoltPersistentState) Get(bucket, key []byte) ([]byte, error) {
 		if b == nil {
 			return nil
 		}
-		v := b.Get(key)
-		if v != nil {
-			value = make([]byte, len(v))
-			copy(value, v)
-		}
+		value = copyBytes(b.Get(key))
 		return nil
 	})
 }
@@ -120,3 +132,14 @@ func (b *BoltPersistentState) openDB() error {
 	b.db = db
 	return err
 }
+
+// copyBytes returns a copy of data, which is only valid for the lifetime of a
+// bolt transaction.
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	result := make([]byte, len(data))
+	copy(result, data)
+	return result
+}
@@ -61,6 +61,13 @@ func TestBoltPersistentState(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, value, actualValue)
 
+	actualKeyValues := make(map[string][]byte)
+	require.NoError(t, b.ForEach(bucket, func(k, v []byte) error {
+		actualKeyValues[string(k)] = v
+		return nil
+	}))
+	assert.Equal(t, map[string][]byte{string(key): value}, actualKeyValues)
+
 	require.NoError(t, b.Close())
 
 	b, err = NewBoltPersistentState(fs, path, vfst.DefaultUmask, nil)
@@ -30,6 +30,7 @@ const (
 type PersistentState interface {
 	Close() error
 	Delete(bucket, key []byte) error
+	ForEach(bucket []byte, fn func(k, v []byte) error) error
 	Get(bucket, key []byte) ([]byte, error)
 	Set(bucket, key, value []byte) error
 }
@@ -38,24 +39,31 @@ type PersistentState interface {
 type ApplyOptions struct {
 	DestDir           string
 	DryRun            bool
+	Events            func(*Event)
+	Filter            *EntryTypeFilter
 	Ignore            func(string) bool
+	KeepGoing         bool
 	PersistentState   PersistentState
+	Plan              *Plan
+	Redactor          *Redactor
 	Remove            bool
 	ScriptStateBucket []byte
 	Stdout            io.Writer
 	Umask             os.FileMode
 	Verbose           bool
+	errors            ApplyErrors
+	evaluator         *batchEvaluator
 }
 
 // An Entry is either a Dir, a File, or a Symlink.
 type Entry interface {
 	AppendAllEntries(allEntries []Entry) []Entry
 	Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *ApplyOptions) error
-	ConcreteValue(ignore func(string) bool, sourceDir string, umask os.FileMode, recursive bool) (interface{}, error)
+	ConcreteValue(ignore func(string) bool, filter *EntryTypeFilter, sourceDir func(Entry) string, umask os.FileMode, recursive bool) (interface{}, error)
 	Evaluate(ignore func(string) bool) error
 	SourceName() string
 	TargetName() string
-	archive(w *tar.Writer, ignore func(string) bool, headerTemplate *tar.Header, umask os.FileMode) error
+	archive(w *tar.Writer, ignore func(string) bool, filter *EntryTypeFilter, headerTemplate *tar.Header, umask os.FileMode) error
 }
 
 type parsedSourceFilePath struct {
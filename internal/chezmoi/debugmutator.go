@@ -9,13 +9,16 @@ import (
 
 // A DebugMutator wraps a Mutator and logs all of the actions it executes.
 type DebugMutator struct {
-	m Mutator
+	m        Mutator
+	redactor *Redactor
 }
 
-// NewDebugMutator returns a new DebugMutator.
-func NewDebugMutator(m Mutator) *DebugMutator {
+// NewDebugMutator returns a new DebugMutator. Secrets known to redactor are
+// masked in its logs.
+func NewDebugMutator(m Mutator, redactor *Redactor) *DebugMutator {
 	return &DebugMutator{
-		m: m,
+		m:        m,
+		redactor: redactor,
 	}
 }
 
@@ -26,10 +29,17 @@ func (m *DebugMutator) Chmod(name string, mode os.FileMode) error {
 	})
 }
 
+// Chown implements Mutator.Chown.
+func (m *DebugMutator) Chown(name string, uid, gid int) error {
+	return Debugf("Chown(%q, %d, %d)", []interface{}{name, uid, gid}, func() error {
+		return m.m.Chown(name, uid, gid)
+	})
+}
+
 // IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
 func (m *DebugMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
 	var output []byte
-	cmdStr := ShellQuoteArgs(append([]string{cmd.Path}, cmd.Args[1:]...))
+	cmdStr := m.redactor.RedactString(ShellQuoteArgs(append([]string{cmd.Path}, cmd.Args[1:]...)))
 	err := Debugf("IdempotentCmdOutput(%q)", []interface{}{cmdStr}, func() error {
 		var err error
 		output, err = m.m.IdempotentCmdOutput(cmd)
@@ -61,7 +71,7 @@ func (m *DebugMutator) Rename(oldpath, newpath string) error {
 
 // RunCmd implements Mutator.RunCmd.
 func (m *DebugMutator) RunCmd(cmd *exec.Cmd) error {
-	cmdStr := ShellQuoteArgs(append([]string{cmd.Path}, cmd.Args[1:]...))
+	cmdStr := m.redactor.RedactString(ShellQuoteArgs(append([]string{cmd.Path}, cmd.Args[1:]...)))
 	return Debugf("Run(%q)", []interface{}{cmdStr}, func() error {
 		return m.m.RunCmd(cmd)
 	})
@@ -87,7 +97,7 @@ func (m *DebugMutator) WriteFile(name string, data []byte, perm os.FileMode, cur
 
 // WriteSymlink implements Mutator.WriteSymlink.
 func (m *DebugMutator) WriteSymlink(oldname, newname string) error {
-	return Debugf("WriteSymlink(%q, %q)", []interface{}{oldname, newname}, func() error {
+	return Debugf("WriteSymlink(%q, %q)", []interface{}{m.redactor.RedactString(oldname), newname}, func() error {
 		return m.m.WriteSymlink(oldname, newname)
 	})
 }
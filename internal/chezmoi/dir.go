@@ -22,7 +22,13 @@ type Dir struct {
 	targetName string
 	Exact      bool
 	Perm       os.FileMode
+	Ownership  Ownership
 	Entries    map[string]Entry
+
+	// implicit is true if the directory is only in the target state because
+	// it contains an XDG base directory. Implicit directories are created if
+	// needed but are otherwise not managed.
+	implicit bool
 }
 
 type dirConcreteValue struct {
@@ -31,6 +37,8 @@ type dirConcreteValue struct {
 	TargetPath string        `json:"targetPath" yaml:"targetPath"`
 	Exact      bool          `json:"exact" yaml:"exact"`
 	Perm       int           `json:"perm" yaml:"perm"`
+	Owner      string        `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Group      string        `json:"group,omitempty" yaml:"group,omitempty"`
 	Entries    []interface{} `json:"entries" yaml:"entries"`
 }
 
@@ -87,7 +95,9 @@ func newDir(sourceName, targetName string, exact bool, perm os.FileMode) *Dir {
 
 // AppendAllEntries appends all Entries in d to allEntries.
 func (d *Dir) AppendAllEntries(allEntries []Entry) []Entry {
-	allEntries = append(allEntries, d)
+	if !d.implicit {
+		allEntries = append(allEntries, d)
+	}
 	for _, entry := range d.Entries {
 		allEntries = entry.AppendAllEntries(allEntries)
 	}
@@ -100,6 +110,16 @@ func (d *Dir) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Apply
 		return nil
 	}
 	targetPath := filepath.Join(applyOptions.DestDir, d.targetName)
+	if !applyOptions.Filter.IncludeEntry(d) {
+		// Excluded directories are not changed, but their entries are still
+		// applied if the directory already exists.
+		if info, err := fs.Stat(targetPath); os.IsNotExist(err) || err == nil && !info.IsDir() {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return d.applyEntries(fs, mutator, follow, applyOptions)
+	}
 	var info os.FileInfo
 	var err error
 	if follow {
@@ -109,11 +129,14 @@ func (d *Dir) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Apply
 	}
 	switch {
 	case err == nil && info.IsDir():
-		if info.Mode().Perm() != d.Perm&^applyOptions.Umask {
+		if !d.implicit && info.Mode().Perm() != d.Perm&^applyOptions.Umask {
 			if err := mutator.Chmod(targetPath, d.Perm&^applyOptions.Umask); err != nil {
 				return err
 			}
 		}
+		if err := d.Ownership.apply(mutator, targetPath, info); err != nil {
+			return err
+		}
 	case err == nil:
 		if err := mutator.RemoveAll(targetPath); err != nil {
 			return err
@@ -123,13 +146,14 @@ func (d *Dir) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Apply
 		if err := mutator.Mkdir(targetPath, d.Perm&^applyOptions.Umask); err != nil {
 			return err
 		}
+		if err := d.Ownership.apply(mutator, targetPath, nil); err != nil {
+			return err
+		}
 	default:
 		return err
 	}
-	for _, entryName := range sortedEntryNames(d.Entries) {
-		if err := d.Entries[entryName].Apply(fs, mutator, follow, applyOptions); err != nil {
-			return err
-		}
+	if err := d.applyEntries(fs, mutator, follow, applyOptions); err != nil {
+		return err
 	}
 	if d.Exact {
 		infos, err := fs.ReadDir(targetPath)
@@ -143,7 +167,9 @@ func (d *Dir) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Apply
 					continue
 				}
 				if err := mutator.RemoveAll(filepath.Join(targetPath, name)); err != nil {
-					return err
+					if err := applyOptions.HandleError(filepath.Join(d.targetName, name), err); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -152,28 +178,38 @@ func (d *Dir) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Apply
 }
 
 // ConcreteValue implements Entry.ConcreteValue.
-func (d *Dir) ConcreteValue(ignore func(string) bool, sourceDir string, umask os.FileMode, recursive bool) (interface{}, error) {
+func (d *Dir) ConcreteValue(ignore func(string) bool, filter *EntryTypeFilter, sourceDir func(Entry) string, umask os.FileMode, recursive bool) (interface{}, error) {
 	if ignore(d.targetName) {
 		return nil, nil
 	}
+	include := filter.IncludeEntry(d) && !d.implicit
+	if !include && !recursive {
+		return nil, nil
+	}
 	var entryConcreteValues []interface{}
 	if recursive {
 		for _, entryName := range sortedEntryNames(d.Entries) {
-			entryConcreteValue, err := d.Entries[entryName].ConcreteValue(ignore, sourceDir, umask, recursive)
+			entryConcreteValue, err := d.Entries[entryName].ConcreteValue(ignore, filter, sourceDir, umask, recursive)
 			if err != nil {
 				return nil, err
 			}
-			if entryConcreteValue != nil {
-				entryConcreteValues = append(entryConcreteValues, entryConcreteValue)
-			}
+			entryConcreteValues = AppendConcreteValue(entryConcreteValues, entryConcreteValue)
+		}
+	}
+	if !include {
+		if entryConcreteValues == nil {
+			return nil, nil
 		}
+		return entryConcreteValues, nil
 	}
 	return &dirConcreteValue{
 		Type:       "dir",
-		SourcePath: filepath.Join(sourceDir, d.SourceName()),
+		SourcePath: filepath.Join(sourceDir(d), d.SourceName()),
 		TargetPath: d.TargetName(),
 		Exact:      d.Exact,
 		Perm:       int(d.Perm &^ umask),
+		Owner:      d.Ownership.Owner,
+		Group:      d.Ownership.Group,
 		Entries:    entryConcreteValues,
 	}, nil
 }
@@ -207,21 +243,37 @@ func (d *Dir) TargetName() string {
 }
 
 // archive writes d to w.
-func (d *Dir) archive(w *tar.Writer, ignore func(string) bool, headerTemplate *tar.Header, umask os.FileMode) error {
+func (d *Dir) archive(w *tar.Writer, ignore func(string) bool, filter *EntryTypeFilter, headerTemplate *tar.Header, umask os.FileMode) error {
 	if ignore(d.targetName) {
 		return nil
 	}
-	header := *headerTemplate
-	header.Typeflag = tar.TypeDir
-	header.Name = d.targetName
-	header.Mode = int64(d.Perm &^ umask)
-	if err := w.WriteHeader(&header); err != nil {
-		return err
+	if filter.IncludeEntry(d) {
+		header := *headerTemplate
+		header.Typeflag = tar.TypeDir
+		header.Name = d.targetName
+		header.Mode = int64(d.Perm &^ umask)
+		if err := w.WriteHeader(&header); err != nil {
+			return err
+		}
 	}
 	for _, entryName := range sortedEntryNames(d.Entries) {
-		if err := d.Entries[entryName].archive(w, ignore, headerTemplate, umask); err != nil {
+		if err := d.Entries[entryName].archive(w, ignore, filter, headerTemplate, umask); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// applyEntries applies all entries in d.
+func (d *Dir) applyEntries(fs vfs.FS, mutator Mutator, follow bool, applyOptions *ApplyOptions) error {
+	for _, entryName := range sortedEntryNames(d.Entries) {
+		entry := d.Entries[entryName]
+		applyOptions.evaluator.evaluate(entry)
+		if err := entry.Apply(fs, mutator, follow, applyOptions); err != nil {
+			if err := applyOptions.HandleError(entry.TargetName(), err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
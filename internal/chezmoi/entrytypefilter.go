@@ -0,0 +1,124 @@
+package chezmoi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An EntryTypeSet is a set of entry types.
+type EntryTypeSet int
+
+// Entry types.
+const (
+	EntryTypeDirs EntryTypeSet = 1 << iota
+	EntryTypeFiles
+	EntryTypeSymlinks
+	EntryTypeScripts
+	EntryTypeEncrypted
+	EntryTypeTemplates
+	EntryTypeOnceScripts
+
+	EntryTypesAll  EntryTypeSet = EntryTypeDirs | EntryTypeFiles | EntryTypeSymlinks | EntryTypeScripts | EntryTypeEncrypted | EntryTypeTemplates | EntryTypeOnceScripts
+	EntryTypesNone EntryTypeSet = 0
+)
+
+var entryTypeNames = map[string]EntryTypeSet{
+	"all":          EntryTypesAll,
+	"dirs":         EntryTypeDirs,
+	"d":            EntryTypeDirs,
+	"encrypted":    EntryTypeEncrypted,
+	"e":            EntryTypeEncrypted,
+	"files":        EntryTypeFiles,
+	"f":            EntryTypeFiles,
+	"none":         EntryTypesNone,
+	"once-scripts": EntryTypeOnceScripts,
+	"o":            EntryTypeOnceScripts,
+	"scripts":      EntryTypeScripts,
+	"symlinks":     EntryTypeSymlinks,
+	"s":            EntryTypeSymlinks,
+	"templates":    EntryTypeTemplates,
+	"t":            EntryTypeTemplates,
+}
+
+// An EntryTypeFilter filters entries by type. An entry is included if it has
+// any of the types in Include and none of the types in Exclude. A nil
+// *EntryTypeFilter includes all entries.
+type EntryTypeFilter struct {
+	Include EntryTypeSet
+	Exclude EntryTypeSet
+}
+
+// ParseEntryTypeSet parses a list of entry type names.
+func ParseEntryTypeSet(names []string) (EntryTypeSet, error) {
+	s := EntryTypesNone
+	for _, name := range names {
+		t, ok := entryTypeNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return EntryTypesNone, fmt.Errorf("%s: unknown entry type", name)
+		}
+		s |= t
+	}
+	return s, nil
+}
+
+// NewEntryTypeFilter returns a new EntryTypeFilter.
+func NewEntryTypeFilter(include, exclude EntryTypeSet) *EntryTypeFilter {
+	return &EntryTypeFilter{
+		Include: include,
+		Exclude: exclude,
+	}
+}
+
+// IncludeEntry returns whether entry is included by f.
+func (f *EntryTypeFilter) IncludeEntry(entry Entry) bool {
+	if f == nil {
+		return true
+	}
+	t := EntryTypeOf(entry)
+	return t&f.Include != 0 && t&f.Exclude == 0
+}
+
+// EntryTypeOf returns the types of entry.
+func EntryTypeOf(entry Entry) EntryTypeSet {
+	var t EntryTypeSet
+	switch entry := entry.(type) {
+	case *Dir:
+		t = EntryTypeDirs
+	case *File:
+		t = EntryTypeFiles
+		if entry.Encrypted {
+			t |= EntryTypeEncrypted
+		}
+		if entry.Template {
+			t |= EntryTypeTemplates
+		}
+	case *Script:
+		t = EntryTypeScripts
+		if entry.Once {
+			t |= EntryTypeOnceScripts
+		}
+		if entry.Template {
+			t |= EntryTypeTemplates
+		}
+	case *Symlink:
+		t = EntryTypeSymlinks
+		if entry.Template {
+			t |= EntryTypeTemplates
+		}
+	}
+	return t
+}
+
+// AppendConcreteValue appends value to values. Entries that are excluded by a
+// filter but whose children are not have a concrete value of their children's
+// concrete values, which are appended individually.
+func AppendConcreteValue(values []interface{}, value interface{}) []interface{} {
+	switch value := value.(type) {
+	case nil:
+		return values
+	case []interface{}:
+		return append(values, value...)
+	default:
+		return append(values, value)
+	}
+}
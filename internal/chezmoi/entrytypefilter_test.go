@@ -0,0 +1,80 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryTypeFilter(t *testing.T) {
+	dir := &Dir{}
+	file := &File{}
+	encryptedFile := &File{Encrypted: true}
+	templateSymlink := &Symlink{Template: true}
+	script := &Script{}
+	onceScript := &Script{Once: true}
+	for _, tc := range []struct {
+		name     string
+		include  []string
+		exclude  []string
+		included []Entry
+		excluded []Entry
+	}{
+		{
+			name:     "all",
+			include:  []string{"all"},
+			included: []Entry{dir, file, encryptedFile, templateSymlink, script, onceScript},
+		},
+		{
+			name:     "exclude_scripts",
+			include:  []string{"all"},
+			exclude:  []string{"scripts"},
+			included: []Entry{dir, file, encryptedFile, templateSymlink},
+			excluded: []Entry{script, onceScript},
+		},
+		{
+			name:     "include_encrypted",
+			include:  []string{"encrypted"},
+			included: []Entry{encryptedFile},
+			excluded: []Entry{dir, file, templateSymlink, script, onceScript},
+		},
+		{
+			name:     "include_once_scripts_and_templates",
+			include:  []string{"once-scripts", "t"},
+			included: []Entry{templateSymlink, onceScript},
+			excluded: []Entry{dir, file, encryptedFile, script},
+		},
+		{
+			name:     "exclude_encrypted_files",
+			include:  []string{"files"},
+			exclude:  []string{"encrypted"},
+			included: []Entry{file},
+			excluded: []Entry{dir, encryptedFile, templateSymlink, script, onceScript},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			include, err := ParseEntryTypeSet(tc.include)
+			require.NoError(t, err)
+			exclude, err := ParseEntryTypeSet(tc.exclude)
+			require.NoError(t, err)
+			f := NewEntryTypeFilter(include, exclude)
+			for _, entry := range tc.included {
+				assert.True(t, f.IncludeEntry(entry), "%T %+v", entry, entry)
+			}
+			for _, entry := range tc.excluded {
+				assert.False(t, f.IncludeEntry(entry), "%T %+v", entry, entry)
+			}
+		})
+	}
+}
+
+func TestEntryTypeFilterNil(t *testing.T) {
+	var f *EntryTypeFilter
+	assert.True(t, f.IncludeEntry(&Script{}))
+}
+
+func TestParseEntryTypeSetError(t *testing.T) {
+	_, err := ParseEntryTypeSet([]string{"files", "sockets"})
+	assert.Error(t, err)
+}
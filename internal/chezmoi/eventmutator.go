@@ -0,0 +1,122 @@
+package chezmoi
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Event types.
+const (
+	EventTypeAction  = "action"
+	EventTypeDrift   = "drift"
+	EventTypeError   = "error"
+	EventTypeScript  = "script"
+	EventTypeSkip    = "skip"
+	EventTypeSummary = "summary"
+)
+
+// EventActionRunCmd is the action of events for commands run with
+// Mutator.RunCmd. Other actions are the same as those in plans.
+const EventActionRunCmd = "runCmd"
+
+// An Event is something that happened while applying the target state.
+type Event struct {
+	Type       string        `json:"type"`
+	Action     string        `json:"action,omitempty"`
+	TargetPath string        `json:"targetPath,omitempty"`
+	NewPath    string        `json:"newPath,omitempty"`
+	SourcePath string        `json:"sourcePath,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Summary    *EventSummary `json:"summary,omitempty"`
+}
+
+// An EventSummary counts the events of each type.
+type EventSummary struct {
+	DryRun  bool `json:"dryRun"`
+	Actions int  `json:"actions"`
+	Scripts int  `json:"scripts"`
+	Skipped int  `json:"skipped"`
+	Errors  int  `json:"errors"`
+}
+
+// An EventMutator wraps a Mutator and reports every change as an Event.
+type EventMutator struct {
+	m    Mutator
+	emit func(*Event)
+}
+
+// NewEventMutator returns a new EventMutator that makes changes with m and
+// passes an Event for each change to emit.
+func NewEventMutator(m Mutator, emit func(*Event)) *EventMutator {
+	return &EventMutator{
+		m:    m,
+		emit: emit,
+	}
+}
+
+// Chmod implements Mutator.Chmod.
+func (m *EventMutator) Chmod(name string, mode os.FileMode) error {
+	return m.action(PlanActionChmod, name, "", m.m.Chmod(name, mode))
+}
+
+// Chown implements Mutator.Chown.
+func (m *EventMutator) Chown(name string, uid, gid int) error {
+	return m.action(PlanActionChown, name, "", m.m.Chown(name, uid, gid))
+}
+
+// IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
+func (m *EventMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	return m.m.IdempotentCmdOutput(cmd)
+}
+
+// Mkdir implements Mutator.Mkdir.
+func (m *EventMutator) Mkdir(name string, perm os.FileMode) error {
+	return m.action(PlanActionMkdir, name, "", m.m.Mkdir(name, perm))
+}
+
+// RemoveAll implements Mutator.RemoveAll.
+func (m *EventMutator) RemoveAll(name string) error {
+	return m.action(PlanActionRemoveAll, name, "", m.m.RemoveAll(name))
+}
+
+// Rename implements Mutator.Rename.
+func (m *EventMutator) Rename(oldpath, newpath string) error {
+	return m.action(PlanActionRename, oldpath, newpath, m.m.Rename(oldpath, newpath))
+}
+
+// RunCmd implements Mutator.RunCmd.
+func (m *EventMutator) RunCmd(cmd *exec.Cmd) error {
+	return m.action(EventActionRunCmd, cmd.Path, "", m.m.RunCmd(cmd))
+}
+
+// Stat implements Mutator.Stat.
+func (m *EventMutator) Stat(name string) (os.FileInfo, error) {
+	return m.m.Stat(name)
+}
+
+// WriteFile implements Mutator.WriteFile.
+func (m *EventMutator) WriteFile(name string, data []byte, perm os.FileMode, currData []byte) error {
+	return m.action(PlanActionWriteFile, name, "", m.m.WriteFile(name, data, perm, currData))
+}
+
+// WriteSymlink implements Mutator.WriteSymlink.
+func (m *EventMutator) WriteSymlink(oldname, newname string) error {
+	return m.action(PlanActionWriteSymlink, newname, "", m.m.WriteSymlink(oldname, newname))
+}
+
+// action emits an event for action on targetPath, which failed if err is not
+// nil, and returns err.
+func (m *EventMutator) action(action, targetPath, newPath string, err error) error {
+	event := &Event{
+		Type:       EventTypeAction,
+		Action:     action,
+		TargetPath: targetPath,
+		NewPath:    newPath,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	m.emit(event)
+	return err
+}
@@ -0,0 +1,49 @@
+package chezmoi
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+var _ Mutator = &EventMutator{}
+
+func TestEventMutator(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": &vfst.Dir{Perm: 0o755},
+	})
+	assert.NoError(t, err)
+	defer cleanup()
+
+	var events []*Event
+	m := NewEventMutator(NewFSMutator(fs), func(event *Event) {
+		events = append(events, event)
+	})
+	assert.NoError(t, m.WriteFile("/home/user/file", []byte("# contents of file\n"), 0o644, nil))
+	assert.NoError(t, m.Rename("/home/user/file", "/home/user/renamed"))
+	err = m.Chmod("/home/user/file", 0o600)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	assert.Equal(t, []*Event{
+		{
+			Type:       EventTypeAction,
+			Action:     PlanActionWriteFile,
+			TargetPath: "/home/user/file",
+		},
+		{
+			Type:       EventTypeAction,
+			Action:     PlanActionRename,
+			TargetPath: "/home/user/file",
+			NewPath:    "/home/user/renamed",
+		},
+		{
+			Type:       EventTypeAction,
+			Action:     PlanActionChmod,
+			TargetPath: "/home/user/file",
+			Error:      err.Error(),
+		},
+	}, events)
+}
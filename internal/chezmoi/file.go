@@ -28,6 +28,7 @@ type File struct {
 	Encrypted        bool
 	Perm             os.FileMode
 	Template         bool
+	Ownership        Ownership
 	contents         []byte
 	contentsErr      error
 	evaluateContents func() ([]byte, error)
@@ -40,6 +41,8 @@ type fileConcreteValue struct {
 	Empty      bool   `json:"empty" yaml:"empty"`
 	Encrypted  bool   `json:"encrypted" yaml:"encrypted"`
 	Perm       int    `json:"perm" yaml:"perm"`
+	Owner      string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Group      string `json:"group,omitempty" yaml:"group,omitempty"`
 	Template   bool   `json:"template" yaml:"template"`
 	Contents   string `json:"contents" yaml:"contents"`
 }
@@ -132,7 +135,7 @@ func (f *File) AppendAllEntries(allEntries []Entry) []Entry {
 
 // Apply ensures that the state of targetPath in fs matches f.
 func (f *File) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *ApplyOptions) error {
-	if applyOptions.Ignore(f.targetName) {
+	if applyOptions.Ignore(f.targetName) || !applyOptions.Filter.IncludeEntry(f) {
 		return nil
 	}
 	contents, err := f.Contents()
@@ -164,7 +167,7 @@ func (f *File) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Appl
 				return err
 			}
 		}
-		return nil
+		return f.Ownership.apply(mutator, targetPath, info)
 	case err == nil:
 		if err := mutator.RemoveAll(targetPath); err != nil {
 			return err
@@ -176,12 +179,15 @@ func (f *File) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Appl
 	if isEmpty(contents) && !f.Empty {
 		return nil
 	}
-	return mutator.WriteFile(targetPath, contents, f.Perm&^applyOptions.Umask, currData)
+	if err := mutator.WriteFile(targetPath, contents, f.Perm&^applyOptions.Umask, currData); err != nil {
+		return err
+	}
+	return f.Ownership.apply(mutator, targetPath, nil)
 }
 
 // ConcreteValue implements Entry.ConcreteValue.
-func (f *File) ConcreteValue(ignore func(string) bool, sourceDir string, umask os.FileMode, recursive bool) (interface{}, error) {
-	if ignore(f.targetName) {
+func (f *File) ConcreteValue(ignore func(string) bool, filter *EntryTypeFilter, sourceDir func(Entry) string, umask os.FileMode, recursive bool) (interface{}, error) {
+	if ignore(f.targetName) || !filter.IncludeEntry(f) {
 		return nil, nil
 	}
 	contents, err := f.Contents()
@@ -190,11 +196,13 @@ func (f *File) ConcreteValue(ignore func(string) bool, sourceDir string, umask o
 	}
 	return &fileConcreteValue{
 		Type:       "file",
-		SourcePath: filepath.Join(sourceDir, f.SourceName()),
+		SourcePath: filepath.Join(sourceDir(f), f.SourceName()),
 		TargetPath: f.TargetName(),
 		Empty:      f.Empty,
 		Encrypted:  f.Encrypted,
 		Perm:       int(f.Perm &^ umask),
+		Owner:      f.Ownership.Owner,
+		Group:      f.Ownership.Group,
 		Template:   f.Template,
 		Contents:   string(contents),
 	}, nil
@@ -239,8 +247,8 @@ func (f *File) TargetName() string {
 }
 
 // archive writes f to w.
-func (f *File) archive(w *tar.Writer, ignore func(string) bool, headerTemplate *tar.Header, umask os.FileMode) error {
-	if ignore(f.targetName) {
+func (f *File) archive(w *tar.Writer, ignore func(string) bool, filter *EntryTypeFilter, headerTemplate *tar.Header, umask os.FileMode) error {
+	if ignore(f.targetName) || !filter.IncludeEntry(f) {
 		return nil
 	}
 	contents, err := f.Contents()
@@ -1,6 +1,7 @@
 package chezmoi
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -17,14 +18,17 @@ import (
 type GitDiffMutator struct {
 	m              Mutator
 	prefix         string
+	redactor       *Redactor
 	unifiedEncoder *diff.UnifiedEncoder
 }
 
-// NewGitDiffMutator returns a new GitDiffMutator.
-func NewGitDiffMutator(unifiedEncoder *diff.UnifiedEncoder, m Mutator, prefix string) *GitDiffMutator {
+// NewGitDiffMutator returns a new GitDiffMutator. Secrets known to redactor are
+// masked in its diffs.
+func NewGitDiffMutator(unifiedEncoder *diff.UnifiedEncoder, m Mutator, prefix string, redactor *Redactor) *GitDiffMutator {
 	return &GitDiffMutator{
 		m:              m,
 		prefix:         prefix,
+		redactor:       redactor,
 		unifiedEncoder: unifiedEncoder,
 	}
 }
@@ -59,6 +63,32 @@ func (m *GitDiffMutator) Chmod(name string, mode os.FileMode) error {
 	})
 }
 
+// Chown implements Mutator.Chown. git diffs do not include owners and groups,
+// so the change is written as extended header lines in the style of mode
+// changes.
+func (m *GitDiffMutator) Chown(name string, uid, gid int) error {
+	// Targets that do not exist yet would be created by the current user.
+	currUID, currGID := os.Geteuid(), os.Getegid()
+	if info, err := m.m.Stat(name); err == nil {
+		if infoUID, infoGID, ok := fileOwnership(info); ok {
+			currUID, currGID = infoUID, infoGID
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	newUID, newGID := uid, gid
+	if newUID == -1 {
+		newUID = currUID
+	}
+	if newGID == -1 {
+		newGID = currGID
+	}
+	path := m.trimPrefix(name)
+	return m.unifiedEncoder.Encode(&gitDiffPatch{
+		message: fmt.Sprintf("diff --git a/%s b/%s\nold owner %d:%d\nnew owner %d:%d\n", path, path, currUID, currGID, newUID, newGID),
+	})
+}
+
 // IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
 func (m *GitDiffMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
 	return m.m.IdempotentCmdOutput(cmd)
@@ -144,6 +174,10 @@ func (m *GitDiffMutator) WriteFile(filename string, data []byte, perm os.FileMod
 		return err
 	}
 	path := m.trimPrefix(filename)
+	// Hashes are computed from the redacted data too, as the hash of a short
+	// secret could be used to recover it.
+	currData = m.redactor.Redact(currData)
+	data = m.redactor.Redact(data)
 	isBinary := isBinary(currData) || isBinary(data)
 	var chunks []diff.Chunk
 	if !isBinary {
@@ -171,6 +205,7 @@ func (m *GitDiffMutator) WriteFile(filename string, data []byte, perm os.FileMod
 
 // WriteSymlink implements Mutator.WriteSymlink.
 func (m *GitDiffMutator) WriteSymlink(oldname, newname string) error {
+	oldname = m.redactor.RedactString(oldname)
 	return m.unifiedEncoder.Encode(&gitDiffPatch{
 		filePatches: []diff.FilePatch{
 			&gitDiffFilePatch{
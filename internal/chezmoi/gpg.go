@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 )
 
 // GPG interfaces with gpg.
@@ -12,11 +13,18 @@ type GPG struct {
 	Command   string
 	Recipient string
 	Symmetric bool
+
+	// mutex ensures that only one gpg runs at a time, as gpg may prompt for a
+	// passphrase and files may be decrypted concurrently.
+	mutex sync.Mutex
 }
 
 // Decrypt decrypts ciphertext. filename is used as a hint for naming temporary
 // files.
 func (g *GPG) Decrypt(filename string, ciphertext []byte) ([]byte, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
 	tempDir, err := ioutil.TempDir("", "chezmoi-decrypt")
 	if err != nil {
 		return nil, err
@@ -49,6 +57,9 @@ func (g *GPG) Decrypt(filename string, ciphertext []byte) ([]byte, error) {
 // Encrypt encrypts plaintext for ts's recipient. filename is used as a hint for
 // naming temporary files.
 func (g *GPG) Encrypt(filename string, plaintext []byte) ([]byte, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
 	tempDir, err := ioutil.TempDir("", "chezmoi-encrypt")
 	if err != nil {
 		return nil, err
@@ -74,7 +85,7 @@ func (g *GPG) Encrypt(filename string, plaintext []byte) ([]byte, error) {
 		}
 		args = append(args, "--encrypt")
 	}
-	args = append(args, filename)
+	args = append(args, inputFilename)
 
 	//nolint:gosec
 	cmd := exec.Command(g.Command, args...)
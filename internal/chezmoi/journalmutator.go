@@ -0,0 +1,255 @@
+package chezmoi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	vfs "github.com/twpayne/go-vfs"
+)
+
+// A JournalMutator wraps a Mutator and records the state of every path before
+// it is changed, so that all changes can be reverted.
+type JournalMutator struct {
+	m       Mutator
+	fs      vfs.FS
+	journal *Journal
+}
+
+// A Journal records the state of paths before they were changed, in the order
+// in which they were changed.
+type Journal struct {
+	Entries []*JournalEntry `json:"entries"`
+}
+
+// A JournalEntry records the state of a path. If Entries is nil then the
+// contents of directories are not recorded, as they were not changed.
+type JournalEntry struct {
+	Path     string                   `json:"path"`
+	Exists   bool                     `json:"exists"`
+	Mode     os.FileMode              `json:"mode,omitempty"`
+	Contents []byte                   `json:"contents,omitempty"`
+	Linkname string                   `json:"linkname,omitempty"`
+	Entries  map[string]*JournalEntry `json:"entries,omitempty"`
+}
+
+// NewJournalMutator returns a new JournalMutator that makes changes with m and
+// reads the state of paths from fs.
+func NewJournalMutator(m Mutator, fs vfs.FS) *JournalMutator {
+	return &JournalMutator{
+		m:       m,
+		fs:      fs,
+		journal: &Journal{},
+	}
+}
+
+// Chmod implements Mutator.Chmod.
+func (m *JournalMutator) Chmod(name string, mode os.FileMode) error {
+	return m.record(func() error {
+		return m.m.Chmod(name, mode)
+	}, name, false)
+}
+
+// Chown implements Mutator.Chown. Owners and groups are not recorded, so
+// reverting restores the state of name but not its ownership.
+func (m *JournalMutator) Chown(name string, uid, gid int) error {
+	return m.record(func() error {
+		return m.m.Chown(name, uid, gid)
+	}, name, false)
+}
+
+// IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
+func (m *JournalMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	return m.m.IdempotentCmdOutput(cmd)
+}
+
+// Journal returns the changes made by m.
+func (m *JournalMutator) Journal() *Journal {
+	return m.journal
+}
+
+// Mkdir implements Mutator.Mkdir.
+func (m *JournalMutator) Mkdir(name string, perm os.FileMode) error {
+	return m.record(func() error {
+		return m.m.Mkdir(name, perm)
+	}, name, false)
+}
+
+// RemoveAll implements Mutator.RemoveAll.
+func (m *JournalMutator) RemoveAll(name string) error {
+	return m.record(func() error {
+		return m.m.RemoveAll(name)
+	}, name, true)
+}
+
+// Rename implements Mutator.Rename.
+func (m *JournalMutator) Rename(oldpath, newpath string) error {
+	return m.record(func() error {
+		return m.m.Rename(oldpath, newpath)
+	}, oldpath, true, newpath)
+}
+
+// RunCmd implements Mutator.RunCmd. The effects of commands are not recorded
+// and cannot be reverted.
+func (m *JournalMutator) RunCmd(cmd *exec.Cmd) error {
+	return m.m.RunCmd(cmd)
+}
+
+// Stat implements Mutator.Stat.
+func (m *JournalMutator) Stat(name string) (os.FileInfo, error) {
+	return m.m.Stat(name)
+}
+
+// WriteFile implements Mutator.WriteFile.
+func (m *JournalMutator) WriteFile(name string, data []byte, perm os.FileMode, currData []byte) error {
+	return m.record(func() error {
+		return m.m.WriteFile(name, data, perm, currData)
+	}, name, false)
+}
+
+// WriteSymlink implements Mutator.WriteSymlink.
+func (m *JournalMutator) WriteSymlink(oldname, newname string) error {
+	return m.record(func() error {
+		return m.m.WriteSymlink(oldname, newname)
+	}, newname, false)
+}
+
+// Rollback reverts all changes recorded in j, in reverse order, using m. fs is
+// used to read the current state of paths.
+func (j *Journal) Rollback(fs vfs.FS, m Mutator) error {
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		if err := j.Entries[i].restore(fs, m); err != nil {
+			return fmt.Errorf("%s: %w", j.Entries[i].Path, err)
+		}
+	}
+	return nil
+}
+
+// record records the state of names, including the contents of directories if
+// recursive is true, and then calls f. If f fails then nothing was changed and
+// the records are discarded.
+func (m *JournalMutator) record(f func() error, name string, recursive bool, names ...string) error {
+	n := len(m.journal.Entries)
+	for _, name := range append([]string{name}, names...) {
+		entry, err := newJournalEntry(m.fs, name, recursive)
+		if err != nil {
+			return err
+		}
+		m.journal.Entries = append(m.journal.Entries, entry)
+	}
+	if err := f(); err != nil {
+		m.journal.Entries = m.journal.Entries[:n]
+		return err
+	}
+	return nil
+}
+
+// newJournalEntry returns the state of name in fs.
+func newJournalEntry(fs vfs.FS, name string, recursive bool) (*JournalEntry, error) {
+	info, err := fs.Lstat(name)
+	switch {
+	case os.IsNotExist(err):
+		return &JournalEntry{
+			Path: name,
+		}, nil
+	case err != nil:
+		return nil, err
+	}
+	entry := &JournalEntry{
+		Path:   name,
+		Exists: true,
+		Mode:   info.Mode(),
+	}
+	switch {
+	case info.Mode().IsRegular():
+		entry.Contents, err = fs.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+	case info.Mode()&os.ModeType == os.ModeSymlink:
+		entry.Linkname, err = fs.Readlink(name)
+		if err != nil {
+			return nil, err
+		}
+	case info.IsDir() && recursive:
+		infos, err := fs.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		entry.Entries = make(map[string]*JournalEntry, len(infos))
+		for _, info := range infos {
+			childEntry, err := newJournalEntry(fs, filepath.Join(name, info.Name()), true)
+			if err != nil {
+				return nil, err
+			}
+			entry.Entries[info.Name()] = childEntry
+		}
+	}
+	return entry, nil
+}
+
+// restore makes the state of e.Path match e.
+func (e *JournalEntry) restore(fs vfs.FS, m Mutator) error {
+	info, err := fs.Lstat(e.Path)
+	switch {
+	case os.IsNotExist(err):
+		info = nil
+	case err != nil:
+		return err
+	}
+
+	if !e.Exists {
+		if info == nil {
+			return nil
+		}
+		return m.RemoveAll(e.Path)
+	}
+
+	// If only the directory itself was recorded and it still exists, then only
+	// its permissions need to be restored.
+	if e.Mode.IsDir() && e.Entries == nil && info != nil && info.IsDir() {
+		if info.Mode().Perm() == e.Mode.Perm() {
+			return nil
+		}
+		return m.Chmod(e.Path, e.Mode.Perm())
+	}
+
+	if info != nil {
+		if err := m.RemoveAll(e.Path); err != nil {
+			return err
+		}
+	}
+	switch {
+	case e.Mode.IsRegular():
+		if err := m.WriteFile(e.Path, e.Contents, e.Mode.Perm(), nil); err != nil {
+			return err
+		}
+		return m.Chmod(e.Path, e.Mode.Perm())
+	case e.Mode&os.ModeType == os.ModeSymlink:
+		return m.WriteSymlink(e.Linkname, e.Path)
+	case e.Mode.IsDir():
+		if err := m.Mkdir(e.Path, e.Mode.Perm()); err != nil {
+			return err
+		}
+		for _, name := range sortedJournalEntryNames(e.Entries) {
+			if err := e.Entries[name].restore(fs, m); err != nil {
+				return err
+			}
+		}
+		return m.Chmod(e.Path, e.Mode.Perm())
+	default:
+		return fmt.Errorf("unsupported mode %s", e.Mode)
+	}
+}
+
+// sortedJournalEntryNames returns the sorted names of entries.
+func sortedJournalEntryNames(entries map[string]*JournalEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,100 @@
+package chezmoi
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+var _ Mutator = &JournalMutator{}
+
+func TestJournalMutatorRollback(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			"dir": map[string]interface{}{
+				"file": "# contents of dir/file\n",
+			},
+			"file":    "# contents of file\n",
+			"private": &vfst.Dir{Perm: 0o700},
+			"symlink": &vfst.Symlink{Target: "file"},
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	m := NewJournalMutator(NewFSMutator(fs), fs)
+	assert.NoError(t, m.WriteFile("/home/user/file", []byte("# new contents of file\n"), 0o644, nil))
+	assert.NoError(t, m.RemoveAll("/home/user/dir"))
+	assert.NoError(t, m.Chmod("/home/user/private", 0o755))
+	assert.NoError(t, m.Rename("/home/user/symlink", "/home/user/renamed"))
+	assert.NoError(t, m.Mkdir("/home/user/newdir", 0o755))
+	assert.NoError(t, m.WriteFile("/home/user/newdir/file", []byte("# contents of newdir/file\n"), 0o644, nil))
+	assert.NoError(t, m.WriteSymlink("newdir", "/home/user/newsymlink"))
+	assert.Error(t, m.RunCmd(exec.Command("false")))
+
+	// Failed operations are not recorded.
+	numEntries := len(m.Journal().Entries)
+	assert.Error(t, m.Mkdir("/home/user/missing/dir", 0o755))
+	assert.Equal(t, numEntries, len(m.Journal().Entries))
+
+	// Journals are stored as JSON, so check that they survive a round trip.
+	data, err := json.Marshal(m.Journal())
+	require.NoError(t, err)
+	var journal Journal
+	require.NoError(t, json.Unmarshal(data, &journal))
+	require.NoError(t, journal.Rollback(fs, NewFSMutator(fs)))
+
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/dir",
+			vfst.TestIsDir,
+		),
+		vfst.TestPath("/home/user/dir/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of dir/file\n"),
+		),
+		vfst.TestPath("/home/user/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of file\n"),
+		),
+		vfst.TestPath("/home/user/private",
+			vfst.TestIsDir,
+			vfst.TestModePerm(0o700),
+		),
+		vfst.TestPath("/home/user/symlink",
+			vfst.TestModeType(os.ModeSymlink),
+			vfst.TestSymlinkTarget("file"),
+		),
+		vfst.TestPath("/home/user/renamed",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/home/user/newdir",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/home/user/newsymlink",
+			vfst.TestDoesNotExist,
+		),
+	)
+}
+
+func TestJournalRollbackError(t *testing.T) {
+	journal := &Journal{
+		Entries: []*JournalEntry{
+			{
+				Path:   "/home/user/file",
+				Exists: true,
+				Mode:   os.ModeNamedPipe,
+			},
+		},
+	}
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+	assert.Error(t, journal.Rollback(fs, NewFSMutator(fs)))
+}
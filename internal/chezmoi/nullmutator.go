@@ -13,6 +13,11 @@ func (NullMutator) Chmod(string, os.FileMode) error {
 	return nil
 }
 
+// Chown implements Mutator.Chown.
+func (NullMutator) Chown(string, int, int) error {
+	return nil
+}
+
 // IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
 func (NullMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
 	return cmd.Output()
@@ -0,0 +1,206 @@
+package chezmoi
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+// An Ownership is the owner and group of a target, each either a name or a
+// numeric ID. Empty fields are not managed.
+type Ownership struct {
+	Owner string
+	Group string
+}
+
+// An AttributeSet is an ordered list of patterns and the ownership of the
+// targets that match them.
+type AttributeSet struct {
+	rules []attributeRule
+}
+
+type attributeRule struct {
+	pattern   string
+	ownership Ownership
+}
+
+// NewAttributeSet returns a new AttributeSet.
+func NewAttributeSet() *AttributeSet {
+	return &AttributeSet{}
+}
+
+// Add adds a pattern to as. ownership overrides the ownership set by earlier
+// patterns.
+func (as *AttributeSet) Add(pattern string, ownership Ownership) error {
+	// Matching an empty name does not check the pattern's syntax.
+	if _, err := doublestar.PathMatch(pattern, pattern); err != nil {
+		return err
+	}
+	as.rules = append(as.rules, attributeRule{
+		pattern:   pattern,
+		ownership: ownership,
+	})
+	return nil
+}
+
+// Ownership returns the ownership of name in as.
+func (as *AttributeSet) Ownership(name string) Ownership {
+	var ownership Ownership
+	for _, rule := range as.rules {
+		if ok, _ := doublestar.PathMatch(rule.pattern, name); !ok {
+			continue
+		}
+		if rule.ownership.Owner != "" {
+			ownership.Owner = rule.ownership.Owner
+		}
+		if rule.ownership.Group != "" {
+			ownership.Group = rule.ownership.Group
+		}
+	}
+	return ownership
+}
+
+// IsZero returns true if o does not manage the owner or the group.
+func (o Ownership) IsZero() bool {
+	return o.Owner == "" && o.Group == ""
+}
+
+// String returns the attributes of o in a .chezmoiattributes file.
+func (o Ownership) String() string {
+	var attributes []string
+	if o.Owner != "" {
+		attributes = append(attributes, "owner="+o.Owner)
+	}
+	if o.Group != "" {
+		attributes = append(attributes, "group="+o.Group)
+	}
+	return strings.Join(attributes, " ")
+}
+
+// apply changes the owner and group of targetPath with mutator to match o.
+// info is the state of targetPath, or nil if targetPath was just written by
+// mutator and so is owned by the current user.
+func (o Ownership) apply(mutator Mutator, targetPath string, info os.FileInfo) error {
+	if o.IsZero() {
+		return nil
+	}
+	uid, gid, err := o.ids()
+	if err != nil {
+		return fmt.Errorf("%s: %w", targetPath, err)
+	}
+	currUID, currGID := os.Geteuid(), os.Getegid()
+	if info != nil {
+		if infoUID, infoGID, ok := fileOwnership(info); ok {
+			currUID, currGID = infoUID, infoGID
+		}
+	}
+	if uid == currUID {
+		uid = -1
+	}
+	if gid == currGID {
+		gid = -1
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return mutator.Chown(targetPath, uid, gid)
+}
+
+// ids returns the numeric owner and group of o, or -1 if they are not
+// managed.
+func (o Ownership) ids() (int, int, error) {
+	uid, gid := -1, -1
+	if o.Owner != "" {
+		if id, err := strconv.Atoi(o.Owner); err == nil {
+			uid = id
+		} else {
+			u, err := user.Lookup(o.Owner)
+			if err != nil {
+				return -1, -1, err
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, err
+			}
+		}
+	}
+	if o.Group != "" {
+		if id, err := strconv.Atoi(o.Group); err == nil {
+			gid = id
+		} else {
+			g, err := user.LookupGroup(o.Group)
+			if err != nil {
+				return -1, -1, err
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, err
+			}
+		}
+	}
+	return uid, gid, nil
+}
+
+// groupName returns the name of the group gid, or gid if it has no name.
+func groupName(gid int) string {
+	name := strconv.Itoa(gid)
+	if g, err := user.LookupGroupId(name); err == nil {
+		return g.Name
+	}
+	return name
+}
+
+// userName returns the name of the user uid, or uid if they have no name.
+func userName(uid int) string {
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		return u.Username
+	}
+	return name
+}
+
+// escapePattern returns name escaped so that it only matches itself as a
+// pattern in a .chezmoiattributes file.
+func escapePattern(name string) string {
+	sb := &strings.Builder{}
+	for _, r := range name {
+		if strings.ContainsRune(`#*?[]{}\`, r) || unicode.IsSpace(r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// splitAttributeFields splits a line of a .chezmoiattributes file into fields
+// separated by unescaped whitespace, ignoring any comment.
+func splitAttributeFields(s string) []string {
+	var fields []string
+	sb := &strings.Builder{}
+	escaped := false
+FOR:
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '#':
+			break FOR
+		case unicode.IsSpace(r):
+			if sb.Len() != 0 {
+				fields = append(fields, sb.String())
+				sb.Reset()
+			}
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() != 0 {
+		fields = append(fields, sb.String())
+	}
+	return fields
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+package chezmoi
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwnership returns the numeric owner and group of info.
+func fileOwnership(info os.FileInfo) (int, int, bool) {
+	statT, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(statT.Uid), int(statT.Gid), true
+}
@@ -0,0 +1,49 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeSet(t *testing.T) {
+	as := NewAttributeSet()
+	require.NoError(t, as.Add("etc/**", Ownership{Owner: "root", Group: "root"}))
+	require.NoError(t, as.Add("etc/shadow", Ownership{Group: "shadow"}))
+	require.NoError(t, as.Add("srv/*", Ownership{Owner: "www"}))
+	assert.Error(t, as.Add("[", Ownership{Owner: "root"}))
+	for name, expectedOwnership := range map[string]Ownership{
+		"etc/hosts":  {Owner: "root", Group: "root"},
+		"etc/shadow": {Owner: "root", Group: "shadow"},
+		"srv/index":  {Owner: "www"},
+		"srv/a/b":    {},
+		"home":       {},
+	} {
+		assert.Equal(t, expectedOwnership, as.Ownership(name), name)
+	}
+}
+
+func TestSplitAttributeFields(t *testing.T) {
+	for _, tc := range []struct {
+		s              string
+		expectedFields []string
+	}{
+		{
+			s: "",
+		},
+		{
+			s: "# comment",
+		},
+		{
+			s:              "etc/hosts owner=root  group=root # comment",
+			expectedFields: []string{"etc/hosts", "owner=root", "group=root"},
+		},
+		{
+			s:              escapePattern("a b#c*") + " owner=root",
+			expectedFields: []string{`a\ b\#c\*`, "owner=root"},
+		},
+	} {
+		assert.Equal(t, tc.expectedFields, splitAttributeFields(tc.s), tc.s)
+	}
+}
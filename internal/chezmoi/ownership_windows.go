@@ -0,0 +1,13 @@
+// +build windows
+
+package chezmoi
+
+import (
+	"os"
+)
+
+// fileOwnership returns false as files do not have numeric owners and groups
+// on Windows.
+func fileOwnership(info os.FileInfo) (int, int, bool) {
+	return -1, -1, false
+}
@@ -0,0 +1,411 @@
+package chezmoi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	vfs "github.com/twpayne/go-vfs"
+)
+
+// PlanVersion is the version of the plan format written by chezmoi.
+const PlanVersion = 1
+
+// Plan actions.
+const (
+	PlanActionChmod        = "chmod"
+	PlanActionChown        = "chown"
+	PlanActionMkdir        = "mkdir"
+	PlanActionRemoveAll    = "removeAll"
+	PlanActionRename       = "rename"
+	PlanActionRunScript    = "runScript"
+	PlanActionWriteFile    = "writeFile"
+	PlanActionWriteSymlink = "writeSymlink"
+)
+
+// A Plan is a list of changes to be made to a destination directory, which
+// can be reviewed before it is executed.
+type Plan struct {
+	Version int         `json:"version"`
+	DestDir string      `json:"destDir"`
+	Steps   []*PlanStep `json:"steps"`
+}
+
+// A PlanStep is a single change. Prior is the expected state of Path before
+// the change is made, and NewPathPrior is the expected state of NewPath.
+type PlanStep struct {
+	Action       string      `json:"action"`
+	Path         string      `json:"path"`
+	NewPath      string      `json:"newPath,omitempty"`
+	Mode         os.FileMode `json:"mode,omitempty"`
+	Owner        *PlanOwner  `json:"owner,omitempty"`
+	Contents     []byte      `json:"contents,omitempty"`
+	SHA256       string      `json:"sha256,omitempty"`
+	Linkname     string      `json:"linkname,omitempty"`
+	SourceName   string      `json:"sourceName,omitempty"`
+	Once         bool        `json:"once,omitempty"`
+	Prior        *PlanState  `json:"prior,omitempty"`
+	NewPathPrior *PlanState  `json:"newPathPrior,omitempty"`
+}
+
+// A PlanOwner is the numeric owner and group set by a chown step. -1 leaves
+// the owner or group unchanged.
+type PlanOwner struct {
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+}
+
+// A PlanState is the state of a path. SHA256 is the hash of a file's contents
+// or, for directories that are removed, of all of the directory's contents.
+type PlanState struct {
+	Exists   bool        `json:"exists"`
+	Mode     os.FileMode `json:"mode,omitempty"`
+	SHA256   string      `json:"sha256,omitempty"`
+	Linkname string      `json:"linkname,omitempty"`
+}
+
+// A planTracker tracks the state of paths as a plan is made or executed,
+// without relying on changes having actually been made.
+type planTracker struct {
+	fs      vfs.FS
+	overlay map[string]*planTrackerEntry
+}
+
+// A planTrackerEntry is the state of a path after a step. fresh is true for
+// directories created by the plan, which are known to be empty.
+type planTrackerEntry struct {
+	state *PlanState
+	fresh bool
+}
+
+// NewPlan returns a new empty Plan for destDir.
+func NewPlan(destDir string) *Plan {
+	return &Plan{
+		Version: PlanVersion,
+		DestDir: destDir,
+	}
+}
+
+// Execute makes the changes in p with mutator, refusing any step whose
+// expected prior state does not match the current state in fs.
+func (p *Plan) Execute(fs vfs.FS, mutator Mutator, applyOptions *ApplyOptions) error {
+	if p.Version != PlanVersion {
+		return fmt.Errorf("unsupported plan version %d", p.Version)
+	}
+	if p.DestDir != applyOptions.DestDir {
+		return fmt.Errorf("plan is for %s, not %s", p.DestDir, applyOptions.DestDir)
+	}
+	t := newPlanTracker(fs)
+	for i, step := range p.Steps {
+		if err := p.executeStep(t, mutator, step, applyOptions); err != nil {
+			return fmt.Errorf("step %d: %s %s: %w", i+1, step.Action, step.Path, err)
+		}
+	}
+	return nil
+}
+
+// addScript adds a step that runs the script targetName.
+func (p *Plan) addScript(destDir, targetName, sourceName string, contents []byte, once bool) {
+	p.Steps = append(p.Steps, &PlanStep{
+		Action:     PlanActionRunScript,
+		Path:       filepath.Join(destDir, targetName),
+		Contents:   contents,
+		SHA256:     sha256Sum(contents),
+		SourceName: sourceName,
+		Once:       once,
+	})
+}
+
+// executeStep executes step.
+func (p *Plan) executeStep(t *planTracker, mutator Mutator, step *PlanStep, applyOptions *ApplyOptions) error {
+	if step.Contents != nil && sha256Sum(step.Contents) != step.SHA256 {
+		return fmt.Errorf("contents do not match sha256 %s", step.SHA256)
+	}
+	if err := t.check(step.Path, step.Prior); err != nil {
+		return err
+	}
+	if step.Action == PlanActionRename {
+		if err := t.check(step.NewPath, step.NewPathPrior); err != nil {
+			return fmt.Errorf("%s: %w", step.NewPath, err)
+		}
+	}
+	switch step.Action {
+	case PlanActionChmod:
+		if err := mutator.Chmod(step.Path, step.Mode); err != nil {
+			return err
+		}
+		return t.chmod(step.Path, step.Mode)
+	case PlanActionChown:
+		if step.Owner == nil {
+			return errors.New("no owner")
+		}
+		return mutator.Chown(step.Path, step.Owner.UID, step.Owner.GID)
+	case PlanActionMkdir:
+		if err := mutator.Mkdir(step.Path, step.Mode); err != nil {
+			return err
+		}
+		t.mkdir(step.Path, step.Mode)
+		return nil
+	case PlanActionRemoveAll:
+		if err := mutator.RemoveAll(step.Path); err != nil {
+			return err
+		}
+		t.removeAll(step.Path)
+		return nil
+	case PlanActionRename:
+		if err := mutator.Rename(step.Path, step.NewPath); err != nil {
+			return err
+		}
+		return t.rename(step.Path, step.NewPath)
+	case PlanActionRunScript:
+		targetName, err := filepath.Rel(p.DestDir, step.Path)
+		if err != nil {
+			return err
+		}
+		// run_once_ scripts may have been run since the plan was made, or
+		// by an earlier execution of the same plan.
+		var key []byte
+		if step.Once {
+			key = scriptStateKey(targetName, step.Contents)
+			scriptStateData, err := applyOptions.PersistentState.Get(applyOptions.ScriptStateBucket, key)
+			if err != nil {
+				return err
+			}
+			if scriptStateData != nil {
+				return nil
+			}
+		}
+		return runScript(targetName, step.SourceName, step.Contents, key, applyOptions)
+	case PlanActionWriteFile:
+		var currData []byte
+		if step.Prior != nil && step.Prior.Exists && step.Prior.Mode.IsRegular() {
+			// The prior state has been checked, so the file is known to
+			// exist and to be unchanged.
+			var err error
+			currData, err = t.fs.ReadFile(step.Path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := mutator.WriteFile(step.Path, step.Contents, step.Mode, currData); err != nil {
+			return err
+		}
+		t.writeFile(step.Path, step.Contents, step.Mode)
+		return nil
+	case PlanActionWriteSymlink:
+		if err := mutator.WriteSymlink(step.Linkname, step.Path); err != nil {
+			return err
+		}
+		t.writeSymlink(step.Path, step.Linkname)
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// newPlanTracker returns a new planTracker that reads states from fs.
+func newPlanTracker(fs vfs.FS) *planTracker {
+	return &planTracker{
+		fs:      fs,
+		overlay: make(map[string]*planTrackerEntry),
+	}
+}
+
+// check returns an error if the state of name does not match expected. A nil
+// expected state matches any state.
+func (t *planTracker) check(name string, expected *PlanState) error {
+	if expected == nil {
+		return nil
+	}
+	actual, err := t.get(name, expected.Mode.IsDir() && expected.SHA256 != "")
+	if err != nil {
+		return err
+	}
+	if *actual != *expected {
+		return fmt.Errorf("precondition failed: expected %s, found %s", expected, actual)
+	}
+	return nil
+}
+
+// chmod records that the mode of name was changed to mode.
+func (t *planTracker) chmod(name string, mode os.FileMode) error {
+	entry, err := t.getEntry(name, false)
+	if err != nil {
+		return err
+	}
+	state := *entry.state
+	state.Mode = state.Mode&^os.ModePerm | mode.Perm()
+	t.overlay[name] = &planTrackerEntry{
+		state: &state,
+		fresh: entry.fresh,
+	}
+	return nil
+}
+
+// get returns the state of name. If hashDirs is true then the state of
+// directories includes a hash of their contents.
+func (t *planTracker) get(name string, hashDirs bool) (*PlanState, error) {
+	entry, err := t.getEntry(name, hashDirs)
+	if err != nil {
+		return nil, err
+	}
+	return entry.state, nil
+}
+
+// getEntry returns the state of name, taking into account all changes
+// recorded so far.
+func (t *planTracker) getEntry(name string, hashDirs bool) (*planTrackerEntry, error) {
+	if entry, ok := t.overlay[name]; ok {
+		return entry, nil
+	}
+	for dir := filepath.Dir(name); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		entry, ok := t.overlay[dir]
+		if !ok {
+			continue
+		}
+		if !entry.state.Exists || !entry.state.Mode.IsDir() || entry.fresh {
+			return &planTrackerEntry{
+				state: &PlanState{},
+			}, nil
+		}
+	}
+	// Directories whose contents have already been changed cannot be hashed
+	// consistently.
+	if hashDirs {
+		prefix := name + string(os.PathSeparator)
+		for path := range t.overlay {
+			if strings.HasPrefix(path, prefix) {
+				hashDirs = false
+				break
+			}
+		}
+	}
+	state, err := readPlanState(t.fs, name, hashDirs)
+	if err != nil {
+		return nil, err
+	}
+	return &planTrackerEntry{
+		state: state,
+	}, nil
+}
+
+// mkdir records that the directory name was created.
+func (t *planTracker) mkdir(name string, perm os.FileMode) {
+	t.overlay[name] = &planTrackerEntry{
+		state: &PlanState{
+			Exists: true,
+			Mode:   os.ModeDir | perm.Perm(),
+		},
+		fresh: true,
+	}
+}
+
+// removeAll records that name and all its contents were removed.
+func (t *planTracker) removeAll(name string) {
+	t.overlay[name] = &planTrackerEntry{
+		state: &PlanState{},
+	}
+}
+
+// rename records that oldpath was renamed to newpath.
+func (t *planTracker) rename(oldpath, newpath string) error {
+	entry, err := t.getEntry(oldpath, false)
+	if err != nil {
+		return err
+	}
+	t.overlay[newpath] = entry
+	t.removeAll(oldpath)
+	return nil
+}
+
+// writeFile records that the file name was written with contents.
+func (t *planTracker) writeFile(name string, contents []byte, perm os.FileMode) {
+	t.overlay[name] = &planTrackerEntry{
+		state: &PlanState{
+			Exists: true,
+			Mode:   perm.Perm(),
+			SHA256: sha256Sum(contents),
+		},
+	}
+}
+
+// writeSymlink records that the symlink name was written pointing to
+// linkname.
+func (t *planTracker) writeSymlink(name, linkname string) {
+	t.overlay[name] = &planTrackerEntry{
+		state: &PlanState{
+			Exists:   true,
+			Mode:     os.ModeSymlink,
+			Linkname: linkname,
+		},
+	}
+}
+
+func (s *PlanState) String() string {
+	switch {
+	case !s.Exists:
+		return "no such file or directory"
+	case s.Mode.IsRegular():
+		return fmt.Sprintf("file %s with sha256 %s", s.Mode, s.SHA256)
+	case s.Mode&os.ModeType == os.ModeSymlink:
+		return fmt.Sprintf("symlink to %s", s.Linkname)
+	case s.Mode.IsDir() && s.SHA256 != "":
+		return fmt.Sprintf("directory %s with sha256 %s", s.Mode, s.SHA256)
+	default:
+		return s.Mode.String()
+	}
+}
+
+// readPlanState returns the state of name in fs. If hashDirs is true then the
+// state of directories includes a hash of their contents.
+func readPlanState(fs vfs.FS, name string, hashDirs bool) (*PlanState, error) {
+	info, err := fs.Lstat(name)
+	switch {
+	case os.IsNotExist(err):
+		return &PlanState{}, nil
+	case err != nil:
+		return nil, err
+	}
+	state := &PlanState{
+		Exists: true,
+		Mode:   info.Mode(),
+	}
+	switch {
+	case info.Mode().IsRegular():
+		contents, err := fs.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		state.SHA256 = sha256Sum(contents)
+	case info.Mode()&os.ModeType == os.ModeSymlink:
+		state.Linkname, err = fs.Readlink(name)
+		if err != nil {
+			return nil, err
+		}
+	case info.IsDir() && hashDirs:
+		h := sha256.New()
+		infos, err := fs.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			childState, err := readPlanState(fs, filepath.Join(name, info.Name()), true)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00", info.Name(), childState.Mode, childState.SHA256, childState.Linkname)
+		}
+		state.SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+	return state, nil
+}
+
+// sha256Sum returns the hex-encoded SHA256 sum of data.
+func sha256Sum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package chezmoi
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-vfs/vfst"
+)
+
+var _ Mutator = &PlanMutator{}
+
+func TestPlan(t *testing.T) {
+	root := map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			"dir":     "# contents of dir\n",
+			"exact":   map[string]interface{}{"file": "# contents of exact/file\n"},
+			"file":    "# contents of file\n",
+			"private": &vfst.Dir{Perm: 0o755},
+		},
+	}
+	plan := func(fs *vfst.TestFS) *Plan {
+		plan := NewPlan("/home/user")
+		m := NewPlanMutator(fs, plan)
+		require.NoError(t, m.RemoveAll("/home/user/dir"))
+		require.NoError(t, m.Mkdir("/home/user/dir", 0o755))
+		require.NoError(t, m.WriteFile("/home/user/dir/file", []byte("# contents of dir/file\n"), 0o644, nil))
+		require.NoError(t, m.RemoveAll("/home/user/exact"))
+		require.NoError(t, m.WriteFile("/home/user/file", []byte("# new contents of file\n"), 0o644, nil))
+		require.NoError(t, m.Chmod("/home/user/private", 0o700))
+		require.NoError(t, m.WriteSymlink("file", "/home/user/symlink"))
+
+		// Plans are stored as JSON, so check that they survive a round trip.
+		data, err := json.Marshal(plan)
+		require.NoError(t, err)
+		var result Plan
+		require.NoError(t, json.Unmarshal(data, &result))
+		return &result
+	}
+
+	t.Run("execute", func(t *testing.T) {
+		fs, cleanup, err := vfst.NewTestFS(root)
+		require.NoError(t, err)
+		defer cleanup()
+
+		p := plan(fs)
+		assert.Len(t, p.Steps, 7)
+		require.NoError(t, p.Execute(fs, NewFSMutator(fs), &ApplyOptions{DestDir: "/home/user"}))
+
+		vfst.RunTests(t, fs, "",
+			vfst.TestPath("/home/user/dir/file",
+				vfst.TestModeIsRegular,
+				vfst.TestContentsString("# contents of dir/file\n"),
+			),
+			vfst.TestPath("/home/user/exact",
+				vfst.TestDoesNotExist,
+			),
+			vfst.TestPath("/home/user/file",
+				vfst.TestModeIsRegular,
+				vfst.TestContentsString("# new contents of file\n"),
+			),
+			vfst.TestPath("/home/user/private",
+				vfst.TestIsDir,
+				vfst.TestModePerm(0o700),
+			),
+			vfst.TestPath("/home/user/symlink",
+				vfst.TestModeType(os.ModeSymlink),
+				vfst.TestSymlinkTarget("file"),
+			),
+		)
+	})
+
+	t.Run("precondition_failed", func(t *testing.T) {
+		fs, cleanup, err := vfst.NewTestFS(root)
+		require.NoError(t, err)
+		defer cleanup()
+
+		p := plan(fs)
+		require.NoError(t, fs.WriteFile("/home/user/exact/file2", []byte("# contents of exact/file2\n"), 0o644))
+		err = p.Execute(fs, NewFSMutator(fs), &ApplyOptions{DestDir: "/home/user"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "step 4: removeAll /home/user/exact: precondition failed")
+
+		vfst.RunTests(t, fs, "",
+			vfst.TestPath("/home/user/exact/file2",
+				vfst.TestModeIsRegular,
+			),
+			vfst.TestPath("/home/user/file",
+				vfst.TestContentsString("# contents of file\n"),
+			),
+		)
+	})
+
+	t.Run("tampered_contents", func(t *testing.T) {
+		fs, cleanup, err := vfst.NewTestFS(root)
+		require.NoError(t, err)
+		defer cleanup()
+
+		p := plan(fs)
+		p.Steps[2].Contents = []byte("# tampered contents of dir/file\n")
+		assert.Error(t, p.Execute(fs, NewFSMutator(fs), &ApplyOptions{DestDir: "/home/user"}))
+	})
+}
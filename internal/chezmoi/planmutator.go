@@ -0,0 +1,163 @@
+package chezmoi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	vfs "github.com/twpayne/go-vfs"
+)
+
+// A PlanMutator records changes in a Plan instead of making them.
+type PlanMutator struct {
+	plan    *Plan
+	tracker *planTracker
+}
+
+// NewPlanMutator returns a new PlanMutator that records changes in plan,
+// reading the prior state of paths from fs.
+func NewPlanMutator(fs vfs.FS, plan *Plan) *PlanMutator {
+	return &PlanMutator{
+		plan:    plan,
+		tracker: newPlanTracker(fs),
+	}
+}
+
+// Chmod implements Mutator.Chmod.
+func (m *PlanMutator) Chmod(name string, mode os.FileMode) error {
+	prior, err := m.tracker.get(name, false)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action: PlanActionChmod,
+		Path:   name,
+		Mode:   mode,
+		Prior:  prior,
+	})
+	return m.tracker.chmod(name, mode)
+}
+
+// Chown implements Mutator.Chown.
+func (m *PlanMutator) Chown(name string, uid, gid int) error {
+	prior, err := m.tracker.get(name, false)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action: PlanActionChown,
+		Path:   name,
+		Owner: &PlanOwner{
+			UID: uid,
+			GID: gid,
+		},
+		Prior: prior,
+	})
+	return nil
+}
+
+// IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
+func (m *PlanMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	return cmd.Output()
+}
+
+// Mkdir implements Mutator.Mkdir.
+func (m *PlanMutator) Mkdir(name string, perm os.FileMode) error {
+	prior, err := m.tracker.get(name, false)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action: PlanActionMkdir,
+		Path:   name,
+		Mode:   perm,
+		Prior:  prior,
+	})
+	m.tracker.mkdir(name, perm)
+	return nil
+}
+
+// RemoveAll implements Mutator.RemoveAll.
+func (m *PlanMutator) RemoveAll(name string) error {
+	prior, err := m.tracker.get(name, true)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action: PlanActionRemoveAll,
+		Path:   name,
+		Prior:  prior,
+	})
+	m.tracker.removeAll(name)
+	return nil
+}
+
+// Rename implements Mutator.Rename.
+func (m *PlanMutator) Rename(oldpath, newpath string) error {
+	prior, err := m.tracker.get(oldpath, false)
+	if err != nil {
+		return err
+	}
+	newPathPrior, err := m.tracker.get(newpath, true)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action:       PlanActionRename,
+		Path:         oldpath,
+		NewPath:      newpath,
+		Prior:        prior,
+		NewPathPrior: newPathPrior,
+	})
+	return m.tracker.rename(oldpath, newpath)
+}
+
+// RunCmd implements Mutator.RunCmd. Arbitrary commands cannot be recorded, so
+// it always returns an error.
+func (m *PlanMutator) RunCmd(cmd *exec.Cmd) error {
+	return fmt.Errorf("%s: commands cannot be planned", cmd.Path)
+}
+
+// Stat implements Mutator.Stat.
+func (m *PlanMutator) Stat(name string) (os.FileInfo, error) {
+	return m.tracker.fs.Stat(name)
+}
+
+// WriteFile implements Mutator.WriteFile.
+func (m *PlanMutator) WriteFile(name string, data []byte, perm os.FileMode, currData []byte) error {
+	prior, err := m.tracker.get(name, false)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action:   PlanActionWriteFile,
+		Path:     name,
+		Mode:     perm,
+		Contents: data,
+		SHA256:   sha256Sum(data),
+		Prior:    prior,
+	})
+	m.tracker.writeFile(name, data, perm)
+	return nil
+}
+
+// WriteSymlink implements Mutator.WriteSymlink.
+func (m *PlanMutator) WriteSymlink(oldname, newname string) error {
+	prior, err := m.tracker.get(newname, false)
+	if err != nil {
+		return err
+	}
+	m.addStep(&PlanStep{
+		Action:   PlanActionWriteSymlink,
+		Path:     newname,
+		Linkname: oldname,
+		Prior:    prior,
+	})
+	m.tracker.writeSymlink(newname, oldname)
+	return nil
+}
+
+// addStep adds step to m's plan.
+func (m *PlanMutator) addStep(step *PlanStep) {
+	m.plan.Steps = append(m.plan.Steps, step)
+}
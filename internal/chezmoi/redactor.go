@@ -0,0 +1,199 @@
+package chezmoi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedSecret replaces secrets in redacted output.
+const RedactedSecret = "********"
+
+// minRedactedSecretLength is the minimum length of a secret that will be
+// redacted. Shorter secrets would mask too much unrelated output.
+const minRedactedSecretLength = 4
+
+// metadataFieldNames are the names, compared case-insensitively, of the fields
+// in structured values returned by secret managers that hold metadata, such as
+// IDs, titles, labels, and URLs, rather than secrets. Strings in them are not
+// redacted, as they would mask too much unrelated output.
+var metadataFieldNames = []string{
+	"createdAt",
+	"creationDate",
+	"designation",
+	"folderId",
+	"id",
+	"k",
+	"label",
+	"metadata",
+	"n",
+	"name",
+	"object",
+	"organizationId",
+	"overview",
+	"request_id",
+	"revisionDate",
+	"t",
+	"templateUuid",
+	"title",
+	"type",
+	"updatedAt",
+	"uri",
+	"uris",
+	"url",
+	"urls",
+	"uuid",
+	"vaultUuid",
+}
+
+// A Redactor masks secrets in human-facing output. A nil *Redactor does not
+// mask anything. Its methods are safe for concurrent use.
+type Redactor struct {
+	mutex    sync.Mutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// NewRedactor returns a new Redactor.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		secrets: make(map[string]struct{}),
+	}
+}
+
+// AddSecret adds secret to r. Leading and trailing whitespace and each line of
+// multi-line secrets are also redacted.
+func (r *Redactor) AddSecret(secret string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	candidates := []string{secret, strings.TrimSpace(secret)}
+	if strings.Contains(secret, "\n") {
+		for _, line := range strings.Split(secret, "\n") {
+			candidates = append(candidates, strings.TrimSpace(line))
+		}
+	}
+	for _, candidate := range candidates {
+		if len(candidate) < minRedactedSecretLength {
+			continue
+		}
+		if _, ok := r.secrets[candidate]; ok {
+			continue
+		}
+		r.secrets[candidate] = struct{}{}
+		r.replacer = nil
+	}
+}
+
+// AddStrings adds all strings in value, which may be a string or an
+// arbitrarily nested structure of maps and slices, to r.
+func (r *Redactor) AddStrings(value interface{}) {
+	if r == nil || value == nil {
+		return
+	}
+	r.addValue(reflect.ValueOf(value), false)
+}
+
+// AddValue adds the secrets in value, which may be a string or an arbitrarily
+// nested structure of maps and slices, to r. All strings in value are added,
+// except those in fields named in metadataFieldNames.
+func (r *Redactor) AddValue(value interface{}) {
+	if r == nil || value == nil {
+		return
+	}
+	r.addValue(reflect.ValueOf(value), true)
+}
+
+// Redact returns data with all secrets masked.
+func (r *Redactor) Redact(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+	replacer := r.getReplacer()
+	if replacer == nil {
+		return data
+	}
+	return []byte(replacer.Replace(string(data)))
+}
+
+// RedactString returns s with all secrets masked.
+func (r *Redactor) RedactString(s string) string {
+	if r == nil {
+		return s
+	}
+	replacer := r.getReplacer()
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// addValue adds the strings in value to r. If skipMetadata is true then
+// strings in fields named in metadataFieldNames are not added.
+func (r *Redactor) addValue(value reflect.Value, skipMetadata bool) {
+	switch value.Kind() {
+	case reflect.String:
+		r.AddSecret(value.String())
+	case reflect.Interface, reflect.Ptr:
+		if !value.IsNil() {
+			r.addValue(value.Elem(), skipMetadata)
+		}
+	case reflect.Array, reflect.Slice:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			r.AddSecret(string(value.Bytes()))
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			r.addValue(value.Index(i), skipMetadata)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if key, ok := iter.Key().Interface().(string); ok && skipMetadata && isMetadataFieldName(key) {
+				continue
+			}
+			r.addValue(iter.Value(), skipMetadata)
+		}
+	}
+}
+
+// getReplacer returns a strings.Replacer that masks all secrets, or nil if
+// there are no secrets.
+func (r *Redactor) getReplacer() *strings.Replacer {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.replacer != nil || len(r.secrets) == 0 {
+		return r.replacer
+	}
+	// Replace longer secrets first so that secrets which contain other secrets
+	// are completely masked.
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return secrets[i] < secrets[j]
+	})
+	oldnew := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		oldnew = append(oldnew, secret, RedactedSecret)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+	return r.replacer
+}
+
+// isMetadataFieldName returns true if name is in metadataFieldNames.
+func isMetadataFieldName(name string) bool {
+	for _, metadataFieldName := range metadataFieldNames {
+		if strings.EqualFold(name, metadataFieldName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		values   []interface{}
+		s        string
+		expected string
+	}{
+		{
+			name:     "empty",
+			s:        "password secret",
+			expected: "password secret",
+		},
+		{
+			name:     "string",
+			values:   []interface{}{"secret"},
+			s:        "password secret",
+			expected: "password ********",
+		},
+		{
+			name:     "trim_space",
+			values:   []interface{}{"secret\n"},
+			s:        "password secret",
+			expected: "password ********",
+		},
+		{
+			name:     "multi_line",
+			values:   []interface{}{"line1\nline2\n"},
+			s:        "  line1\n  line2\n",
+			expected: "  ********\n  ********\n",
+		},
+		{
+			name:     "too_short",
+			values:   []interface{}{"abc"},
+			s:        "abc",
+			expected: "abc",
+		},
+		{
+			name:     "longest_first",
+			values:   []interface{}{"secret", "topsecret"},
+			s:        "topsecret",
+			expected: "********",
+		},
+		{
+			name: "nested",
+			values: []interface{}{
+				map[string]interface{}{
+					"login": map[string]interface{}{
+						"password": "secret",
+						"uris":     []interface{}{"https://example.com", nil},
+					},
+					"revision": 1,
+				},
+			},
+			s:        "https://example.com secret",
+			expected: "https://example.com ********",
+		},
+		{
+			name: "fields",
+			values: []interface{}{
+				map[string]interface{}{
+					"title": "example",
+					"fields": []interface{}{
+						map[string]interface{}{
+							"designation": "username",
+							"name":        "username",
+							"value":       "user@example.com",
+						},
+						map[string]interface{}{
+							"designation": "password",
+							"name":        "password",
+							"value":       "hunter42",
+						},
+					},
+				},
+				map[string]string{
+					"UserName": "admin",
+					"Password": "letmein",
+				},
+			},
+			s:        "example username user@example.com password hunter42 admin letmein",
+			expected: "example username ******** password ******** ******** ********",
+		},
+		{
+			name: "onepassword_sections",
+			values: []interface{}{
+				map[string]interface{}{
+					"uuid": "wxcplh5udshnonkzg2n4qx262y",
+					"overview": map[string]interface{}{
+						"title": "example",
+						"url":   "https://example.com",
+					},
+					"details": map[string]interface{}{
+						"sections": []interface{}{
+							map[string]interface{}{
+								"name":  "Section_1",
+								"title": "API",
+								"fields": []interface{}{
+									map[string]interface{}{
+										"k": "concealed",
+										"n": "api_token",
+										"t": "API token",
+										"v": "sk-abcdef",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			s:        "wxcplh5udshnonkzg2n4qx262y example https://example.com API API token sk-abcdef",
+			expected: "wxcplh5udshnonkzg2n4qx262y example https://example.com API API token ********",
+		},
+		{
+			name: "vault_kv",
+			values: []interface{}{
+				map[string]interface{}{
+					"token": "s.abcdef",
+					"user":  "admin",
+				},
+			},
+			s:        "token s.abcdef user admin",
+			expected: "token ******** user ********",
+		},
+		{
+			name: "vault",
+			values: []interface{}{
+				map[string]interface{}{
+					"request_id": "6a4e7f0c-1b2d",
+					"data": map[string]interface{}{
+						"data": map[string]interface{}{
+							"api_key": "abcdef0123",
+						},
+						"metadata": map[string]interface{}{
+							"created_time": "2020-05-01T12:00:00Z",
+						},
+					},
+				},
+			},
+			s:        "6a4e7f0c-1b2d 2020-05-01T12:00:00Z abcdef0123",
+			expected: "6a4e7f0c-1b2d 2020-05-01T12:00:00Z ********",
+		},
+		{
+			name: "secret_json",
+			values: []interface{}{
+				map[string]interface{}{
+					"name": "example",
+					"credentials": map[string]interface{}{
+						"client_secret": "0123456789",
+						"scopes":        []interface{}{"read", "write"},
+					},
+				},
+			},
+			s:        "example 0123456789 read write",
+			expected: "example ******** ******** ********",
+		},
+		{
+			name: "lastpass_note",
+			values: []interface{}{
+				[]interface{}{
+					map[string]interface{}{
+						"id":       "1234567890",
+						"name":     "example",
+						"url":      "https://example.com",
+						"password": "hunter42",
+						"note":     "recovery codes",
+					},
+				},
+			},
+			s:        "1234567890 example https://example.com hunter42 recovery codes",
+			expected: "1234567890 example https://example.com ******** ********",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRedactor()
+			for _, value := range tc.values {
+				r.AddValue(value)
+			}
+			assert.Equal(t, tc.expected, r.RedactString(tc.s))
+			assert.Equal(t, []byte(tc.expected), r.Redact([]byte(tc.s)))
+		})
+	}
+}
+
+func TestRedactorAddStrings(t *testing.T) {
+	r := NewRedactor()
+	r.AddStrings(map[string]interface{}{
+		"github": map[string]interface{}{
+			"token": "ghp_secret",
+		},
+	})
+	assert.Equal(t, "token ********", r.RedactString("token ghp_secret"))
+}
+
+func TestNilRedactor(t *testing.T) {
+	var r *Redactor
+	r.AddSecret("secret")
+	assert.Equal(t, "secret", r.RedactString("secret"))
+}
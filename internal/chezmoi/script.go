@@ -92,7 +92,7 @@ func (s *Script) AppendAllEntries(allEntries []Entry) []Entry {
 
 // Apply runs s.
 func (s *Script) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *ApplyOptions) error {
-	if applyOptions.Ignore(s.targetName) {
+	if applyOptions.Ignore(s.targetName) || !applyOptions.Filter.IncludeEntry(s) {
 		return nil
 	}
 	contents, err := s.Contents()
@@ -105,8 +105,7 @@ func (s *Script) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Ap
 
 	var key []byte
 	if s.Once {
-		contentsKeyArr := sha256.Sum256(contents)
-		key = []byte(s.targetName + ":" + hex.EncodeToString(contentsKeyArr[:]))
+		key = scriptStateKey(s.targetName, contents)
 		scriptStateData, err := applyOptions.PersistentState.Get(applyOptions.ScriptStateBucket, key)
 		if err != nil {
 			return err
@@ -116,66 +115,17 @@ func (s *Script) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *Ap
 		}
 	}
 
-	if applyOptions.Verbose {
-		if _, err := applyOptions.Stdout.Write(contents); err != nil {
-			return err
-		}
-	}
-	if applyOptions.DryRun {
+	if applyOptions.Plan != nil {
+		applyOptions.Plan.addScript(applyOptions.DestDir, s.targetName, s.sourceName, contents, s.Once)
 		return nil
 	}
 
-	// Write the temporary script file. Put the randomness on the front of the
-	// filename to preserve any file extension for Windows scripts.
-	f, err := ioutil.TempFile("", "*."+filepath.Base(s.targetName))
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		_ = os.RemoveAll(f.Name())
-	}()
-	if err := os.Chmod(f.Name(), 0o700); err != nil {
-		return err
-	}
-	if _, err := f.Write(contents); err != nil {
-		return err
-	}
-	if err := f.Close(); err != nil {
-		return err
-	}
-
-	// Run the temporary script file.
-	//nolint:gosec
-	c := exec.Command(f.Name())
-	c.Dir = filepath.Join(applyOptions.DestDir, filepath.Dir(s.targetName))
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Stdin = os.Stdin
-	if err := c.Run(); err != nil {
-		return err
-	}
-
-	if s.Once {
-		scriptState := &ScriptState{
-			Name:       s.sourceName,
-			ExecutedAt: time.Now(),
-		}
-		scriptStateData, err := json.Marshal(&scriptState)
-		if err != nil {
-			return err
-		}
-		if err := applyOptions.PersistentState.Set(applyOptions.ScriptStateBucket, key, scriptStateData); err != nil {
-			return err
-		}
-	}
-
-	return err
+	return runScript(s.targetName, s.sourceName, contents, key, applyOptions)
 }
 
 // ConcreteValue implements Entry.ConcreteValue.
-func (s *Script) ConcreteValue(ignore func(string) bool, sourceDir string, umask os.FileMode, recursive bool) (interface{}, error) {
-	if ignore(s.targetName) {
+func (s *Script) ConcreteValue(ignore func(string) bool, filter *EntryTypeFilter, sourceDir func(Entry) string, umask os.FileMode, recursive bool) (interface{}, error) {
+	if ignore(s.targetName) || !filter.IncludeEntry(s) {
 		return nil, nil
 	}
 	contents, err := s.Contents()
@@ -184,7 +134,7 @@ func (s *Script) ConcreteValue(ignore func(string) bool, sourceDir string, umask
 	}
 	return &scriptConcreteValue{
 		Type:       "script",
-		SourcePath: filepath.Join(sourceDir, s.SourceName()),
+		SourcePath: filepath.Join(sourceDir(s), s.SourceName()),
 		TargetPath: s.TargetName(),
 		Once:       s.Once,
 		Template:   s.Template,
@@ -221,8 +171,8 @@ func (s *Script) TargetName() string {
 }
 
 // archive writes s to w.
-func (s *Script) archive(w *tar.Writer, ignore func(string) bool, headerTemplate *tar.Header, umask os.FileMode) error {
-	if ignore(s.targetName) {
+func (s *Script) archive(w *tar.Writer, ignore func(string) bool, filter *EntryTypeFilter, headerTemplate *tar.Header, umask os.FileMode) error {
+	if ignore(s.targetName) || !filter.IncludeEntry(s) {
 		return nil
 	}
 	contents, err := s.Contents()
@@ -240,3 +190,87 @@ func (s *Script) archive(w *tar.Writer, ignore func(string) bool, headerTemplate
 	_, err = w.Write(contents)
 	return err
 }
+
+// runScript runs the script targetName with contents. If key is not nil then
+// the script's state is stored in applyOptions.PersistentState under key.
+func runScript(targetName, sourceName string, contents, key []byte, applyOptions *ApplyOptions) (err error) {
+	if applyOptions.Events != nil {
+		defer func() {
+			event := &Event{
+				Type:       EventTypeScript,
+				TargetPath: filepath.Join(applyOptions.DestDir, targetName),
+			}
+			if err != nil {
+				event.Error = err.Error()
+			}
+			applyOptions.Events(event)
+		}()
+	}
+
+	if applyOptions.Verbose {
+		if _, err := applyOptions.Stdout.Write(applyOptions.Redactor.Redact(contents)); err != nil {
+			return err
+		}
+	}
+	if applyOptions.DryRun {
+		return nil
+	}
+
+	// Write the temporary script file. Put the randomness on the front of the
+	// filename to preserve any file extension for Windows scripts.
+	f, err := ioutil.TempFile("", "*."+filepath.Base(targetName))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.RemoveAll(f.Name())
+	}()
+	if err := os.Chmod(f.Name(), 0o700); err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Run the temporary script file.
+	//nolint:gosec
+	c := exec.Command(f.Name())
+	c.Dir = filepath.Join(applyOptions.DestDir, filepath.Dir(targetName))
+	c.Stdout = os.Stdout
+	if applyOptions.Events != nil {
+		// Keep the script's output out of the event stream.
+		c.Stdout = os.Stderr
+	}
+	c.Stderr = os.Stderr
+	c.Stdin = os.Stdin
+	if err := c.Run(); err != nil {
+		return err
+	}
+
+	if key != nil {
+		scriptState := &ScriptState{
+			Name:       sourceName,
+			ExecutedAt: time.Now(),
+		}
+		scriptStateData, err := json.Marshal(&scriptState)
+		if err != nil {
+			return err
+		}
+		if err := applyOptions.PersistentState.Set(applyOptions.ScriptStateBucket, key, scriptStateData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scriptStateKey returns the key of the state of the script targetName with
+// contents.
+func scriptStateKey(targetName string, contents []byte) []byte {
+	contentsKeyArr := sha256.Sum256(contents)
+	return []byte(targetName + ":" + hex.EncodeToString(contentsKeyArr[:]))
+}
@@ -34,7 +34,7 @@ func (s *Symlink) AppendAllEntries(allEntries []Entry) []Entry {
 
 // Apply ensures that the state of s's target in fs matches s.
 func (s *Symlink) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *ApplyOptions) error {
-	if applyOptions.Ignore(s.targetName) {
+	if applyOptions.Ignore(s.targetName) || !applyOptions.Filter.IncludeEntry(s) {
 		return nil
 	}
 	target, err := s.Linkname()
@@ -70,8 +70,8 @@ func (s *Symlink) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptions *A
 }
 
 // ConcreteValue implements Entry.ConcreteValue.
-func (s *Symlink) ConcreteValue(ignore func(string) bool, sourceDir string, umask os.FileMode, recursive bool) (interface{}, error) {
-	if ignore(s.targetName) {
+func (s *Symlink) ConcreteValue(ignore func(string) bool, filter *EntryTypeFilter, sourceDir func(Entry) string, umask os.FileMode, recursive bool) (interface{}, error) {
+	if ignore(s.targetName) || !filter.IncludeEntry(s) {
 		return nil, nil
 	}
 	linkname, err := s.Linkname()
@@ -80,7 +80,7 @@ func (s *Symlink) ConcreteValue(ignore func(string) bool, sourceDir string, umas
 	}
 	return &symlinkConcreteValue{
 		Type:       "symlink",
-		SourcePath: filepath.Join(sourceDir, s.SourceName()),
+		SourcePath: filepath.Join(sourceDir(s), s.SourceName()),
 		TargetPath: s.TargetName(),
 		Template:   s.Template,
 		Linkname:   linkname,
@@ -116,8 +116,8 @@ func (s *Symlink) TargetName() string {
 }
 
 // archive writes s to w.
-func (s *Symlink) archive(w *tar.Writer, ignore func(string) bool, headerTemplate *tar.Header, umask os.FileMode) error {
-	if ignore(s.targetName) {
+func (s *Symlink) archive(w *tar.Writer, ignore func(string) bool, filter *EntryTypeFilter, headerTemplate *tar.Header, umask os.FileMode) error {
+	if ignore(s.targetName) || !filter.IncludeEntry(s) {
 		return nil
 	}
 	linkname, err := s.Linkname()
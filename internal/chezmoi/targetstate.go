@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,17 +12,20 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/bmatcuk/doublestar"
 	"github.com/coreos/go-semver/semver"
 	vfs "github.com/twpayne/go-vfs"
+	xdg "github.com/twpayne/go-xdg/v3"
 )
 
 // DefaultTemplateOptions are the default template options.
 var DefaultTemplateOptions = []string{"missingkey=error"}
 
 const (
+	attributesName   = ".chezmoiattributes"
 	ignoreName       = ".chezmoiignore"
 	removeName       = ".chezmoiremove"
 	templatesDirName = ".chezmoitemplates"
@@ -36,6 +40,9 @@ type AddOptions struct {
 	Recursive    bool
 	Template     bool
 	AutoTemplate bool
+	// Ownership records the owner and group of targets that are not owned
+	// by the current user in .chezmoiattributes.
+	Ownership bool
 }
 
 // An ImportTAROptions contains options for TargetState.ImportTAR.
@@ -48,22 +55,44 @@ type ImportTAROptions struct {
 // A PopulateOptions contains options for TargetState.Populate.
 type PopulateOptions struct {
 	ExecuteTemplates bool
+	// SkipPatterns skips reading .chezmoiattributes, .chezmoiignore, and
+	// .chezmoiremove files, which are always executed as templates.
+	SkipPatterns bool
 }
 
 // A TargetState represents the root target state.
 type TargetState struct {
-	DestDir         string
-	Entries         map[string]Entry
-	GPG             *GPG
-	MinVersion      *semver.Version
-	SourceDir       string
-	TargetIgnore    *PatternSet
-	TargetRemove    *PatternSet
-	TemplateData    map[string]interface{}
-	TemplateFuncs   template.FuncMap
-	TemplateOptions []string
-	Templates       map[string]*template.Template
-	Umask           os.FileMode
+	DestDir           string
+	Entries           map[string]Entry
+	GPG               *GPG
+	InactiveRoots     map[string]bool
+	LazyTemplateData  map[string]func() (interface{}, error)
+	MinVersion        *semver.Version
+	Parallelism       int
+	Roots             map[string]*TargetState
+	SkippedXDGEntries []SkippedXDGEntry
+	SourceDir         string
+	SourceLayers      []string
+	TargetAttributes  *AttributeSet
+	TargetIgnore      *PatternSet
+	TargetRemove      *PatternSet
+	TemplateData      map[string]interface{}
+	TemplateFuncs     template.FuncMap
+	TemplateOptions   []string
+	Templates         map[string]*template.Template
+	Umask             os.FileMode
+	XDG               *xdg.BaseDirectorySpecification
+
+	// entrySourceDirs maps entries that were populated from SourceLayers to
+	// their source directory. All other entries are in SourceDir.
+	entrySourceDirs map[Entry]string
+
+	// entryRoots maps entries in Roots to the root that they are in.
+	entryRoots map[Entry]*TargetState
+
+	// templateDataMutex protects TemplateData and LazyTemplateData, which
+	// are modified when lazy template data is resolved.
+	templateDataMutex sync.Mutex
 }
 
 // A TargetStateOption sets an option on a TargeState.
@@ -90,6 +119,15 @@ func WithGPG(gpg *GPG) TargetStateOption {
 	}
 }
 
+// WithLazyTemplateData sets functions that return template data that is
+// expensive to compute, for example because it must be decrypted. Each function
+// is called the first time that a template references its key.
+func WithLazyTemplateData(lazyTemplateData map[string]func() (interface{}, error)) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.LazyTemplateData = lazyTemplateData
+	}
+}
+
 // WithMinVersion sets the minimum version.
 func WithMinVersion(minVersion *semver.Version) TargetStateOption {
 	return func(ts *TargetState) {
@@ -97,6 +135,31 @@ func WithMinVersion(minVersion *semver.Version) TargetStateOption {
 	}
 }
 
+// WithParallelism sets the maximum number of entries that are evaluated
+// concurrently. Values less than two evaluate entries serially.
+func WithParallelism(parallelism int) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.Parallelism = parallelism
+	}
+}
+
+// WithInactiveRoots sets the source subdirectories of roots that are not
+// active. They are not populated, neither as roots nor as part of ts.
+func WithInactiveRoots(inactiveRoots map[string]bool) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.InactiveRoots = inactiveRoots
+	}
+}
+
+// WithRoots sets the destination roots. Each root contains the targets in
+// the source subdirectory with its name, which are in its destination
+// directory instead of ts's.
+func WithRoots(roots map[string]*TargetState) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.Roots = roots
+	}
+}
+
 // WithSourceDir sets the source directory.
 func WithSourceDir(sourceDir string) TargetStateOption {
 	return func(ts *TargetState) {
@@ -104,6 +167,14 @@ func WithSourceDir(sourceDir string) TargetStateOption {
 	}
 }
 
+// WithSourceLayers sets the source directories that are layered beneath the
+// source directory, from lowest to highest priority.
+func WithSourceLayers(sourceLayers []string) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.SourceLayers = sourceLayers
+	}
+}
+
 // WithTargetIgnore sets the target patterns to ignore.
 func WithTargetIgnore(targetIgnore *PatternSet) TargetStateOption {
 	return func(ts *TargetState) {
@@ -153,13 +224,21 @@ func WithUmask(umask os.FileMode) TargetStateOption {
 	}
 }
 
+// WithXDG sets the XDG base directories that XDG prefixes resolve to.
+func WithXDG(bds *xdg.BaseDirectorySpecification) TargetStateOption {
+	return func(ts *TargetState) {
+		ts.XDG = bds
+	}
+}
+
 // NewTargetState creates a new TargetState with the given options.
 func NewTargetState(options ...TargetStateOption) *TargetState {
 	ts := &TargetState{
-		Entries:         make(map[string]Entry),
-		TargetIgnore:    NewPatternSet(),
-		TargetRemove:    NewPatternSet(),
-		TemplateOptions: DefaultTemplateOptions,
+		Entries:          make(map[string]Entry),
+		TargetAttributes: NewAttributeSet(),
+		TargetIgnore:     NewPatternSet(),
+		TargetRemove:     NewPatternSet(),
+		TemplateOptions:  DefaultTemplateOptions,
 	}
 	for _, o := range options {
 		o(ts)
@@ -167,14 +246,21 @@ func NewTargetState(options ...TargetStateOption) *TargetState {
 	return ts
 }
 
-// Add adds a new target to ts.
+// Add adds a new target to ts, or to the root of ts that contains it. The
+// target is always added to ts.SourceDir, so ts should not have any source
+// layers.
 func (ts *TargetState) Add(fs vfs.FS, addOptions AddOptions, targetPath string, info os.FileInfo, follow bool, mutator Mutator) error {
-	contains, err := vfs.Contains(fs, targetPath, ts.DestDir)
+	root, err := ts.findRoot(fs, targetPath)
 	if err != nil {
 		return err
 	}
-	if !contains {
-		return fmt.Errorf("%s: outside target directory", targetPath)
+	if root != ts {
+		if _, err := fs.Stat(root.SourceDir); os.IsNotExist(err) {
+			if err := mutator.Mkdir(root.SourceDir, 0o777&^ts.Umask); err != nil {
+				return err
+			}
+		}
+		return root.Add(fs, addOptions, targetPath, info, follow, mutator)
 	}
 	targetName, err := filepath.Rel(ts.DestDir, targetPath)
 	if err != nil {
@@ -197,14 +283,23 @@ func (ts *TargetState) Add(fs vfs.FS, addOptions AddOptions, targetPath string,
 		}
 	}
 
-	// Add the parent directories, if needed.
+	// Add the parent directories, if needed. Targets in XDG base directories
+	// are added with XDG prefixes.
 	parentDirSourceName := ""
 	entries := ts.Entries
-	if parentDirName := filepath.Dir(targetName); parentDirName != "." {
+	if prefix, xdgEntries, ok, err := ts.xdgParentDir(targetName); err != nil {
+		return err
+	} else if ok {
+		parentDirSourceName = prefix
+		entries = xdgEntries
+	} else if parentDirName := filepath.Dir(targetName); parentDirName != "." {
 		parentEntry, err := ts.findEntry(parentDirName)
 		if err != nil && !os.IsNotExist(err) {
 			return err
 		}
+		if dir, ok := parentEntry.(*Dir); ok && dir.implicit {
+			parentEntry = nil
+		}
 		if parentEntry == nil {
 			if err := ts.Add(fs, addOptions, filepath.Join(ts.DestDir, parentDirName), nil, follow, mutator); err != nil {
 				return err
@@ -239,7 +334,10 @@ func (ts *TargetState) Add(fs vfs.FS, addOptions AddOptions, targetPath string,
 		// recursively, add a .keep file so the directory is managed by git.
 		// chezmoi will ignore the .keep file as it begins with a dot.
 		createKeepFile := len(infos) == 0 || !addOptions.Recursive
-		return ts.addDir(targetName, entries, parentDirSourceName, addOptions.Exact, perm, createKeepFile, mutator)
+		if err := ts.addDir(targetName, entries, parentDirSourceName, addOptions.Exact, perm, createKeepFile, mutator); err != nil {
+			return err
+		}
+		return ts.addOwnership(fs, addOptions, targetName, entries[filepath.Base(targetName)], info, mutator)
 	case info.Mode().IsRegular():
 		if info.Size() == 0 && !addOptions.Empty {
 			entry, err := ts.Get(fs, targetPath)
@@ -273,7 +371,10 @@ func (ts *TargetState) Add(fs vfs.FS, addOptions AddOptions, targetPath string,
 		if private {
 			perm &^= 0o77
 		}
-		return ts.addFile(targetName, entries, parentDirSourceName, info, perm, addOptions.Encrypt, addOptions.Template, contents, mutator)
+		if err := ts.addFile(targetName, entries, parentDirSourceName, info, perm, addOptions.Encrypt, addOptions.Template, contents, mutator); err != nil {
+			return err
+		}
+		return ts.addOwnership(fs, addOptions, targetName, entries[filepath.Base(targetName)], info, mutator)
 	case info.Mode()&os.ModeType == os.ModeSymlink:
 		linkname, err := fs.Readlink(targetPath)
 		if err != nil {
@@ -285,12 +386,15 @@ func (ts *TargetState) Add(fs vfs.FS, addOptions AddOptions, targetPath string,
 	}
 }
 
-// AllEntries returns all Entrys in ts.
+// AllEntries returns all Entrys in ts and its roots.
 func (ts *TargetState) AllEntries() []Entry {
 	var allEntries []Entry
 	for _, entry := range ts.Entries {
 		allEntries = entry.AppendAllEntries(allEntries)
 	}
+	for _, name := range sortedRootNames(ts.Roots) {
+		allEntries = append(allEntries, ts.Roots[name].AllEntries()...)
+	}
 	return allEntries
 }
 
@@ -334,53 +438,178 @@ func (ts *TargetState) Apply(fs vfs.FS, mutator Mutator, follow bool, applyOptio
 		sort.Sort(sort.Reverse(sort.StringSlice(sortedTargetsToRemove)))
 		for _, target := range sortedTargetsToRemove {
 			if err := mutator.RemoveAll(target); err != nil {
-				return err
+				relPath := strings.TrimPrefix(target, ts.DestDir+string(filepath.Separator))
+				if err := applyOptions.HandleError(relPath, err); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
+	entries := make([]Entry, 0, len(ts.Entries))
 	for _, entryName := range sortedEntryNames(ts.Entries) {
-		if err := ts.Entries[entryName].Apply(fs, mutator, follow, applyOptions); err != nil {
+		entries = append(entries, ts.Entries[entryName])
+	}
+	applyOptions.evaluator = ts.newBatchEvaluator(entries, applyOptions.Filter)
+
+	for _, entry := range entries {
+		applyOptions.evaluator.evaluate(entry)
+		if err := entry.Apply(fs, mutator, follow, applyOptions); err != nil {
+			if err := applyOptions.HandleError(entry.TargetName(), err); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range sortedRootNames(ts.Roots) {
+		if err := ts.applyRoot(fs, mutator, follow, ts.Roots[name], applyOptions); err != nil {
 			return err
 		}
 	}
+	return applyOptions.Err()
+}
+
+// ApplyEntry applies entry, which is in ts or one of its roots. Errors are
+// handled by applyOptions.HandleError.
+func (ts *TargetState) ApplyEntry(fs vfs.FS, mutator Mutator, follow bool, entry Entry, applyOptions *ApplyOptions) error {
+	root := ts.rootOf(entry)
+	entryApplyOptions := applyOptions
+	targetName := entry.TargetName()
+	if root != ts {
+		entryApplyOptions = root.rootApplyOptions(applyOptions)
+		targetName = root.TargetPath(entry)
+	}
+	applyOptions.evaluator.evaluate(entry)
+	err := entry.Apply(fs, mutator, follow, entryApplyOptions)
+	if root != ts {
+		err = root.rootError(err)
+		for _, entryError := range entryApplyOptions.errors {
+			applyOptions.errors = append(applyOptions.errors, root.rootEntryError(entryError))
+		}
+	}
+	if err != nil {
+		return applyOptions.HandleError(targetName, err)
+	}
 	return nil
 }
 
-// Archive writes ts to w.
-func (ts *TargetState) Archive(w *tar.Writer, umask os.FileMode) error {
+// ApplyEntries applies entries, which are in ts or its roots, in order. Errors
+// are handled by applyOptions.HandleError.
+func (ts *TargetState) ApplyEntries(fs vfs.FS, mutator Mutator, follow bool, entries []Entry, applyOptions *ApplyOptions) error {
+	applyOptions.evaluator = ts.newBatchEvaluator(entries, applyOptions.Filter)
+	for _, entry := range entries {
+		if err := ts.ApplyEntry(fs, mutator, follow, entry, applyOptions); err != nil {
+			return err
+		}
+	}
+	return applyOptions.Err()
+}
+
+// Archive writes the entries in ts included by filter to w. Entries in ts's
+// roots are not written.
+func (ts *TargetState) Archive(w *tar.Writer, filter *EntryTypeFilter, umask os.FileMode) error {
 	headerTemplate, err := ts.getTarHeaderTemplate()
 	if err != nil {
 		return err
 	}
 
 	for _, entryName := range sortedEntryNames(ts.Entries) {
-		if err := ts.Entries[entryName].archive(w, ts.TargetIgnore.Match, headerTemplate, umask); err != nil {
+		if err := ts.Entries[entryName].archive(w, ts.TargetIgnore.Match, filter, headerTemplate, umask); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// ConcreteValue returns a value suitable for serialization.
-func (ts *TargetState) ConcreteValue(recursive bool) (interface{}, error) {
+// ConcreteValue returns a value suitable for serialization of the entries in
+// ts included by filter.
+func (ts *TargetState) ConcreteValue(filter *EntryTypeFilter, recursive bool) (interface{}, error) {
 	var entryConcreteValues []interface{}
 	for _, entryName := range sortedEntryNames(ts.Entries) {
-		entryConcreteValue, err := ts.Entries[entryName].ConcreteValue(ts.TargetIgnore.Match, ts.SourceDir, ts.Umask, recursive)
+		entryConcreteValue, err := ts.Entries[entryName].ConcreteValue(ts.TargetIgnore.Match, filter, ts.SourceDirOf, ts.Umask, recursive)
 		if err != nil {
 			return nil, err
 		}
-		if entryConcreteValue != nil {
-			entryConcreteValues = append(entryConcreteValues, entryConcreteValue)
+		entryConcreteValues = AppendConcreteValue(entryConcreteValues, entryConcreteValue)
+	}
+	for _, name := range sortedRootNames(ts.Roots) {
+		rootConcreteValue, err := ts.Roots[name].ConcreteValue(filter, recursive)
+		if err != nil {
+			return nil, err
 		}
+		entryConcreteValues = AppendConcreteValue(entryConcreteValues, rootConcreteValue)
 	}
 	return entryConcreteValues, nil
 }
 
-// Evaluate evaluates all of the entries in ts.
+// EntryConcreteValue returns a value suitable for serialization of entry,
+// which is in ts or one of its roots, if it is included by filter.
+func (ts *TargetState) EntryConcreteValue(entry Entry, filter *EntryTypeFilter, recursive bool) (interface{}, error) {
+	root := ts.rootOf(entry)
+	return entry.ConcreteValue(root.TargetIgnore.Match, filter, root.SourceDirOf, root.Umask, recursive)
+}
+
+// Evaluate evaluates all of the entries in ts and its roots.
 func (ts *TargetState) Evaluate() error {
+	entries := make([]Entry, 0, len(ts.Entries))
 	for _, entryName := range sortedEntryNames(ts.Entries) {
-		if err := ts.Entries[entryName].Evaluate(ts.TargetIgnore.Match); err != nil {
+		entries = append(entries, ts.Entries[entryName])
+	}
+	if err := ts.EvaluateEntries(entries); err != nil {
+		return err
+	}
+	for _, name := range sortedRootNames(ts.Roots) {
+		if err := ts.Roots[name].Evaluate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EvaluateEntries evaluates entries and all of their descendants, using up to
+// ts.Parallelism goroutines. Mutators are not used, so entries can be
+// evaluated in any order. The returned error is the first error in the order
+// in which entries would be evaluated serially.
+func (ts *TargetState) EvaluateEntries(entries []Entry) error {
+	if ts.Parallelism < 2 {
+		for _, entry := range entries {
+			if err := entry.Evaluate(ts.rootOf(entry).TargetIgnore.Match); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var leafEntries []Entry
+	for _, entry := range entries {
+		leafEntries = ts.appendLeafEntries(leafEntries, entry, nil)
+	}
+	return ts.evaluateLeafEntries(leafEntries)
+}
+
+// evaluateLeafEntries evaluates leafEntries, none of which are directories,
+// using up to ts.Parallelism goroutines. The returned error is the first error
+// in leafEntries.
+func (ts *TargetState) evaluateLeafEntries(leafEntries []Entry) error {
+	errs := make([]error, len(leafEntries))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < ts.Parallelism && i < len(leafEntries); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				errs[index] = leafEntries[index].Evaluate(ts.rootOf(leafEntries[index]).TargetIgnore.Match)
+			}
+		}()
+	}
+	for index := range leafEntries {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
 			return err
 		}
 	}
@@ -399,8 +628,15 @@ func (ts *TargetState) ExecuteTemplateData(name string, data []byte) ([]byte, er
 			return nil, err
 		}
 	}
+	ts.templateDataMutex.Lock()
+	err = ts.resolveLazyTemplateData(tmpl, name)
+	templateData := ts.TemplateData
+	ts.templateDataMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
 	sb := &strings.Builder{}
-	if err = tmpl.ExecuteTemplate(sb, name, ts.TemplateData); err != nil {
+	if err = tmpl.ExecuteTemplate(sb, name, templateData); err != nil {
 		return nil, err
 	}
 	return []byte(sb.String()), nil
@@ -408,18 +644,43 @@ func (ts *TargetState) ExecuteTemplateData(name string, data []byte) ([]byte, er
 
 // Get returns the state of the given target, or nil if no such target is found.
 func (ts *TargetState) Get(fs vfs.Stater, target string) (Entry, error) {
-	contains, err := vfs.Contains(fs, target, ts.DestDir)
+	root, err := ts.findRoot(fs, target)
 	if err != nil {
 		return nil, err
 	}
-	if !contains {
-		return nil, fmt.Errorf("%s: outside target directory", target)
+	targetName, err := filepath.Rel(root.DestDir, target)
+	if err != nil {
+		return nil, err
 	}
-	targetName, err := filepath.Rel(ts.DestDir, target)
+	entry, err := root.findEntry(targetName)
 	if err != nil {
 		return nil, err
 	}
-	return ts.findEntry(targetName)
+	// Implicit directories are not in the source state.
+	if dir, ok := entry.(*Dir); ok && dir.implicit {
+		return nil, os.ErrNotExist
+	}
+	return entry, nil
+}
+
+// Ignored returns whether entry, which is in ts or one of its roots, is
+// ignored.
+func (ts *TargetState) Ignored(entry Entry) bool {
+	return ts.rootOf(entry).TargetIgnore.Match(entry.TargetName())
+}
+
+// IgnoredPath returns whether the target at targetPath is ignored by ts, or by
+// the root of ts that contains it.
+func (ts *TargetState) IgnoredPath(fs vfs.Stater, targetPath string) (bool, error) {
+	root, err := ts.findRoot(fs, targetPath)
+	if err != nil {
+		return false, err
+	}
+	targetName, err := filepath.Rel(root.DestDir, targetPath)
+	if err != nil {
+		return false, err
+	}
+	return root.TargetIgnore.Match(targetName), nil
 }
 
 // ImportTAR imports a tar archive.
@@ -444,154 +705,104 @@ func (ts *TargetState) ImportTAR(r *tar.Reader, importTAROptions ImportTAROption
 	return nil
 }
 
-// Populate walks fs from ts.SourceDir to populate ts.
+// Populate walks fs from each of ts's source directories in turn to populate
+// ts. Entries in later source directories override entries with the same
+// target name in earlier ones, and attributes, ignore patterns, remove
+// patterns, and templates from all source directories are merged.
 func (ts *TargetState) Populate(fs vfs.FS, options *PopulateOptions) error {
-	return vfs.Walk(fs, ts.SourceDir, func(path string, info os.FileInfo, _ error) error {
-		relPath, err := filepath.Rel(ts.SourceDir, path)
-		if err != nil {
+	for _, sourceDir := range ts.SourceDirs() {
+		if err := ts.populateSourceDir(fs, sourceDir, options); err != nil {
 			return err
 		}
-		if relPath == "." {
-			return nil
+	}
+	ts.setOwnership()
+	for _, name := range sortedRootNames(ts.Roots) {
+		if err := ts.populateRoot(fs, name, options); err != nil {
+			return err
 		}
-		// Treat all files and directories beginning with "." specially.
-		if _, name := filepath.Split(relPath); strings.HasPrefix(name, ".") {
-			switch {
-			case info.Name() == ignoreName:
-				dns := dirNames(parseDirNameComponents(splitPathList(relPath)))
-				return ts.addPatterns(fs, ts.TargetIgnore, path, filepath.Join(dns...))
-			case info.Name() == removeName:
-				dns := dirNames(parseDirNameComponents(splitPathList(relPath)))
-				return ts.addPatterns(fs, ts.TargetRemove, path, filepath.Join(dns...))
-			case info.Name() == templatesDirName:
-				if err := ts.addTemplatesDir(fs, path); err != nil {
-					return err
-				}
-				return filepath.SkipDir
-			case info.Name() == versionName:
-				data, err := fs.ReadFile(path)
-				if err != nil {
-					return err
-				}
-				version, err := semver.NewVersion(strings.TrimSpace(string(data)))
-				if err != nil {
-					return err
-				}
-				if ts.MinVersion == nil || ts.MinVersion.LessThan(*version) {
-					ts.MinVersion = version
-				}
-				return nil
-			case info.IsDir():
-				// Don't recurse into ignored subdirectories.
-				return filepath.SkipDir
-			}
-			// Ignore all other files and directories.
-			return nil
+	}
+	return nil
+}
+
+// SourceDirOf returns the source directory that entry was populated from.
+func (ts *TargetState) SourceDirOf(entry Entry) string {
+	if root := ts.rootOf(entry); root != ts {
+		return root.SourceDirOf(entry)
+	}
+	if sourceDir, ok := ts.entrySourceDirs[entry]; ok {
+		return sourceDir
+	}
+	return ts.SourceDir
+}
+
+// SourceDirs returns all of ts's source directories, from lowest to highest
+// priority.
+func (ts *TargetState) SourceDirs() []string {
+	sourceDirs := make([]string, 0, len(ts.SourceLayers)+1)
+	sourceDirs = append(sourceDirs, ts.SourceLayers...)
+	return append(sourceDirs, ts.SourceDir)
+}
+
+// SourcePath returns the path of entry in its source directory.
+func (ts *TargetState) SourcePath(entry Entry) string {
+	return filepath.Join(ts.SourceDirOf(entry), entry.SourceName())
+}
+
+// TargetPath returns the path of entry, which is in ts or one of its roots, in
+// its destination directory.
+func (ts *TargetState) TargetPath(entry Entry) string {
+	return filepath.Join(ts.rootOf(entry).DestDir, entry.TargetName())
+}
+
+func (ts *TargetState) addAttributes(fs vfs.FS, path, relPath string) error {
+	data, err := ts.executeTemplate(fs, path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(relPath)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := splitAttributeFields(s.Text())
+		if len(fields) == 0 {
+			continue
 		}
-		switch {
-		case info.IsDir():
-			components := splitPathList(relPath)
-			das := parseDirNameComponents(components)
-			dns := dirNames(das)
-			targetName := filepath.Join(dns...)
-			entries, err := ts.findEntries(dns[:len(dns)-1])
-			if err != nil {
-				return err
-			}
-			da := das[len(das)-1]
-			entries[da.Name] = newDir(relPath, targetName, da.Exact, da.Perm)
-		case info.Mode().IsRegular():
-			psfp := parseSourceFilePath(relPath)
-			dns := dirNames(psfp.dirAttributes)
-			entries, err := ts.findEntries(dns)
-			if err != nil {
-				return err
+		var ownership Ownership
+		for _, field := range fields[1:] {
+			key, value := field, ""
+			if index := strings.IndexRune(field, '='); index != -1 {
+				key, value = field[:index], field[index+1:]
 			}
 			switch {
-			case psfp.fileAttributes != nil && psfp.fileAttributes.Mode&os.ModeType == 0 || psfp.scriptAttributes != nil:
-				readFile := func() ([]byte, error) {
-					return fs.ReadFile(path)
-				}
-				evaluateContents := readFile
-				if psfp.fileAttributes != nil && psfp.fileAttributes.Encrypted {
-					prevEvaluateContents := evaluateContents
-					evaluateContents = func() ([]byte, error) {
-						ciphertext, err := prevEvaluateContents()
-						if err != nil {
-							return nil, err
-						}
-						return ts.GPG.Decrypt(path, ciphertext)
-					}
-				}
-				if psfp.fileAttributes != nil && psfp.fileAttributes.Template || psfp.scriptAttributes != nil && psfp.scriptAttributes.Template {
-					if options == nil || options.ExecuteTemplates {
-						prevEvaluateContents := evaluateContents
-						evaluateContents = func() ([]byte, error) {
-							data, err := prevEvaluateContents()
-							if err != nil {
-								return nil, err
-							}
-							return ts.ExecuteTemplateData(path, data)
-						}
-					}
-				}
-				switch {
-				case psfp.fileAttributes != nil:
-					entry := &File{
-						sourceName:       relPath,
-						targetName:       filepath.Join(append(dns, psfp.fileAttributes.Name)...),
-						Empty:            psfp.fileAttributes.Empty,
-						Encrypted:        psfp.fileAttributes.Encrypted,
-						Perm:             psfp.fileAttributes.Mode.Perm(),
-						Template:         psfp.fileAttributes.Template,
-						evaluateContents: evaluateContents,
-					}
-					entries[psfp.fileAttributes.Name] = entry
-				case psfp.scriptAttributes != nil:
-					entry := &Script{
-						sourceName:       relPath,
-						targetName:       filepath.Join(append(dns, psfp.scriptAttributes.Name)...),
-						Once:             psfp.scriptAttributes.Once,
-						Template:         psfp.scriptAttributes.Template,
-						evaluateContents: evaluateContents,
-					}
-					entries[psfp.scriptAttributes.Name] = entry
-				}
-			case psfp.fileAttributes != nil && psfp.fileAttributes.Mode&os.ModeType == os.ModeSymlink:
-				evaluateLinkname := func() (string, error) {
-					data, err := fs.ReadFile(path)
-					return string(data), err
-				}
-				if psfp.fileAttributes.Template {
-					evaluateLinkname = func() (string, error) {
-						data, err := ts.executeTemplate(fs, path)
-						return string(data), err
-					}
-				}
-				entry := &Symlink{
-					sourceName:       relPath,
-					targetName:       filepath.Join(append(dns, psfp.fileAttributes.Name)...),
-					Template:         psfp.fileAttributes.Template,
-					evaluateLinkname: evaluateLinkname,
-				}
-				entries[psfp.fileAttributes.Name] = entry
+			case key == "owner" && value != "":
+				ownership.Owner = value
+			case key == "group" && value != "":
+				ownership.Group = value
 			default:
-				return fmt.Errorf("%s: unsupported file type", path)
+				return fmt.Errorf("%s: %s: invalid attribute", path, field)
 			}
-		default:
-			return fmt.Errorf("%s: unsupported file type", path)
 		}
-		return nil
-	})
+		if err := ts.TargetAttributes.Add(filepath.Join(dir, fields[0]), ownership); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
 }
 
 func (ts *TargetState) addDir(targetName string, entries map[string]Entry, parentDirSourceName string, exact bool, perm os.FileMode, createKeepFile bool, mutator Mutator) error {
 	name := filepath.Base(targetName)
+	var implicitDir *Dir
 	if entry, ok := entries[name]; ok {
-		if _, ok = entry.(*Dir); !ok {
+		dir, ok := entry.(*Dir)
+		if !ok {
 			return fmt.Errorf("%s: already added and not a directory", targetName)
 		}
-		return nil
+		if !dir.implicit {
+			return nil
+		}
+		implicitDir = dir
 	}
 	sourceName := DirAttributes{
 		Name:  name,
@@ -599,9 +810,13 @@ func (ts *TargetState) addDir(targetName string, entries map[string]Entry, paren
 		Perm:  perm,
 	}.SourceName()
 	if parentDirSourceName != "" {
-		sourceName = filepath.Join(parentDirSourceName, sourceName)
+		sourceName = joinSourceName(parentDirSourceName, sourceName)
 	}
 	dir := newDir(sourceName, targetName, exact, perm)
+	// An implicit directory becomes a real directory, keeping its entries.
+	if implicitDir != nil {
+		dir.Entries = implicitDir.Entries
+	}
 	if err := mutator.Mkdir(filepath.Join(ts.SourceDir, sourceName), 0o777&^ts.Umask); err != nil {
 		return err
 	}
@@ -639,7 +854,7 @@ func (ts *TargetState) addFile(targetName string, entries map[string]Entry, pare
 		Template:  template,
 	}.SourceName()
 	if parentDirSourceName != "" {
-		sourceName = filepath.Join(parentDirSourceName, sourceName)
+		sourceName = joinSourceName(parentDirSourceName, sourceName)
 	}
 	file := &File{
 		sourceName: sourceName,
@@ -665,6 +880,59 @@ func (ts *TargetState) addFile(targetName string, entries map[string]Entry, pare
 	return mutator.WriteFile(filepath.Join(ts.SourceDir, sourceName), contents, 0o666&^ts.Umask, existingContents)
 }
 
+// addOwnership records the owner and group of the target targetName, with
+// entry and info, in ts's .chezmoiattributes if they differ from those that
+// would be applied.
+func (ts *TargetState) addOwnership(fs vfs.FS, addOptions AddOptions, targetName string, entry Entry, info os.FileInfo, mutator Mutator) error {
+	if !addOptions.Ownership {
+		return nil
+	}
+	uid, gid, ok := fileOwnership(info)
+	if !ok {
+		return nil
+	}
+	wantUID, wantGID, err := ts.TargetAttributes.Ownership(targetName).ids()
+	if err != nil {
+		return err
+	}
+	if wantUID == -1 {
+		wantUID = os.Geteuid()
+	}
+	if wantGID == -1 {
+		wantGID = os.Getegid()
+	}
+	var ownership Ownership
+	if uid != wantUID {
+		ownership.Owner = userName(uid)
+	}
+	if gid != wantGID {
+		ownership.Group = groupName(gid)
+	}
+	if ownership.IsZero() {
+		return nil
+	}
+	if err := ts.TargetAttributes.Add(targetName, ownership); err != nil {
+		return err
+	}
+	switch entry := entry.(type) {
+	case *Dir:
+		entry.Ownership = ts.TargetAttributes.Ownership(targetName)
+	case *File:
+		entry.Ownership = ts.TargetAttributes.Ownership(targetName)
+	}
+	path := filepath.Join(ts.SourceDir, attributesName)
+	currData, err := fs.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data := currData
+	if len(data) != 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(data, escapePattern(targetName)+" "+ownership.String()+"\n"...)
+	return mutator.WriteFile(path, data, 0o666&^ts.Umask, currData)
+}
+
 func (ts *TargetState) addPatterns(fs vfs.FS, ps *PatternSet, path, relPath string) error {
 	data, err := ts.executeTemplate(fs, path)
 	if err != nil {
@@ -717,7 +985,7 @@ func (ts *TargetState) addSymlink(targetName string, entries map[string]Entry, p
 		Mode: os.ModeSymlink,
 	}.SourceName()
 	if parentDirSourceName != "" {
-		sourceName = filepath.Join(parentDirSourceName, sourceName)
+		sourceName = joinSourceName(parentDirSourceName, sourceName)
 	}
 	symlink := &Symlink{
 		sourceName: sourceName,
@@ -752,7 +1020,7 @@ func (ts *TargetState) addTemplatesDir(fs vfs.FS, path string) error {
 				return err
 			}
 			name := strings.TrimPrefix(filepath.ToSlash(path), prefix)
-			tmpl, err := template.New(name).Parse(string(contents))
+			tmpl, err := template.New(name).Option(ts.TemplateOptions...).Funcs(ts.TemplateFuncs).Parse(string(contents))
 			if err != nil {
 				return err
 			}
@@ -769,6 +1037,57 @@ func (ts *TargetState) addTemplatesDir(fs vfs.FS, path string) error {
 	})
 }
 
+// appendLeafEntries appends entry, or all of its descendants if it is a
+// directory, to leafEntries, skipping entries that are ignored or not included
+// by filter. The descendants of directories that are not included by filter
+// are also skipped, as they are only applied if the directory already exists.
+func (ts *TargetState) appendLeafEntries(leafEntries []Entry, entry Entry, filter *EntryTypeFilter) []Entry {
+	if ts.Ignored(entry) || !filter.IncludeEntry(entry) {
+		return leafEntries
+	}
+	dir, ok := entry.(*Dir)
+	if !ok {
+		return append(leafEntries, entry)
+	}
+	for _, entryName := range sortedEntryNames(dir.Entries) {
+		leafEntries = ts.appendLeafEntries(leafEntries, dir.Entries[entryName], filter)
+	}
+	return leafEntries
+}
+
+// applyRoot applies root, which is one of ts's roots, with applyOptions.
+// Errors applying targets in root are recorded with their target paths.
+func (ts *TargetState) applyRoot(fs vfs.FS, mutator Mutator, follow bool, root *TargetState, applyOptions *ApplyOptions) error {
+	rootApplyOptions := root.rootApplyOptions(applyOptions)
+	if err := root.Apply(fs, mutator, follow, rootApplyOptions); err != nil {
+		if _, ok := err.(ApplyErrors); !ok || !applyOptions.KeepGoing {
+			return root.rootError(err)
+		}
+	}
+	for _, entryError := range rootApplyOptions.errors {
+		applyOptions.errors = append(applyOptions.errors, root.rootEntryError(entryError))
+	}
+	return nil
+}
+
+// rootEntryError returns entryError, which is an error applying a target in
+// ts, with its target name replaced by the target's path.
+func (ts *TargetState) rootEntryError(entryError *EntryError) *EntryError {
+	return &EntryError{
+		TargetName: filepath.Join(ts.DestDir, entryError.TargetName),
+		Err:        entryError.Err,
+	}
+}
+
+// rootError returns err, which is an error applying ts, with the target name
+// of any *EntryError replaced by the target's path.
+func (ts *TargetState) rootError(err error) error {
+	if entryError, ok := err.(*EntryError); ok {
+		return ts.rootEntryError(entryError)
+	}
+	return err
+}
+
 func (ts *TargetState) executeTemplate(fs vfs.FS, path string) ([]byte, error) {
 	data, err := fs.ReadFile(path)
 	if err != nil {
@@ -791,6 +1110,33 @@ func (ts *TargetState) findEntries(dirNames []string) (map[string]Entry, error)
 	return entries, nil
 }
 
+// findRoot returns ts, or the root of ts, whose destination directory most
+// closely contains targetPath.
+func (ts *TargetState) findRoot(fs vfs.Stater, targetPath string) (*TargetState, error) {
+	var root *TargetState
+	contains, err := vfs.Contains(fs, targetPath, ts.DestDir)
+	if err != nil {
+		return nil, err
+	}
+	if contains {
+		root = ts
+	}
+	for _, name := range sortedRootNames(ts.Roots) {
+		r := ts.Roots[name]
+		contains, err := vfs.Contains(fs, targetPath, r.DestDir)
+		if err != nil {
+			return nil, err
+		}
+		if contains && (root == nil || len(r.DestDir) > len(root.DestDir)) {
+			root = r
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%s: outside target directory", targetPath)
+	}
+	return root, nil
+}
+
 func (ts *TargetState) findEntry(name string) (Entry, error) {
 	names := splitPathList(name)
 	entries, err := ts.findEntries(names[:len(names)-1])
@@ -820,7 +1166,12 @@ func (ts *TargetState) importHeader(r io.Reader, importTAROptions ImportTAROptio
 	}
 	parentDirSourceName := ""
 	entries := ts.Entries
-	if parentDirName := filepath.Dir(targetName); parentDirName != "." {
+	if prefix, xdgEntries, ok, err := ts.xdgParentDir(targetName); err != nil {
+		return err
+	} else if ok {
+		parentDirSourceName = prefix
+		entries = xdgEntries
+	} else if parentDirName := filepath.Dir(targetName); parentDirName != "." {
 		parentEntry, err := ts.findEntry(parentDirName)
 		if err != nil {
 			return err
@@ -829,6 +1180,9 @@ func (ts *TargetState) importHeader(r io.Reader, importTAROptions ImportTAROptio
 		if !ok {
 			return fmt.Errorf("%s: parent is not a directory", targetName)
 		}
+		if parentDir.implicit {
+			return fmt.Errorf("%s: parent is not in the source state", targetName)
+		}
 		parentDirSourceName = parentDir.sourceName
 		entries = parentDir.Entries
 	}
@@ -851,3 +1205,279 @@ func (ts *TargetState) importHeader(r io.Reader, importTAROptions ImportTAROptio
 		return fmt.Errorf("%s: unspported typeflag '%c'", header.Name, header.Typeflag)
 	}
 }
+
+// populateRoot populates the root name from the subdirectory name of each of
+// ts's source directories. Templates are shared with the root.
+func (ts *TargetState) populateRoot(fs vfs.FS, name string, options *PopulateOptions) error {
+	root := ts.Roots[name]
+	root.SourceDir = filepath.Join(ts.SourceDir, name)
+	root.SourceLayers = nil
+	for _, sourceLayer := range ts.SourceLayers {
+		root.SourceLayers = append(root.SourceLayers, filepath.Join(sourceLayer, name))
+	}
+	root.Templates = ts.Templates
+	if err := root.Populate(fs, options); err != nil {
+		return err
+	}
+	if root.MinVersion != nil && (ts.MinVersion == nil || ts.MinVersion.LessThan(*root.MinVersion)) {
+		ts.MinVersion = root.MinVersion
+	}
+	if ts.entryRoots == nil {
+		ts.entryRoots = make(map[Entry]*TargetState)
+	}
+	for _, entry := range root.AllEntries() {
+		ts.entryRoots[entry] = root
+	}
+	return nil
+}
+
+func (ts *TargetState) populateSourceDir(fs vfs.FS, sourceDir string, options *PopulateOptions) error {
+	return vfs.Walk(fs, sourceDir, func(path string, info os.FileInfo, _ error) error {
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		// Roots are populated separately, and inactive roots not at all.
+		if _, ok := ts.Roots[relPath]; (ok || ts.InactiveRoots[relPath]) && info.IsDir() {
+			return filepath.SkipDir
+		}
+		// Treat all files and directories beginning with "." specially.
+		if _, name := filepath.Split(relPath); strings.HasPrefix(name, ".") {
+			switch {
+			case (info.Name() == attributesName || info.Name() == ignoreName || info.Name() == removeName) && options != nil && options.SkipPatterns:
+				return nil
+			case info.Name() == attributesName:
+				dns := dirNames(parseDirNameComponents(splitPathList(relPath)))
+				return ts.addAttributes(fs, path, filepath.Join(dns...))
+			case info.Name() == ignoreName:
+				dns := dirNames(parseDirNameComponents(splitPathList(relPath)))
+				return ts.addPatterns(fs, ts.TargetIgnore, path, filepath.Join(dns...))
+			case info.Name() == removeName:
+				dns := dirNames(parseDirNameComponents(splitPathList(relPath)))
+				return ts.addPatterns(fs, ts.TargetRemove, path, filepath.Join(dns...))
+			case info.Name() == templatesDirName:
+				if err := ts.addTemplatesDir(fs, path); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			case info.Name() == versionName:
+				data, err := fs.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				version, err := semver.NewVersion(strings.TrimSpace(string(data)))
+				if err != nil {
+					return err
+				}
+				if ts.MinVersion == nil || ts.MinVersion.LessThan(*version) {
+					ts.MinVersion = version
+				}
+				return nil
+			case info.IsDir():
+				// Don't recurse into ignored subdirectories.
+				return filepath.SkipDir
+			}
+			// Ignore all other files and directories.
+			return nil
+		}
+		switch {
+		case info.IsDir():
+			xdgDNs, xdgRelPath, err := ts.parseXDGSourcePath(relPath)
+			if errors.Is(err, errXDGDirNotInDestDir) {
+				prefix, _ := SplitXDGPrefix(relPath)
+				ts.skipXDGEntry(path, prefix)
+				return filepath.SkipDir
+			} else if err != nil {
+				return err
+			}
+			components := splitPathList(xdgRelPath)
+			das := parseDirNameComponents(components)
+			dns := append(xdgDNs, dirNames(das)...)
+			targetName := filepath.Join(dns...)
+			entries, err := ts.findEntries(dns[:len(dns)-1])
+			if err != nil {
+				return err
+			}
+			da := das[len(das)-1]
+			dir := newDir(relPath, targetName, da.Exact, da.Perm)
+			// A directory in a later source directory keeps the entries of
+			// the same directory in earlier ones.
+			if existingDir, ok := entries[da.Name].(*Dir); ok {
+				dir.Entries = existingDir.Entries
+			}
+			ts.setEntry(entries, da.Name, sourceDir, dir)
+		case info.Mode().IsRegular():
+			xdgDNs, xdgRelPath, err := ts.parseXDGSourcePath(relPath)
+			if errors.Is(err, errXDGDirNotInDestDir) {
+				prefix, _ := SplitXDGPrefix(relPath)
+				ts.skipXDGEntry(path, prefix)
+				return nil
+			} else if err != nil {
+				return err
+			}
+			psfp := parseSourceFilePath(xdgRelPath)
+			dns := append(xdgDNs, dirNames(psfp.dirAttributes)...)
+			entries, err := ts.findEntries(dns)
+			if err != nil {
+				return err
+			}
+			switch {
+			case psfp.fileAttributes != nil && psfp.fileAttributes.Mode&os.ModeType == 0 || psfp.scriptAttributes != nil:
+				readFile := func() ([]byte, error) {
+					return fs.ReadFile(path)
+				}
+				evaluateContents := readFile
+				if psfp.fileAttributes != nil && psfp.fileAttributes.Encrypted {
+					prevEvaluateContents := evaluateContents
+					evaluateContents = func() ([]byte, error) {
+						ciphertext, err := prevEvaluateContents()
+						if err != nil {
+							return nil, err
+						}
+						return ts.GPG.Decrypt(path, ciphertext)
+					}
+				}
+				if psfp.fileAttributes != nil && psfp.fileAttributes.Template || psfp.scriptAttributes != nil && psfp.scriptAttributes.Template {
+					if options == nil || options.ExecuteTemplates {
+						prevEvaluateContents := evaluateContents
+						evaluateContents = func() ([]byte, error) {
+							data, err := prevEvaluateContents()
+							if err != nil {
+								return nil, err
+							}
+							return ts.ExecuteTemplateData(path, data)
+						}
+					}
+				}
+				switch {
+				case psfp.fileAttributes != nil:
+					entry := &File{
+						sourceName:       relPath,
+						targetName:       filepath.Join(append(dns, psfp.fileAttributes.Name)...),
+						Empty:            psfp.fileAttributes.Empty,
+						Encrypted:        psfp.fileAttributes.Encrypted,
+						Perm:             psfp.fileAttributes.Mode.Perm(),
+						Template:         psfp.fileAttributes.Template,
+						evaluateContents: evaluateContents,
+					}
+					ts.setEntry(entries, psfp.fileAttributes.Name, sourceDir, entry)
+				case psfp.scriptAttributes != nil:
+					entry := &Script{
+						sourceName:       relPath,
+						targetName:       filepath.Join(append(dns, psfp.scriptAttributes.Name)...),
+						Once:             psfp.scriptAttributes.Once,
+						Template:         psfp.scriptAttributes.Template,
+						evaluateContents: evaluateContents,
+					}
+					ts.setEntry(entries, psfp.scriptAttributes.Name, sourceDir, entry)
+				}
+			case psfp.fileAttributes != nil && psfp.fileAttributes.Mode&os.ModeType == os.ModeSymlink:
+				evaluateLinkname := func() (string, error) {
+					data, err := fs.ReadFile(path)
+					return string(data), err
+				}
+				if psfp.fileAttributes.Template {
+					evaluateLinkname = func() (string, error) {
+						data, err := ts.executeTemplate(fs, path)
+						return string(data), err
+					}
+				}
+				entry := &Symlink{
+					sourceName:       relPath,
+					targetName:       filepath.Join(append(dns, psfp.fileAttributes.Name)...),
+					Template:         psfp.fileAttributes.Template,
+					evaluateLinkname: evaluateLinkname,
+				}
+				ts.setEntry(entries, psfp.fileAttributes.Name, sourceDir, entry)
+			default:
+				return fmt.Errorf("%s: unsupported file type", path)
+			}
+		default:
+			return fmt.Errorf("%s: unsupported file type", path)
+		}
+		return nil
+	})
+}
+
+// resolveLazyTemplateData adds the values of all lazy template data referenced
+// by the template name in tmpl to ts.TemplateData. ts.TemplateData is replaced
+// rather than modified, as other templates may be executing with it
+// concurrently. The caller must hold ts.templateDataMutex.
+func (ts *TargetState) resolveLazyTemplateData(tmpl *template.Template, name string) error {
+	for key, f := range ts.LazyTemplateData {
+		if !templateReferencesKey(tmpl, name, key) {
+			continue
+		}
+		value, err := f()
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		templateData := make(map[string]interface{}, len(ts.TemplateData)+1)
+		for k, v := range ts.TemplateData {
+			templateData[k] = v
+		}
+		templateData[key] = value
+		ts.TemplateData = templateData
+		delete(ts.LazyTemplateData, key)
+	}
+	return nil
+}
+
+// rootApplyOptions returns a copy of applyOptions for applying ts as a root.
+func (ts *TargetState) rootApplyOptions(applyOptions *ApplyOptions) *ApplyOptions {
+	rootApplyOptions := *applyOptions
+	rootApplyOptions.DestDir = ts.DestDir
+	rootApplyOptions.Ignore = ts.TargetIgnore.Match
+	rootApplyOptions.Umask = ts.Umask
+	rootApplyOptions.errors = nil
+	return &rootApplyOptions
+}
+
+// rootOf returns the root of ts that entry is in, or ts if entry is not in
+// any of its roots.
+func (ts *TargetState) rootOf(entry Entry) *TargetState {
+	if root, ok := ts.entryRoots[entry]; ok {
+		return root
+	}
+	return ts
+}
+
+// setEntry sets entries[name] to entry, which was populated from sourceDir.
+func (ts *TargetState) setEntry(entries map[string]Entry, name, sourceDir string, entry Entry) {
+	if sourceDir != ts.SourceDir {
+		if ts.entrySourceDirs == nil {
+			ts.entrySourceDirs = make(map[Entry]string)
+		}
+		ts.entrySourceDirs[entry] = sourceDir
+	}
+	entries[name] = entry
+}
+
+// setOwnership sets the ownership of all of ts's entries, but not those of its
+// roots, from ts.TargetAttributes.
+func (ts *TargetState) setOwnership() {
+	var allEntries []Entry
+	for _, entry := range ts.Entries {
+		allEntries = entry.AppendAllEntries(allEntries)
+	}
+	for _, entry := range allEntries {
+		switch entry := entry.(type) {
+		case *Dir:
+			entry.Ownership = ts.TargetAttributes.Ownership(entry.targetName)
+		case *File:
+			entry.Ownership = ts.TargetAttributes.Ownership(entry.targetName)
+		}
+	}
+}
+
+func sortedRootNames(roots map[string]*TargetState) []string {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
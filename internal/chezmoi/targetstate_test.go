@@ -1,15 +1,20 @@
 package chezmoi
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twpayne/go-vfs/vfst"
+	xdg "github.com/twpayne/go-xdg/v3"
 )
 
 func TestEndToEnd(t *testing.T) {
@@ -114,12 +119,391 @@ func TestEndToEnd(t *testing.T) {
 				Stdout:            os.Stdout,
 				Umask:             0o22,
 			}
-			assert.NoError(t, ts.Apply(fs, NewVerboseMutator(os.Stderr, NewFSMutator(fs), false, 0), tc.follow, applyOptions))
+			assert.NoError(t, ts.Apply(fs, NewVerboseMutator(os.Stderr, NewFSMutator(fs), false, 0, nil), tc.follow, applyOptions))
 			vfst.RunTests(t, fs, "", tc.tests)
 		})
 	}
 }
 
+func TestTargetStateEvaluateParallel(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			"a.tmpl":     "{{ barrier }}",
+			"b.tmpl":     "{{ barrier }}",
+			"dir/c.tmpl": "{{ barrier }}",
+			"dir/d.tmpl": "{{ barrier }}",
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	// barrier only returns once it has been called by all templates, so
+	// evaluation only succeeds if all templates are executed concurrently.
+	const n = 4
+	var wg sync.WaitGroup
+	wg.Add(n)
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithParallelism(n),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithTemplateFuncs(template.FuncMap{
+			"barrier": func() (string, error) {
+				wg.Done()
+				done := make(chan struct{})
+				go func() {
+					wg.Wait()
+					close(done)
+				}()
+				select {
+				case <-done:
+					return "ok", nil
+				case <-time.After(5 * time.Second):
+					return "", errors.New("timeout")
+				}
+			},
+		}),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	require.NoError(t, ts.Evaluate())
+	for _, entry := range ts.AllEntries() {
+		if file, ok := entry.(*File); ok {
+			contents, err := file.Contents()
+			require.NoError(t, err)
+			assert.Equal(t, []byte("ok"), contents)
+		}
+	}
+}
+
+func TestTargetStateEvaluateParallelErrorOrder(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			"a":      "a",
+			"b.tmpl": `{{ fail "b" }}`,
+			"c.tmpl": `{{ fail "c" }}`,
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithParallelism(4),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithTemplateFuncs(template.FuncMap{
+			"fail": func(s string) (string, error) {
+				return "", errors.New(s)
+			},
+		}),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	err = ts.Evaluate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b.tmpl")
+}
+
+// A failMkdirMutator is an FSMutator that fails to create directories.
+type failMkdirMutator struct {
+	*FSMutator
+}
+
+func (m failMkdirMutator) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+}
+
+func TestTargetStateApplyKeepGoing(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			"a.tmpl": `{{ fail "a" }}`,
+			"dir": map[string]interface{}{
+				"file": "# contents of dir/file\n",
+			},
+			"z": "# contents of z\n",
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithTemplateFuncs(template.FuncMap{
+			"fail": func(s string) (string, error) {
+				return "", errors.New(s)
+			},
+		}),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	applyOptions := &ApplyOptions{
+		DestDir:   ts.DestDir,
+		Ignore:    ts.TargetIgnore.Match,
+		KeepGoing: true,
+		Umask:     0o22,
+	}
+	err = ts.Apply(fs, failMkdirMutator{NewFSMutator(fs)}, false, applyOptions)
+	var applyErrors ApplyErrors
+	require.True(t, errors.As(err, &applyErrors))
+	require.Len(t, applyErrors, 2)
+	assert.Equal(t, "a", applyErrors[0].TargetName)
+	assert.Equal(t, "dir", applyErrors[1].TargetName)
+	assert.True(t, errors.Is(applyErrors[1], os.ErrPermission))
+
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/a",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/home/user/dir",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/home/user/z",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of z\n"),
+		),
+	)
+}
+
+func TestTargetStateApplyFilter(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			"dir": map[string]interface{}{
+				"file":           "# contents of dir/file\n",
+				"template.tmpl":  "# contents of dir/template\n",
+				"symlink_link":   "file",
+				"run_script.sh":  "#!/bin/sh\n",
+				"exact_subdir":   map[string]interface{}{},
+				"private_subdir": map[string]interface{}{},
+			},
+			"existing_dir": map[string]interface{}{
+				"file.tmpl": "# contents of existing_dir/file\n",
+			},
+		},
+		"/home/user/existing_dir": &vfst.Dir{Perm: 0o700},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithUmask(0o22),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	applyOptions := &ApplyOptions{
+		DestDir: ts.DestDir,
+		Filter:  NewEntryTypeFilter(EntryTypeTemplates, EntryTypesNone),
+		Ignore:  ts.TargetIgnore.Match,
+		Umask:   0o22,
+	}
+	require.NoError(t, ts.Apply(fs, NewFSMutator(fs), false, applyOptions))
+
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/dir",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/home/user/existing_dir",
+			vfst.TestIsDir,
+			vfst.TestModePerm(0o700),
+		),
+		vfst.TestPath("/home/user/existing_dir/file",
+			vfst.TestModeIsRegular,
+			vfst.TestContentsString("# contents of existing_dir/file\n"),
+		),
+	)
+
+	concreteValue, err := ts.ConcreteValue(NewEntryTypeFilter(EntryTypeFiles, EntryTypeTemplates), true)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		&fileConcreteValue{
+			Type:       "file",
+			SourcePath: "/home/user/.local/share/chezmoi/dir/file",
+			TargetPath: "dir/file",
+			Perm:       0o644,
+			Contents:   "# contents of dir/file\n",
+		},
+	}, concreteValue)
+}
+
+func TestTargetStatePopulateLayers(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/base": map[string]interface{}{
+			".chezmoiignore":           "dir/ignored\n",
+			".chezmoiremove":           "old\n",
+			".chezmoitemplates/header": "# header\n",
+			"dir": map[string]interface{}{
+				"base":    "# contents of dir/base\n",
+				"ignored": "# contents of dir/ignored\n",
+			},
+			"file":     "# base contents of file\n",
+			"template": "# base contents of template\n",
+		},
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			".chezmoiignore": "dir/overlay-ignored\n",
+			"private_dir": map[string]interface{}{
+				"overlay":         "# contents of dir/overlay\n",
+				"overlay-ignored": "# contents of dir/overlay-ignored\n",
+			},
+			"template.tmpl": "{{ template \"header\" }}",
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithSourceLayers([]string{"/base"}),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+
+	dir, ok := ts.Entries["dir"].(*Dir)
+	require.True(t, ok)
+	assert.Equal(t, os.FileMode(0o700), dir.Perm)
+	assert.Equal(t, "/home/user/.local/share/chezmoi", ts.SourceDirOf(dir))
+	assert.Equal(t, "/base/dir/base", ts.SourcePath(dir.Entries["base"]))
+	assert.Equal(t, "/home/user/.local/share/chezmoi/private_dir/overlay", ts.SourcePath(dir.Entries["overlay"]))
+	assert.Equal(t, "/base/file", ts.SourcePath(ts.Entries["file"]))
+
+	templateFile, ok := ts.Entries["template"].(*File)
+	require.True(t, ok)
+	assert.Equal(t, "/home/user/.local/share/chezmoi", ts.SourceDirOf(templateFile))
+	contents, err := templateFile.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "# header\n", string(contents))
+
+	assert.True(t, ts.TargetIgnore.Match("dir/ignored"))
+	assert.True(t, ts.TargetIgnore.Match("dir/overlay-ignored"))
+	assert.True(t, ts.TargetRemove.Match("old"))
+}
+
+func TestTargetStateRoots(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user/.local/share/chezmoi": map[string]interface{}{
+			"dot_bashrc": "# contents of .bashrc\n",
+			"etc": map[string]interface{}{
+				".chezmoiignore": "ignored\n",
+				"hosts":          "# contents of /etc/hosts\n",
+				"ignored":        "# contents of /etc/ignored\n",
+			},
+		},
+		"/etc": &vfst.Dir{Perm: 0o755},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	root := NewTargetState(
+		WithDestDir("/etc"),
+		WithUmask(0o77),
+	)
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithRoots(map[string]*TargetState{
+			"etc": root,
+		}),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithUmask(0o22),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	assert.Equal(t, "/home/user/.local/share/chezmoi/etc", root.SourceDir)
+	assert.NotContains(t, ts.Entries, "etc")
+
+	entry, err := ts.Get(fs, "/etc/hosts")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/hosts", ts.TargetPath(entry))
+	assert.Equal(t, "/home/user/.local/share/chezmoi/etc/hosts", ts.SourcePath(entry))
+	ignoredEntry, err := ts.Get(fs, "/etc/ignored")
+	require.NoError(t, err)
+	assert.True(t, ts.Ignored(ignoredEntry))
+	_, err = ts.Get(fs, "/usr/local/etc/hosts")
+	assert.Error(t, err)
+
+	applyOptions := &ApplyOptions{
+		DestDir: ts.DestDir,
+		Ignore:  ts.TargetIgnore.Match,
+		Umask:   ts.Umask,
+	}
+	require.NoError(t, ts.Apply(fs, NewFSMutator(fs), false, applyOptions))
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.bashrc",
+			vfst.TestModeIsRegular,
+			vfst.TestModePerm(0o644),
+		),
+		vfst.TestPath("/home/user/hosts",
+			vfst.TestDoesNotExist,
+		),
+		vfst.TestPath("/etc/hosts",
+			vfst.TestModeIsRegular,
+			vfst.TestModePerm(0o600),
+			vfst.TestContentsString("# contents of /etc/hosts\n"),
+		),
+		vfst.TestPath("/etc/ignored",
+			vfst.TestDoesNotExist,
+		),
+	)
+}
+
+func TestTargetStateOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".local/share/chezmoi": map[string]interface{}{
+				".chezmoiattributes": strings.Join([]string{
+					"# ownership of .dir",
+					".dir owner=54321 group=54321",
+					".dir/file group=54322",
+				}, "\n"),
+				"dot_dir/file": "# contents of .dir/file\n",
+			},
+			".added": "# contents of .added\n",
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithUmask(0o22),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	entry, err := ts.Get(fs, "/home/user/.dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, Ownership{Group: "54322"}, entry.(*File).Ownership)
+
+	applyOptions := &ApplyOptions{
+		DestDir: ts.DestDir,
+		Ignore:  ts.TargetIgnore.Match,
+		Umask:   ts.Umask,
+	}
+	require.NoError(t, ts.Apply(fs, NewFSMutator(fs), false, applyOptions))
+	for path, expectedIDs := range map[string][]int{
+		"/home/user/.dir":      {54321, 54321},
+		"/home/user/.dir/file": {0, 54322},
+	} {
+		info, err := fs.Lstat(path)
+		require.NoError(t, err)
+		uid, gid, ok := fileOwnership(info)
+		require.True(t, ok)
+		assert.Equal(t, expectedIDs, []int{uid, gid}, path)
+	}
+	anyMutator := NewAnyMutator(NullMutator{})
+	require.NoError(t, ts.Apply(fs, anyMutator, false, applyOptions))
+	assert.False(t, anyMutator.Mutated())
+
+	require.NoError(t, fs.Chown("/home/user/.added", 54323, 0))
+	require.NoError(t, ts.Add(fs, AddOptions{Ownership: true}, "/home/user/.added", nil, false, NewFSMutator(fs)))
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.local/share/chezmoi/.chezmoiattributes",
+			vfst.TestContentsString(strings.Join([]string{
+				"# ownership of .dir",
+				".dir owner=54321 group=54321",
+				".dir/file group=54322",
+				".added owner=" + userName(54323),
+			}, "\n")+"\n"),
+		),
+	)
+}
+
 func TestTargetStatePopulate(t *testing.T) {
 	for _, tc := range []struct {
 		name          string
@@ -467,7 +851,7 @@ func TestTargetStatePopulate(t *testing.T) {
 				WithDestDir("/"),
 				WithSourceDir("/"),
 				WithTemplates(map[string]*template.Template{
-					"foo": template.Must(template.New("foo").Parse("bar")),
+					"foo": template.Must(template.New("foo").Option(DefaultTemplateOptions...).Parse("bar")),
 				}),
 			),
 		},
@@ -488,3 +872,94 @@ func TestTargetStatePopulate(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetStateXDG(t *testing.T) {
+	fs, cleanup, err := vfst.NewTestFS(map[string]interface{}{
+		"/home/user": map[string]interface{}{
+			".cfg": &vfst.Dir{Perm: 0o700},
+			".local/share/chezmoi": map[string]interface{}{
+				"xdg_config_nvim/init.vim":         "\" contents of .cfg/nvim/init.vim\n",
+				"xdg_config_private_starship.toml": "# contents of .cfg/starship.toml\n",
+			},
+			".new": "# contents of .new\n",
+			".data/app": map[string]interface{}{
+				"file": "# contents of .data/app/file\n",
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer cleanup()
+
+	bds := &xdg.BaseDirectorySpecification{
+		CacheHome:  "/var/cache",
+		ConfigHome: "/home/user/.cfg",
+		DataHome:   "/home/user/.data",
+	}
+	ts := NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithUmask(0o22),
+		WithXDG(bds),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	entry, err := ts.Get(fs, "/home/user/.cfg/starship.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "/home/user/.local/share/chezmoi/xdg_config_private_starship.toml", ts.SourcePath(entry))
+	_, err = ts.Get(fs, "/home/user/.cfg")
+	assert.True(t, os.IsNotExist(err))
+	var targetNames []string
+	for _, entry := range ts.AllEntries() {
+		targetNames = append(targetNames, entry.TargetName())
+	}
+	assert.ElementsMatch(t, []string{".cfg/nvim", ".cfg/nvim/init.vim", ".cfg/starship.toml"}, targetNames)
+
+	applyOptions := &ApplyOptions{
+		DestDir: ts.DestDir,
+		Ignore:  ts.TargetIgnore.Match,
+		Umask:   ts.Umask,
+	}
+	require.NoError(t, ts.Apply(fs, NewFSMutator(fs), false, applyOptions))
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.cfg",
+			vfst.TestIsDir,
+			vfst.TestModePerm(0o700),
+		),
+		vfst.TestPath("/home/user/.cfg/nvim/init.vim",
+			vfst.TestContentsString("\" contents of .cfg/nvim/init.vim\n"),
+		),
+		vfst.TestPath("/home/user/.cfg/starship.toml",
+			vfst.TestModePerm(0o600),
+		),
+	)
+
+	require.NoError(t, fs.Rename("/home/user/.new", "/home/user/.cfg/new"))
+	require.NoError(t, ts.Add(fs, AddOptions{}, "/home/user/.cfg/new", nil, false, NewFSMutator(fs)))
+	require.NoError(t, ts.Add(fs, AddOptions{}, "/home/user/.data/app/file", nil, false, NewFSMutator(fs)))
+	vfst.RunTests(t, fs, "",
+		vfst.TestPath("/home/user/.local/share/chezmoi/xdg_config_new",
+			vfst.TestContentsString("# contents of .new\n"),
+		),
+		vfst.TestPath("/home/user/.local/share/chezmoi/xdg_data_app/file",
+			vfst.TestContentsString("# contents of .data/app/file\n"),
+		),
+		vfst.TestPath("/home/user/.local/share/chezmoi/dot_data",
+			vfst.TestDoesNotExist,
+		),
+	)
+
+	require.NoError(t, fs.WriteFile("/home/user/.local/share/chezmoi/xdg_cache_file", nil, 0o666))
+	ts = NewTargetState(
+		WithDestDir("/home/user"),
+		WithSourceDir("/home/user/.local/share/chezmoi"),
+		WithXDG(bds),
+	)
+	require.NoError(t, ts.Populate(fs, nil))
+	assert.Equal(t, []SkippedXDGEntry{
+		{
+			SourcePath: "/home/user/.local/share/chezmoi/xdg_cache_file",
+			XDGDir:     "/var/cache",
+		},
+	}, ts.SkippedXDGEntries)
+	_, err = ts.Get(fs, "/home/user/.cfg/starship.toml")
+	assert.NoError(t, err)
+}
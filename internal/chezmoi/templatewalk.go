@@ -0,0 +1,255 @@
+package chezmoi
+
+import (
+	"text/template"
+	"text/template/parse"
+)
+
+// A TemplateCall is a call to a template function.
+type TemplateCall struct {
+	// Template is the name of the template that contains the call.
+	Template string
+	Func     string
+	// Args contains the arguments to the call. Arguments that are not
+	// literals, and so cannot be known without executing the template, are
+	// nil.
+	Args []*string
+	Text string
+}
+
+// FindTemplateCalls returns all calls to the functions in funcs in the
+// template name in tmpl and any template that it invokes, without executing
+// any of them.
+func FindTemplateCalls(tmpl *template.Template, name string, funcs map[string]bool) []TemplateCall {
+	var templateCalls []TemplateCall
+	walkReachableTemplates(tmpl, name, func(templateName string, node parse.Node) {
+		pipe, ok := node.(*parse.PipeNode)
+		if !ok {
+			return
+		}
+		for i, cmd := range pipe.Cmds {
+			identifier, ok := cmd.Args[0].(*parse.IdentifierNode)
+			if !ok || !funcs[identifier.Ident] {
+				continue
+			}
+			args := make([]*string, 0, len(cmd.Args))
+			for _, arg := range cmd.Args[1:] {
+				args = append(args, templateLiteral(arg))
+			}
+			text := cmd.String()
+			// In a pipeline, the result of the previous command is passed as
+			// the last argument.
+			if i > 0 {
+				prevCmd := pipe.Cmds[i-1]
+				if len(prevCmd.Args) == 1 {
+					args = append(args, templateLiteral(prevCmd.Args[0]))
+				} else {
+					args = append(args, nil)
+				}
+				text = prevCmd.String() + " | " + text
+			}
+			templateCalls = append(templateCalls, TemplateCall{
+				Template: templateName,
+				Func:     identifier.Ident,
+				Args:     args,
+				Text:     text,
+			})
+		}
+	})
+	return templateCalls
+}
+
+// WalkTemplateNodes calls f for node and all of its descendants, in depth-first
+// order. The trees of templates invoked with the template action are not
+// walked.
+func WalkTemplateNodes(node parse.Node, f func(parse.Node)) {
+	if node == nil {
+		return
+	}
+	f(node)
+	switch node := node.(type) {
+	case *parse.ActionNode:
+		walkTemplatePipeNode(node.Pipe, f)
+	case *parse.ChainNode:
+		WalkTemplateNodes(node.Node, f)
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			WalkTemplateNodes(arg, f)
+		}
+	case *parse.IfNode:
+		walkTemplateBranchNode(&node.BranchNode, f)
+	case *parse.ListNode:
+		for _, child := range node.Nodes {
+			WalkTemplateNodes(child, f)
+		}
+	case *parse.PipeNode:
+		for _, decl := range node.Decl {
+			WalkTemplateNodes(decl, f)
+		}
+		for _, cmd := range node.Cmds {
+			WalkTemplateNodes(cmd, f)
+		}
+	case *parse.RangeNode:
+		walkTemplateBranchNode(&node.BranchNode, f)
+	case *parse.TemplateNode:
+		walkTemplatePipeNode(node.Pipe, f)
+	case *parse.WithNode:
+		walkTemplateBranchNode(&node.BranchNode, f)
+	}
+}
+
+// templateLiteral returns the value of node if it is a literal, or nil
+// otherwise.
+func templateLiteral(node parse.Node) *string {
+	var s string
+	switch node := node.(type) {
+	case *parse.BoolNode:
+		s = node.String()
+	case *parse.NumberNode:
+		s = node.Text
+	case *parse.StringNode:
+		s = node.Text
+	default:
+		return nil
+	}
+	return &s
+}
+
+// templateReferencesKey returns whether the template name in tmpl, or any
+// template that it invokes, might reference the top-level data key. Field
+// accesses like .key and $.key and string literals equal to key, as used by
+// index, are considered references. Bare uses of the whole data in name, as .
+// outside range and with actions or as $, reference every key, as the data
+// may be passed to functions like toJson, ranged over, assigned to variables,
+// or passed to other templates.
+func templateReferencesKey(tmpl *template.Template, name, key string) bool {
+	referenced := false
+	visited := make(map[string]bool)
+	pending := []string{name}
+	for len(pending) > 0 && !referenced {
+		templateName := pending[0]
+		pending = pending[1:]
+		if visited[templateName] {
+			continue
+		}
+		visited[templateName] = true
+		t := tmpl.Lookup(templateName)
+		if t == nil || t.Tree == nil {
+			continue
+		}
+		// Only in the template name are . and $ the whole data. Templates
+		// that it invokes receive the whole data only through a bare ., which
+		// is already a reference.
+		root := templateName == name
+		walkTemplateScopes(t.Tree.Root, root, func(node parse.Node, dotIsRoot bool) {
+			switch node := node.(type) {
+			case *parse.DotNode:
+				if dotIsRoot {
+					referenced = true
+				}
+			case *parse.FieldNode:
+				if node.Ident[0] == key {
+					referenced = true
+				}
+			case *parse.StringNode:
+				if node.Text == key {
+					referenced = true
+				}
+			case *parse.TemplateNode:
+				pending = append(pending, node.Name)
+			case *parse.VariableNode:
+				switch {
+				case len(node.Ident) > 1 && node.Ident[0] == "$" && node.Ident[1] == key:
+					referenced = true
+				case len(node.Ident) == 1 && node.Ident[0] == "$" && root:
+					referenced = true
+				}
+			}
+		})
+	}
+	return referenced
+}
+
+// walkReachableTemplates calls f for every node in the template name in tmpl
+// and in every template that it invokes, directly or indirectly. Each template
+// is walked at most once.
+func walkReachableTemplates(tmpl *template.Template, name string, f func(string, parse.Node)) {
+	visited := make(map[string]bool)
+	pending := []string{name}
+	for len(pending) > 0 {
+		templateName := pending[0]
+		pending = pending[1:]
+		if visited[templateName] {
+			continue
+		}
+		visited[templateName] = true
+		t := tmpl.Lookup(templateName)
+		if t == nil || t.Tree == nil {
+			continue
+		}
+		WalkTemplateNodes(t.Tree.Root, func(node parse.Node) {
+			if templateNode, ok := node.(*parse.TemplateNode); ok {
+				pending = append(pending, templateNode.Name)
+			}
+			f(templateName, node)
+		})
+	}
+}
+
+func walkTemplateBranchNode(node *parse.BranchNode, f func(parse.Node)) {
+	walkTemplatePipeNode(node.Pipe, f)
+	if node.List != nil {
+		WalkTemplateNodes(node.List, f)
+	}
+	if node.ElseList != nil {
+		WalkTemplateNodes(node.ElseList, f)
+	}
+}
+
+// walkTemplateScopes calls f for node and all of its descendants, in
+// depth-first order, like WalkTemplateNodes. dotIsRoot is passed to f and is
+// whether . is the data that node is executed with, which is false in the
+// bodies of range and with actions.
+func walkTemplateScopes(node parse.Node, dotIsRoot bool, f func(parse.Node, bool)) {
+	if node == nil {
+		return
+	}
+	switch node := node.(type) {
+	case *parse.IfNode:
+		f(node, dotIsRoot)
+		walkTemplateScopedBranchNode(&node.BranchNode, dotIsRoot, dotIsRoot, f)
+	case *parse.RangeNode:
+		f(node, dotIsRoot)
+		walkTemplateScopedBranchNode(&node.BranchNode, dotIsRoot, false, f)
+	case *parse.WithNode:
+		f(node, dotIsRoot)
+		walkTemplateScopedBranchNode(&node.BranchNode, dotIsRoot, false, f)
+	case *parse.ListNode:
+		f(node, dotIsRoot)
+		for _, child := range node.Nodes {
+			walkTemplateScopes(child, dotIsRoot, f)
+		}
+	default:
+		WalkTemplateNodes(node, func(node parse.Node) {
+			f(node, dotIsRoot)
+		})
+	}
+}
+
+func walkTemplateScopedBranchNode(node *parse.BranchNode, dotIsRoot, listDotIsRoot bool, f func(parse.Node, bool)) {
+	if node.Pipe != nil {
+		walkTemplateScopes(node.Pipe, dotIsRoot, f)
+	}
+	if node.List != nil {
+		walkTemplateScopes(node.List, listDotIsRoot, f)
+	}
+	if node.ElseList != nil {
+		walkTemplateScopes(node.ElseList, dotIsRoot, f)
+	}
+}
+
+func walkTemplatePipeNode(node *parse.PipeNode, f func(parse.Node)) {
+	if node != nil {
+		WalkTemplateNodes(node, f)
+	}
+}
@@ -0,0 +1,165 @@
+package chezmoi
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateReferencesKey(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		text     string
+		expected bool
+	}{
+		{
+			name:     "empty",
+			text:     "",
+			expected: false,
+		},
+		{
+			name:     "field",
+			text:     "{{ .secrets.token }}",
+			expected: true,
+		},
+		{
+			name:     "other_field",
+			text:     "{{ .chezmoi.os }}",
+			expected: false,
+		},
+		{
+			name:     "nested_field",
+			text:     "{{ .chezmoi.secrets }}",
+			expected: false,
+		},
+		{
+			name:     "root_variable",
+			text:     "{{ range .list }}{{ $.secrets.token }}{{ end }}",
+			expected: true,
+		},
+		{
+			name:     "index",
+			text:     `{{ index . "secrets" "token" }}`,
+			expected: true,
+		},
+		{
+			name:     "if",
+			text:     "{{ if eq .chezmoi.os \"linux\" }}{{ else }}{{ .secrets.token }}{{ end }}",
+			expected: true,
+		},
+		{
+			name:     "template",
+			text:     `{{ template "secrets" . }}`,
+			expected: true,
+		},
+		{
+			name:     "unused_template",
+			text:     `{{ template "other" .chezmoi }}`,
+			expected: false,
+		},
+		{
+			name:     "dot_argument",
+			text:     `{{ printf "%v" . }}`,
+			expected: true,
+		},
+		{
+			name:     "dot_range",
+			text:     "{{ range $k, $v := . }}{{ $k }}{{ end }}",
+			expected: true,
+		},
+		{
+			name:     "dot_variable",
+			text:     "{{ $d := . }}{{ $d.secrets.token }}",
+			expected: true,
+		},
+		{
+			name:     "dot_template",
+			text:     `{{ template "dot" . }}`,
+			expected: true,
+		},
+		{
+			name:     "root_variable_argument",
+			text:     `{{ range .list }}{{ printf "%v" $ }}{{ end }}`,
+			expected: true,
+		},
+		{
+			name:     "range_dot",
+			text:     "{{ range .list }}{{ . }}{{ else }}{{ .chezmoi.os }}{{ end }}",
+			expected: false,
+		},
+		{
+			name:     "range_else_dot",
+			text:     "{{ range .list }}{{ . }}{{ else }}{{ . }}{{ end }}",
+			expected: true,
+		},
+		{
+			name:     "with_dot",
+			text:     "{{ with .chezmoi }}{{ .os }}{{ . }}{{ end }}",
+			expected: false,
+		},
+		{
+			name:     "template_dot",
+			text:     `{{ template "dot" .chezmoi }}`,
+			expected: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Parse(tc.text)
+			require.NoError(t, err)
+			_, err = tmpl.New("secrets").Parse("{{ .secrets.token }}")
+			require.NoError(t, err)
+			_, err = tmpl.New("other").Parse("{{ .chezmoi.os }}")
+			require.NoError(t, err)
+			_, err = tmpl.New("dot").Parse(`{{ printf "%v" . }}{{ printf "%v" $ }}`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, templateReferencesKey(tmpl, "test", "secrets"))
+		})
+	}
+}
+
+func TestFindTemplateCalls(t *testing.T) {
+	stringPtr := func(s string) *string {
+		return &s
+	}
+	funcs := template.FuncMap{
+		"pass": func(string) string { return "" },
+		"trim": func(string) string { return "" },
+	}
+	tmpl, err := template.New("test").Funcs(funcs).Parse(`` +
+		`{{ pass "a" | trim }}` +
+		`{{ "b" | pass }}` +
+		`{{ if .x }}{{ pass .y }}{{ end }}` +
+		`{{ template "other" . }}`,
+	)
+	require.NoError(t, err)
+	_, err = tmpl.New("other").Parse(`{{ pass "c" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, []TemplateCall{
+		{
+			Template: "test",
+			Func:     "pass",
+			Args:     []*string{stringPtr("a")},
+			Text:     `pass "a"`,
+		},
+		{
+			Template: "test",
+			Func:     "pass",
+			Args:     []*string{stringPtr("b")},
+			Text:     `"b" | pass`,
+		},
+		{
+			Template: "test",
+			Func:     "pass",
+			Args:     []*string{nil},
+			Text:     `pass .y`,
+		},
+		{
+			Template: "other",
+			Func:     "pass",
+			Args:     []*string{stringPtr("c")},
+			Text:     `pass "c"`,
+		},
+	}, FindTemplateCalls(tmpl, "test", map[string]bool{"pass": true}))
+}
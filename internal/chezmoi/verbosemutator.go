@@ -22,15 +22,18 @@ type VerboseMutator struct {
 	w               io.Writer
 	colored         bool
 	maxDiffDataSize int
+	redactor        *Redactor
 }
 
-// NewVerboseMutator returns a new VerboseMutator.
-func NewVerboseMutator(w io.Writer, m Mutator, colored bool, maxDiffDataSize int) *VerboseMutator {
+// NewVerboseMutator returns a new VerboseMutator. Secrets known to redactor are
+// masked in its output.
+func NewVerboseMutator(w io.Writer, m Mutator, colored bool, maxDiffDataSize int, redactor *Redactor) *VerboseMutator {
 	return &VerboseMutator{
 		m:               m,
 		w:               w,
 		colored:         colored,
 		maxDiffDataSize: maxDiffDataSize,
+		redactor:        redactor,
 	}
 }
 
@@ -46,9 +49,29 @@ func (m *VerboseMutator) Chmod(name string, mode os.FileMode) error {
 	return err
 }
 
+// Chown implements Mutator.Chown.
+func (m *VerboseMutator) Chown(name string, uid, gid int) error {
+	var action string
+	switch {
+	case uid == -1:
+		action = fmt.Sprintf("chgrp %d %s", gid, MaybeShellQuote(name))
+	case gid == -1:
+		action = fmt.Sprintf("chown %d %s", uid, MaybeShellQuote(name))
+	default:
+		action = fmt.Sprintf("chown %d:%d %s", uid, gid, MaybeShellQuote(name))
+	}
+	err := m.m.Chown(name, uid, gid)
+	if err == nil {
+		_, _ = fmt.Fprintln(m.w, action)
+	} else {
+		_, _ = fmt.Fprintf(m.w, "%s: %v\n", action, err)
+	}
+	return err
+}
+
 // IdempotentCmdOutput implements Mutator.IdempotentCmdOutput.
 func (m *VerboseMutator) IdempotentCmdOutput(cmd *exec.Cmd) ([]byte, error) {
-	action := cmdString(cmd)
+	action := m.redactor.RedactString(cmdString(cmd))
 	output, err := m.m.IdempotentCmdOutput(cmd)
 	if err != nil {
 		_, _ = fmt.Fprintf(m.w, "%s: %v\n", action, err)
@@ -94,7 +117,7 @@ func (m *VerboseMutator) Rename(oldpath, newpath string) error {
 
 // RunCmd implements Mutator.RunCmd.
 func (m *VerboseMutator) RunCmd(cmd *exec.Cmd) error {
-	action := cmdString(cmd)
+	action := m.redactor.RedactString(cmdString(cmd))
 	err := m.m.RunCmd(cmd)
 	if err == nil {
 		_, _ = fmt.Fprintln(m.w, action)
@@ -125,11 +148,11 @@ func (m *VerboseMutator) WriteFile(name string, data []byte, perm os.FileMode, c
 				return nil
 			}
 		}
-		aLines, err := splitLines(currData)
+		aLines, err := splitLines(m.redactor.Redact(currData))
 		if err != nil {
 			return err
 		}
-		bLines, err := splitLines(data)
+		bLines, err := splitLines(m.redactor.Redact(data))
 		if err != nil {
 			return err
 		}
@@ -155,7 +178,7 @@ func (m *VerboseMutator) WriteFile(name string, data []byte, perm os.FileMode, c
 
 // WriteSymlink implements Mutator.WriteSymlink.
 func (m *VerboseMutator) WriteSymlink(oldname, newname string) error {
-	action := fmt.Sprintf("ln -sf %s %s", MaybeShellQuote(oldname), MaybeShellQuote(newname))
+	action := m.redactor.RedactString(fmt.Sprintf("ln -sf %s %s", MaybeShellQuote(oldname), MaybeShellQuote(newname)))
 	err := m.m.WriteSymlink(oldname, newname)
 	if err == nil {
 		_, _ = fmt.Fprintln(m.w, action)
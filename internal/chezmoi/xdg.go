@@ -0,0 +1,169 @@
+package chezmoi
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	xdg "github.com/twpayne/go-xdg/v3"
+)
+
+// XDG prefixes, which are only recognized at the top level of the source
+// directory.
+const (
+	xdgCachePrefix  = "xdg_cache_"
+	xdgConfigPrefix = "xdg_config_"
+	xdgDataPrefix   = "xdg_data_"
+)
+
+// errXDGDirNotInDestDir is returned when an XDG base directory is not in the
+// destination directory.
+var errXDGDirNotInDestDir = errors.New("not in destination directory")
+
+// A SkippedXDGEntry is an entry in the source directory with an XDG prefix
+// that is not in the target state because its XDG base directory is not in
+// the destination directory.
+type SkippedXDGEntry struct {
+	SourcePath string
+	XDGDir     string
+}
+
+var xdgPrefixes = []string{
+	xdgCachePrefix,
+	xdgConfigPrefix,
+	xdgDataPrefix,
+}
+
+// SplitXDGPrefix splits the XDG prefix, if any, from sourceName, returning the
+// prefix and the rest of sourceName.
+func SplitXDGPrefix(sourceName string) (string, string) {
+	for _, prefix := range xdgPrefixes {
+		rest := strings.TrimPrefix(sourceName, prefix)
+		if rest != sourceName && rest != "" && !strings.HasPrefix(rest, string(filepath.Separator)) {
+			return prefix, rest
+		}
+	}
+	return "", sourceName
+}
+
+// xdgDir returns the XDG base directory of prefix in bds.
+func xdgDir(bds *xdg.BaseDirectorySpecification, prefix string) string {
+	switch prefix {
+	case xdgCachePrefix:
+		return bds.CacheHome
+	case xdgConfigPrefix:
+		return bds.ConfigHome
+	case xdgDataPrefix:
+		return bds.DataHome
+	default:
+		return ""
+	}
+}
+
+// implicitDirEntries returns the entries of the directory targetName, adding
+// implicit directories for it and its parents if they are not already in ts.
+func (ts *TargetState) implicitDirEntries(targetName string) (map[string]Entry, error) {
+	entries := ts.Entries
+	dns := splitPathList(targetName)
+	for i, dn := range dns {
+		dirTargetName := filepath.Join(dns[:i+1]...)
+		entry, ok := entries[dn]
+		if !ok {
+			dir := newDir("", dirTargetName, false, 0o777)
+			dir.implicit = true
+			entries[dn] = dir
+			entry = dir
+		}
+		dir, ok := entry.(*Dir)
+		if !ok {
+			return nil, fmt.Errorf("%s: not a directory", dirTargetName)
+		}
+		entries = dir.Entries
+	}
+	return entries, nil
+}
+
+// parseXDGSourcePath splits the XDG prefix, if any, from relPath, returning
+// the target names of the XDG base directory's components and the rest of
+// relPath. The XDG base directory is added to ts. If the XDG base directory is
+// not in ts.DestDir then the returned error wraps errXDGDirNotInDestDir.
+func (ts *TargetState) parseXDGSourcePath(relPath string) ([]string, string, error) {
+	if ts.XDG == nil {
+		return nil, relPath, nil
+	}
+	prefix, rest := SplitXDGPrefix(relPath)
+	if prefix == "" {
+		return nil, relPath, nil
+	}
+	targetName, err := ts.xdgTargetName(prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := ts.implicitDirEntries(targetName); err != nil {
+		return nil, "", err
+	}
+	return splitPathList(targetName), rest, nil
+}
+
+// xdgParentDir returns the XDG prefix and entries of the parent directory of
+// targetName if it is an XDG base directory, adding the XDG base directory to
+// ts if needed. XDG base directories that are already in the source state
+// without an XDG prefix keep their existing source names.
+func (ts *TargetState) xdgParentDir(targetName string) (string, map[string]Entry, bool, error) {
+	if ts.XDG == nil {
+		return "", nil, false, nil
+	}
+	parentDirName := filepath.Dir(targetName)
+	for _, prefix := range xdgPrefixes {
+		xdgTargetName, err := ts.xdgTargetName(prefix)
+		if err != nil || xdgTargetName != parentDirName {
+			continue
+		}
+		if entry, err := ts.findEntry(xdgTargetName); err == nil {
+			if dir, ok := entry.(*Dir); !ok || !dir.implicit {
+				return "", nil, false, nil
+			}
+		}
+		entries, err := ts.implicitDirEntries(xdgTargetName)
+		if err != nil {
+			return "", nil, false, err
+		}
+		return prefix, entries, true, nil
+	}
+	return "", nil, false, nil
+}
+
+// xdgTargetName returns the target name of the XDG base directory of prefix,
+// which must be in ts.DestDir.
+func (ts *TargetState) xdgTargetName(prefix string) (string, error) {
+	dir := xdgDir(ts.XDG, prefix)
+	targetName, err := filepath.Rel(ts.DestDir, dir)
+	if err != nil {
+		return "", err
+	}
+	if targetName == "." || targetName == ".." || strings.HasPrefix(targetName, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: %w", dir, errXDGDirNotInDestDir)
+	}
+	return targetName, nil
+}
+
+// skipXDGEntry records that the entry at sourcePath, whose source name has
+// prefix, is skipped because its XDG base directory is not in ts.DestDir.
+func (ts *TargetState) skipXDGEntry(sourcePath, prefix string) {
+	ts.SkippedXDGEntries = append(ts.SkippedXDGEntries, SkippedXDGEntry{
+		SourcePath: sourcePath,
+		XDGDir:     xdgDir(ts.XDG, prefix),
+	})
+}
+
+// joinSourceName returns the source name of sourceName in the directory with
+// source name parentDirSourceName, which may be an XDG prefix.
+func joinSourceName(parentDirSourceName, sourceName string) string {
+	for _, prefix := range xdgPrefixes {
+		if parentDirSourceName == prefix {
+			return prefix + sourceName
+		}
+	}
+	return filepath.Join(parentDirSourceName, sourceName)
+}
@@ -33,6 +33,8 @@ func TestChezmoi(t *testing.T) {
 		},
 		Condition: func(cond string) (bool, error) {
 			switch cond {
+			case "root":
+				return os.Geteuid() == 0, nil
 			case "windows":
 				return runtime.GOOS == "windows", nil
 			default: